@@ -0,0 +1,111 @@
+package alarms
+
+import (
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+type Alarm struct {
+	Address     string
+	Endpoint    uint8
+	ClusterId   cluster.ClusterId
+	AlarmCode   uint8
+	Description string
+	TimeStamp   uint32
+}
+
+// Table tracks the alarms devices have raised through the Alarms cluster
+// until they are reset.
+type Table struct {
+	library *cluster.ClusterLibrary
+	mutex   sync.Mutex
+	alarms  map[string][]*Alarm
+}
+
+func New(library *cluster.ClusterLibrary) *Table {
+	return &Table{library: library, alarms: map[string][]*Alarm{}}
+}
+
+// Handle records Alarm notifications and successful GetAlarm responses.
+// It returns false for every other message.
+func (t *Table) Handle(message *zcl.ZclIncomingMessage) bool {
+	if message.ClusterID != uint16(cluster.Alarms) || message.Data == nil {
+		return false
+	}
+	switch cmd := message.Data.Command.(type) {
+	case *cluster.AlarmCommand:
+		t.add(message.SrcAddr, message.SrcEndpoint, cluster.ClusterId(cmd.ClusterIdentifier), cmd.AlarmCode, 0)
+	case *cluster.GetAlarmResponse:
+		if cmd.Status != cluster.ZclStatusSuccess {
+			return true
+		}
+		t.add(message.SrcAddr, message.SrcEndpoint, cluster.ClusterId(cmd.ClusterIdentifier), cmd.AlarmCode, cmd.TimeStamp)
+	default:
+		return false
+	}
+	return true
+}
+
+func (t *Table) Active(address string) []*Alarm {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	active := make([]*Alarm, len(t.alarms[address]))
+	copy(active, t.alarms[address])
+	return active
+}
+
+// Reset removes an alarm after a ResetAlarm command was sent to an endpoint
+// of the device.
+func (t *Table) Reset(address string, endpoint uint8, clusterId cluster.ClusterId, alarmCode uint8) {
+	t.remove(address, func(alarm *Alarm) bool {
+		return alarm.Endpoint == endpoint && alarm.ClusterId == clusterId && alarm.AlarmCode == alarmCode
+	})
+}
+
+// ResetAll removes the alarms of an endpoint after a ResetAllAlarms command
+// was sent to it.
+func (t *Table) ResetAll(address string, endpoint uint8) {
+	t.remove(address, func(alarm *Alarm) bool {
+		return alarm.Endpoint == endpoint
+	})
+}
+
+func (t *Table) remove(address string, reset func(alarm *Alarm) bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var active []*Alarm
+	for _, alarm := range t.alarms[address] {
+		if !reset(alarm) {
+			active = append(active, alarm)
+		}
+	}
+	if len(active) == 0 {
+		delete(t.alarms, address)
+		return
+	}
+	t.alarms[address] = active
+}
+
+func (t *Table) add(address string, endpoint uint8, clusterId cluster.ClusterId, alarmCode uint8, timeStamp uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, alarm := range t.alarms[address] {
+		if alarm.Endpoint == endpoint && alarm.ClusterId == clusterId && alarm.AlarmCode == alarmCode {
+			if timeStamp > 0 {
+				alarm.TimeStamp = timeStamp
+			}
+			return
+		}
+	}
+	description, _ := t.library.AlarmDescription(clusterId, alarmCode)
+	t.alarms[address] = append(t.alarms[address], &Alarm{
+		Address:     address,
+		Endpoint:    endpoint,
+		ClusterId:   clusterId,
+		AlarmCode:   alarmCode,
+		Description: description,
+		TimeStamp:   timeStamp,
+	})
+}
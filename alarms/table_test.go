@@ -0,0 +1,80 @@
+package alarms
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestAlarms(t *testing.T) { TestingT(t) }
+
+type TableSuite struct{}
+
+var _ = Suite(&TableSuite{})
+
+func message(c *C, data []uint8) *zcl.ZclIncomingMessage {
+	return messageFrom(c, 1, data)
+}
+
+func messageFrom(c *C, endpoint uint8, data []uint8) *zcl.ZclIncomingMessage {
+	m, err := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:   uint16(cluster.Alarms),
+		SrcAddr:     "0x1234",
+		SrcEndpoint: endpoint,
+		Data:        data,
+	})
+	c.Assert(err, IsNil)
+	return m
+}
+
+func (s *TableSuite) TestAlarmDescription(c *C) {
+	library := cluster.New()
+	description, ok := library.AlarmDescription(cluster.PowerConfiguration, 0x10)
+	c.Assert(ok, Equals, true)
+	c.Assert(description, Equals, "BatteryVoltageMinThresholdReached")
+	_, ok = library.AlarmDescription(cluster.OnOff, 0x00)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *TableSuite) TestTracksAlarms(c *C) {
+	t := New(cluster.New())
+
+	c.Assert(t.Handle(message(c, []uint8{0x19, 0x01, 0x00, 0x10, 0x01, 0x00})), Equals, true)
+	c.Assert(t.Handle(message(c, []uint8{0x19, 0x02, 0x00, 0x10, 0x01, 0x00})), Equals, true)
+	c.Assert(t.Handle(message(c, []uint8{0x19, 0x03, 0x01, 0x00, 0x01, 0x02, 0x00, 0x10, 0x27, 0x00, 0x00})), Equals, true)
+
+	c.Assert(t.Active("0x1234"), DeepEquals, []*Alarm{
+		{"0x1234", 1, cluster.PowerConfiguration, 0x10, "BatteryVoltageMinThresholdReached", 0},
+		{"0x1234", 1, cluster.DeviceTemperatureConfiguration, 0x01, "DeviceTemperatureTooHigh", 10000},
+	})
+
+	t.Reset("0x1234", 1, cluster.PowerConfiguration, 0x10)
+	c.Assert(t.Active("0x1234"), HasLen, 1)
+	t.ResetAll("0x1234", 1)
+	c.Assert(t.Active("0x1234"), HasLen, 0)
+}
+
+func (s *TableSuite) TestResetsPerEndpoint(c *C) {
+	t := New(cluster.New())
+	t.Handle(messageFrom(c, 1, []uint8{0x19, 0x01, 0x00, 0x10, 0x01, 0x00}))
+	t.Handle(messageFrom(c, 2, []uint8{0x19, 0x02, 0x00, 0x10, 0x01, 0x00}))
+	c.Assert(t.Active("0x1234"), HasLen, 2)
+
+	t.Reset("0x1234", 1, cluster.PowerConfiguration, 0x10)
+	c.Assert(t.Active("0x1234"), DeepEquals, []*Alarm{
+		{"0x1234", 2, cluster.PowerConfiguration, 0x10, "BatteryVoltageMinThresholdReached", 0},
+	})
+	t.ResetAll("0x1234", 1)
+	c.Assert(t.Active("0x1234"), HasLen, 1)
+	t.ResetAll("0x1234", 2)
+	c.Assert(t.Active("0x1234"), HasLen, 0)
+}
+
+func (s *TableSuite) TestIgnoresFailedGetAlarmResponse(c *C) {
+	t := New(cluster.New())
+	c.Assert(t.Handle(message(c, []uint8{0x19, 0x01, 0x01, 0x8b})), Equals, true)
+	c.Assert(t.Active("0x1234"), HasLen, 0)
+}
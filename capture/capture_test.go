@@ -0,0 +1,244 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	. "gopkg.in/check.v1"
+)
+
+func TestCapture(t *testing.T) { TestingT(t) }
+
+type CaptureSuite struct{}
+
+var _ = Suite(&CaptureSuite{})
+
+var (
+	networkKey = Key{0x01, 0x03, 0x05, 0x07, 0x09, 0x0b, 0x0d, 0x0f, 0x00, 0x02, 0x04, 0x06, 0x08, 0x0a, 0x0c, 0x0d}
+	source64   = []uint8{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	// onCommand is an OnOff On command with transaction sequence number 5.
+	onCommand  = []uint8{0x01, 0x05, 0x01}
+	captureAt  = time.Unix(1546300800, 250000000)
+	apsOnOff   = aps(0x40, onCommand)
+	macHeader  = []uint8{0x41, 0x88, 0x17, 0x62, 0x1a, 0x00, 0x00, 0x34, 0x12}
+	nwkPlain   = []uint8{0x08, 0x00, 0x00, 0x00, 0x34, 0x12, 0x1e, 0x09}
+	nwkSecured = []uint8{0x08, 0x02, 0x00, 0x00, 0x34, 0x12, 0x1e, 0x09}
+)
+
+// aps returns an APS data frame of the OnOff cluster from endpoint 1 to 11.
+func aps(control uint8, payload []uint8) []uint8 {
+	return append([]uint8{control, 0x0b, 0x06, 0x00, 0x04, 0x01, 0x01, 0x2a}, payload...)
+}
+
+// secure encrypts the payload of a frame with a network key, as an end device
+// with the extended address source64 would.
+func secure(header []uint8, key Key, payload []uint8) []uint8 {
+	aux := append([]uint8{0x28, 0x01, 0x00, 0x00, 0x00}, source64...)
+	aux = append(aux, 0x00)
+	a := append(append([]uint8{}, header...), aux...)
+	a[len(header)] |= securityLevel
+	nonce := append(append([]uint8{}, source64...), 0x01, 0x00, 0x00, 0x00, 0x2d)
+	frame := append(append([]uint8{}, header...), aux...)
+	return append(frame, ccmSeal(key, nonce, a, payload, micSize)...)
+}
+
+func mac(nwk ...[]uint8) []uint8 {
+	frame := append([]uint8{}, macHeader...)
+	for _, b := range nwk {
+		frame = append(frame, b...)
+	}
+	return frame
+}
+
+func pcap(linkType uint32, frames ...[]uint8) []uint8 {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.LittleEndian, []uint32{pcapMagicMicroseconds, 0x00040002, 0, 0, 65535, linkType})
+	for _, frame := range frames {
+		binary.Write(b, binary.LittleEndian, []uint32{uint32(captureAt.Unix()), 250000, uint32(len(frame)), uint32(len(frame))})
+		b.Write(frame)
+	}
+	return b.Bytes()
+}
+
+func pcapng(linkType uint32, frames ...[]uint8) []uint8 {
+	b := &bytes.Buffer{}
+	block := func(blockType uint32, body []uint8) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		binary.Write(b, binary.BigEndian, []uint32{blockType, uint32(len(body) + 12)})
+		b.Write(body)
+		binary.Write(b, binary.BigEndian, uint32(len(body)+12))
+	}
+	block(pcapngSectionHeaderBlock, []uint8{0x1a, 0x2b, 0x3c, 0x4d, 0x00, 0x01, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	// nanosecond resolution
+	block(pcapngInterfaceDescription, []uint8{0x00, uint8(linkType), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09, 0x00, 0x01, 0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	for _, frame := range frames {
+		ticks := uint64(captureAt.UnixNano())
+		body := make([]uint8, 20)
+		binary.BigEndian.PutUint32(body[4:], uint32(ticks>>32))
+		binary.BigEndian.PutUint32(body[8:], uint32(ticks))
+		binary.BigEndian.PutUint32(body[12:], uint32(len(frame)))
+		binary.BigEndian.PutUint32(body[16:], uint32(len(frame)))
+		block(pcapngEnhancedPacketBlock, append(body, frame...))
+	}
+	return b.Bytes()
+}
+
+// zepOverEthernet encapsulates a frame in ZEP v2 over UDP over IPv4 over
+// Ethernet, with an LQI of 200 and a 2 byte FCS.
+func zepOverEthernet(frame []uint8) []uint8 {
+	zep := []uint8{'E', 'X', 0x02, 0x01, 0x0b, 0x00, 0x00, 0x00, 200}
+	zep = append(zep, make([]uint8, 22)...)
+	zep = append(zep, uint8(len(frame)+2))
+	zep = append(append(zep, frame...), 0xff, 0xff)
+	udp := make([]uint8, 8)
+	binary.BigEndian.PutUint16(udp, ZepPort)
+	binary.BigEndian.PutUint16(udp[2:], ZepPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(zep)))
+	ip := []uint8{0x45, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 17, 0x00, 0x00, 10, 0, 0, 1, 10, 0, 0, 2}
+	ethernet := []uint8{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 2, 0x08, 0x00}
+	return append(append(append(ethernet, ip...), udp...), zep...)
+}
+
+func read(c *C, data []uint8, keys ...Key) (*Reader, []*Packet) {
+	r, err := NewReader(bytes.NewReader(data), cluster.New())
+	c.Assert(err, IsNil)
+	for _, key := range keys {
+		r.AddNetworkKey(key)
+	}
+	packets, err := r.ReadAll()
+	c.Assert(err, IsNil)
+	return r, packets
+}
+
+func (s *CaptureSuite) TestPcap(c *C) {
+	withFCS := append(mac(nwkPlain, apsOnOff), 0xaa, 0xbb)
+	_, packets := read(c, pcap(LinkTypeIEEE802154WithFCS, withFCS))
+	c.Assert(packets, HasLen, 1)
+	p := packets[0]
+	c.Assert(p.Err, IsNil)
+	c.Assert(p.Number, Equals, 1)
+	c.Assert(p.Time.Equal(captureAt), Equals, true)
+	c.Assert(p.ProfileID, Equals, uint16(0x0104))
+	c.Assert(p.DstAddr, Equals, uint16(0x0000))
+	m := p.Message
+	c.Assert(m.ClusterID, Equals, uint16(0x0006))
+	c.Assert(m.SrcAddr, Equals, "0x1234")
+	c.Assert(m.SrcEndpoint, Equals, uint8(1))
+	c.Assert(m.DstEndpoint, Equals, uint8(11))
+	c.Assert(m.TransactionSeqNumber, Equals, uint8(0x2a))
+	c.Assert(m.Data.TransactionSequenceNumber, Equals, uint8(5))
+	c.Assert(m.Data.CommandName, Equals, "On")
+	c.Assert(m.Data.Command, DeepEquals, &cluster.OnCommand{})
+}
+
+func (s *CaptureSuite) TestPcapngZepWithNetworkKey(c *C) {
+	frame := zepOverEthernet(mac(secure(nwkSecured, networkKey, apsOnOff)))
+	r, packets := read(c, pcapng(LinkTypeEthernet, frame), networkKey)
+	c.Assert(r.Encrypted(), Equals, 0)
+	c.Assert(packets, HasLen, 1)
+	c.Assert(packets[0].Time.Equal(captureAt), Equals, true)
+	c.Assert(packets[0].Message.LinkQuality, Equals, uint8(200))
+	c.Assert(packets[0].Message.Data.CommandName, Equals, "On")
+}
+
+func (s *CaptureSuite) TestWithoutNetworkKey(c *C) {
+	frame := mac(secure(nwkSecured, networkKey, apsOnOff))
+	r, packets := read(c, pcap(LinkTypeIEEE802154NoFCS, frame, mac(nwkPlain, apsOnOff)), DefaultLinkKey)
+	c.Assert(r.Encrypted(), Equals, 1)
+	c.Assert(packets, HasLen, 1)
+	c.Assert(packets[0].Number, Equals, 2)
+}
+
+func (s *CaptureSuite) TestApsSecurity(c *C) {
+	// the NWK header carries the source extended address, the APS auxiliary
+	// header doesn't
+	nwk := append([]uint8{0x08, 0x10, 0x00, 0x00, 0x34, 0x12, 0x1e, 0x09}, source64...)
+	header := aps(0x60, nil)
+	aux := []uint8{0x00, 0x07, 0x00, 0x00, 0x00}
+	a := append(append([]uint8{}, header...), aux...)
+	a[len(header)] |= securityLevel
+	nonce := append(append([]uint8{}, source64...), 0x07, 0x00, 0x00, 0x00, 0x05)
+	apsSecured := append(append(header, aux...), ccmSeal(DefaultLinkKey, nonce, a, onCommand, micSize)...)
+	_, packets := read(c, pcap(LinkTypeIEEE802154NoFCS, mac(nwk, apsSecured)))
+	c.Assert(packets, HasLen, 1)
+	c.Assert(packets[0].Message.SecurityUse, Equals, true)
+	c.Assert(packets[0].Message.Data.CommandName, Equals, "On")
+}
+
+func (s *CaptureSuite) TestSkipsZdp(c *C) {
+	zdp := []uint8{0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x34, 0x12}
+	_, packets := read(c, pcap(LinkTypeIEEE802154NoFCS, mac(nwkPlain, zdp), []uint8{0x02, 0x00, 0x17}))
+	c.Assert(packets, HasLen, 0)
+}
+
+func (s *CaptureSuite) TestNotCapture(c *C) {
+	_, err := NewReader(bytes.NewReader([]uint8("not a capture file at all")), cluster.New())
+	c.Assert(err, ErrorMatches, "not a capture file: magic .*")
+}
+
+func (s *CaptureSuite) TestTruncated(c *C) {
+	data := pcap(LinkTypeIEEE802154NoFCS, mac(nwkPlain, apsOnOff))
+	r, err := NewReader(bytes.NewReader(data[:len(data)-1]), cluster.New())
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(err, ErrorMatches, "pcap record truncated: .*")
+	r, _ = NewReader(bytes.NewReader(data[:24]), cluster.New())
+	_, err = r.Next()
+	c.Assert(err, Equals, io.EOF)
+}
+
+func (s *CaptureSuite) TestInvalidLengths(c *C) {
+	data := pcap(LinkTypeIEEE802154NoFCS, mac(nwkPlain, apsOnOff))
+	binary.LittleEndian.PutUint32(data[24+8:], 0xffffffff)
+	r, err := NewReader(bytes.NewReader(data), cluster.New())
+	c.Assert(err, IsNil)
+	_, err = r.Next()
+	c.Assert(err, ErrorMatches, "pcap record of 4294967295 bytes exceeds 1048576 bytes")
+
+	data = pcapng(LinkTypeIEEE802154NoFCS, mac(nwkPlain, apsOnOff))
+	// the length of the enhanced packet block, after the section header and
+	// the interface description
+	binary.BigEndian.PutUint32(data[28+32+4:], 0xfffffff0)
+	r, _ = NewReader(bytes.NewReader(data), cluster.New())
+	_, err = r.Next()
+	c.Assert(err, ErrorMatches, "pcapng block with invalid length 4294967280")
+}
+
+func (s *CaptureSuite) TestTimestampResolutionOutOfRange(c *C) {
+	for _, resolution := range []uint8{0xc0, 0xff, 20} {
+		data := pcapng(LinkTypeIEEE802154NoFCS, mac(nwkPlain, apsOnOff))
+		// the if_tsresol option of the interface description
+		data[28+8+12] = resolution
+		r, _ := NewReader(bytes.NewReader(data), cluster.New())
+		_, err := r.Next()
+		c.Assert(err, ErrorMatches, "pcapng timestamp resolution 0x.. is out of range")
+	}
+}
+
+// TestCCM checks the cipher with packet vector #1 of RFC 3610.
+func (s *CaptureSuite) TestCCM(c *C) {
+	decode := func(s string) []uint8 {
+		b, _ := hex.DecodeString(s)
+		return b
+	}
+	key, err := ParseKey("c0:c1:c2:c3:c4:c5:c6:c7:c8:c9:ca:cb:cc:cd:ce:cf")
+	c.Assert(err, IsNil)
+	nonce := decode("00000003020100a0a1a2a3a4a5")
+	a := decode("0001020304050607")
+	plaintext := decode("08090a0b0c0d0e0f101112131415161718191a1b1c1d1e")
+	sealed := ccmSeal(key, nonce, a, plaintext, 8)
+	c.Assert(hex.EncodeToString(sealed), Equals, "588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0")
+	opened, ok := ccmOpen(key, nonce, a, sealed, 8)
+	c.Assert(ok, Equals, true)
+	c.Assert(opened, DeepEquals, plaintext)
+	sealed[0] ^= 1
+	_, ok = ccmOpen(key, nonce, a, sealed, 8)
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,96 @@
+package capture
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Key is a 128 bit Zigbee network or link key.
+type Key [16]byte
+
+// DefaultLinkKey is the well known trust center link key, "ZigBeeAlliance09".
+var DefaultLinkKey = Key{0x5a, 0x69, 0x67, 0x42, 0x65, 0x65, 0x41, 0x6c, 0x6c, 0x69, 0x61, 0x6e, 0x63, 0x65, 0x30, 0x39}
+
+// ParseKey parses a key written as hex, with or without separators, e.g.
+// "01:03:05:07:09:0b:0d:0f:00:02:04:06:08:0a:0c:0d".
+func ParseKey(s string) (Key, error) {
+	var key Key
+	b, err := hex.DecodeString(strings.NewReplacer(":", "", " ", "", "-", "", "0x", "").Replace(s))
+	if err != nil {
+		return key, fmt.Errorf("invalid key: %s", err)
+	}
+	if len(b) != len(key) {
+		return key, fmt.Errorf("invalid key: %d bytes, want %d", len(b), len(key))
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+// ccmOpen decrypts and authenticates a CCM* message with a 13 byte nonce and
+// a tagSize byte MIC at the end of ciphertext.
+func ccmOpen(key Key, nonce []uint8, a []uint8, ciphertext []uint8, tagSize int) ([]uint8, bool) {
+	if len(ciphertext) < tagSize {
+		return nil, false
+	}
+	block, _ := aes.NewCipher(key[:])
+	size := len(ciphertext) - tagSize
+	plaintext := make([]uint8, size)
+	ctr(block, nonce, 1, plaintext, ciphertext[:size])
+	tag := make([]uint8, tagSize)
+	ctr(block, nonce, 0, tag, ciphertext[size:])
+	expected := cbcMac(block, nonce, a, plaintext, tagSize)
+	return plaintext, subtle.ConstantTimeCompare(tag, expected) == 1
+}
+
+// ccmSeal encrypts and authenticates a CCM* message, appending the MIC.
+func ccmSeal(key Key, nonce []uint8, a []uint8, plaintext []uint8, tagSize int) []uint8 {
+	block, _ := aes.NewCipher(key[:])
+	sealed := make([]uint8, len(plaintext)+tagSize)
+	ctr(block, nonce, 1, sealed, plaintext)
+	ctr(block, nonce, 0, sealed[len(plaintext):], cbcMac(block, nonce, a, plaintext, tagSize))
+	return sealed
+}
+
+// ctr xors src with the key stream starting at counter block i.
+func ctr(block cipher.Block, nonce []uint8, i uint16, dst []uint8, src []uint8) {
+	var a, s [aes.BlockSize]uint8
+	a[0] = 1
+	copy(a[1:14], nonce)
+	for offset := 0; offset < len(src); offset += aes.BlockSize {
+		binary.BigEndian.PutUint16(a[14:], i)
+		block.Encrypt(s[:], a[:])
+		for j := offset; j < len(src) && j < offset+aes.BlockSize; j++ {
+			dst[j] = src[j] ^ s[j-offset]
+		}
+		i++
+	}
+}
+
+func cbcMac(block cipher.Block, nonce []uint8, a []uint8, m []uint8, tagSize int) []uint8 {
+	var x [aes.BlockSize]uint8
+	x[0] = uint8((tagSize-2)/2)<<3 | 1
+	if len(a) > 0 {
+		x[0] |= 0x40
+	}
+	copy(x[1:14], nonce)
+	binary.BigEndian.PutUint16(x[14:], uint16(len(m)))
+	block.Encrypt(x[:], x[:])
+	mac := func(data []uint8) {
+		for offset := 0; offset < len(data); offset += aes.BlockSize {
+			for j := offset; j < len(data) && j < offset+aes.BlockSize; j++ {
+				x[j-offset] ^= data[j]
+			}
+			block.Encrypt(x[:], x[:])
+		}
+	}
+	if len(a) > 0 {
+		mac(append([]uint8{uint8(len(a) >> 8), uint8(len(a))}, a...))
+	}
+	mac(m)
+	return append([]uint8(nil), x[:tagSize]...)
+}
@@ -0,0 +1,213 @@
+package capture
+
+import (
+	"encoding/binary"
+)
+
+const (
+	macFrameTypeData  = 0x01
+	macAddressingLong = 0x03
+	macBroadcast      = 0xffff
+)
+
+// macFrame is an IEEE 802.15.4 data frame.
+type macFrame struct {
+	sequence uint8
+	dstPan   uint16
+	dst      uint16
+	src      uint16
+	payload  []uint8
+}
+
+// unwrap finds the IEEE 802.15.4 frame in a captured record, with the FCS
+// removed, and the link quality if the encapsulation has it.
+func unwrap(r *record) (frame []uint8, lqi uint8, ok bool) {
+	data := r.data
+	switch r.linkType {
+	case LinkTypeIEEE802154WithFCS:
+		if len(data) < 2 {
+			return nil, 0, false
+		}
+		return data[:len(data)-2], 0, true
+	case LinkTypeIEEE802154NoFCS:
+		return data, 0, true
+	case LinkTypeIEEE802154TAP:
+		return unwrapTap(data)
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return nil, 0, false
+		}
+		etherType, offset := binary.BigEndian.Uint16(data[12:]), 14
+		for etherType == 0x8100 && len(data) >= offset+4 {
+			etherType, offset = binary.BigEndian.Uint16(data[offset+2:]), offset+4
+		}
+		return unwrapIP(etherType, data[offset:])
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, 0, false
+		}
+		return unwrapIP(binary.BigEndian.Uint16(data[14:]), data[16:])
+	case LinkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return nil, 0, false
+		}
+		return unwrapIP(binary.BigEndian.Uint16(data), data[20:])
+	case LinkTypeNull, LinkTypeLoop:
+		if len(data) < 4 {
+			return nil, 0, false
+		}
+		family := binary.LittleEndian.Uint32(data)
+		if r.linkType == LinkTypeLoop || family > 0xffff {
+			family = binary.BigEndian.Uint32(data)
+		}
+		switch family {
+		case 2:
+			return unwrapIP(0x0800, data[4:])
+		case 10, 24, 28, 30:
+			return unwrapIP(0x86dd, data[4:])
+		}
+	}
+	return nil, 0, false
+}
+
+// unwrapTap removes the TLV header of the IEEE 802.15.4 TAP encapsulation.
+func unwrapTap(data []uint8) ([]uint8, uint8, bool) {
+	if len(data) < 4 {
+		return nil, 0, false
+	}
+	size := int(binary.LittleEndian.Uint16(data[2:]))
+	if size < 4 || size > len(data) {
+		return nil, 0, false
+	}
+	fcsSize, lqi := 2, uint8(0)
+	for tlvs := data[4:size]; len(tlvs) >= 4; {
+		tlvType, tlvSize := binary.LittleEndian.Uint16(tlvs), int(binary.LittleEndian.Uint16(tlvs[2:]))
+		if 4+tlvSize > len(tlvs) {
+			break
+		}
+		switch {
+		case tlvType == tapFcsType && tlvSize >= 1:
+			fcsSize = []int{0, 2, 4}[tlvs[4]%3]
+		case tlvType == tapLqi && tlvSize >= 1:
+			lqi = tlvs[4]
+		}
+		tlvs = tlvs[4+(tlvSize+3)/4*4:]
+	}
+	frame := data[size:]
+	if len(frame) < fcsSize {
+		return nil, 0, false
+	}
+	return frame[:len(frame)-fcsSize], lqi, true
+}
+
+func unwrapIP(etherType uint16, packet []uint8) ([]uint8, uint8, bool) {
+	var udp []uint8
+	switch etherType {
+	case 0x0800:
+		if len(packet) < 20 || packet[9] != 17 {
+			return nil, 0, false
+		}
+		headerSize := int(packet[0]&0x0f) * 4
+		if len(packet) < headerSize {
+			return nil, 0, false
+		}
+		udp = packet[headerSize:]
+	case 0x86dd:
+		if len(packet) < 40 || packet[6] != 17 {
+			return nil, 0, false
+		}
+		udp = packet[40:]
+	default:
+		return nil, 0, false
+	}
+	if len(udp) < 8 || (binary.BigEndian.Uint16(udp) != ZepPort && binary.BigEndian.Uint16(udp[2:]) != ZepPort) {
+		return nil, 0, false
+	}
+	return unwrapZep(udp[8:])
+}
+
+// unwrapZep removes the header of a ZigBee Encapsulation Protocol data packet.
+// The two last bytes of the frame are the FCS, or the RSSI and LQI.
+func unwrapZep(zep []uint8) ([]uint8, uint8, bool) {
+	if len(zep) < 4 || zep[0] != 'E' || zep[1] != 'X' {
+		return nil, 0, false
+	}
+	var headerSize int
+	var lqi uint8
+	switch {
+	case zep[2] == 1 && len(zep) >= 16:
+		headerSize, lqi = 16, zep[7]
+	case zep[2] == 2 && zep[3] == 1 && len(zep) >= 32:
+		headerSize, lqi = 32, zep[8]
+	default:
+		return nil, 0, false
+	}
+	size := int(zep[headerSize-1])
+	if size < 2 || headerSize+size > len(zep) {
+		return nil, 0, false
+	}
+	return zep[headerSize : headerSize+size-2], lqi, true
+}
+
+// parseMac parses an unsecured IEEE 802.15.4 data frame with short
+// addresses, the only kind Zigbee sends data in.
+func parseMac(data []uint8) (*macFrame, bool) {
+	if len(data) < 3 {
+		return nil, false
+	}
+	control := binary.LittleEndian.Uint16(data)
+	if control&0x07 != macFrameTypeData || control&0x08 != 0 {
+		return nil, false
+	}
+	dstMode, srcMode := (control>>10)&0x03, (control>>14)&0x03
+	panIdCompression := control&0x40 != 0
+	f := &macFrame{sequence: data[2], dst: macBroadcast}
+	offset := 3
+	field := func(size int) (uint64, bool) {
+		if len(data) < offset+size {
+			return 0, false
+		}
+		var v uint64
+		for i := size - 1; i >= 0; i-- {
+			v = v<<8 | uint64(data[offset+i])
+		}
+		offset += size
+		return v, true
+	}
+	if dstMode != 0 {
+		pan, ok := field(2)
+		if !ok {
+			return nil, false
+		}
+		f.dstPan = uint16(pan)
+		dst, ok := field(addressSize(dstMode))
+		if !ok {
+			return nil, false
+		}
+		f.dst = uint16(dst)
+	}
+	if srcMode != 0 {
+		if !panIdCompression || dstMode == 0 {
+			if _, ok := field(2); !ok {
+				return nil, false
+			}
+		}
+		src, ok := field(addressSize(srcMode))
+		if !ok {
+			return nil, false
+		}
+		f.src = uint16(src)
+	}
+	if dstMode == macAddressingLong || srcMode == macAddressingLong {
+		return nil, false
+	}
+	f.payload = data[offset:]
+	return f, true
+}
+
+func addressSize(mode uint16) int {
+	if mode == macAddressingLong {
+		return 8
+	}
+	return 2
+}
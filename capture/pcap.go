@@ -0,0 +1,249 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Link types of the captures the reader understands.
+const (
+	LinkTypeNull                 uint32 = 0
+	LinkTypeEthernet             uint32 = 1
+	LinkTypeLoop                 uint32 = 108
+	LinkTypeLinuxSLL             uint32 = 113
+	LinkTypeIEEE802154WithFCS    uint32 = 195
+	LinkTypeIEEE802154NoFCS      uint32 = 230
+	LinkTypeLinuxSLL2            uint32 = 276
+	LinkTypeIEEE802154TAP        uint32 = 283
+	ZepPort                      uint16 = 17754
+	pcapMagicMicroseconds        uint32 = 0xa1b2c3d4
+	pcapMagicNanoseconds         uint32 = 0xa1b23c4d
+	pcapngSectionHeaderBlock     uint32 = 0x0a0d0d0a
+	pcapngInterfaceDescription   uint32 = 0x00000001
+	pcapngSimplePacketBlock      uint32 = 0x00000003
+	pcapngEnhancedPacketBlock    uint32 = 0x00000006
+	pcapngByteOrderMagic         uint32 = 0x1a2b3c4d
+	pcapngOptionTimestampResolve uint16 = 9
+	// maxLength bounds the records and blocks read from a capture, well above
+	// the 262144 bytes snapshot length of libpcap, so that a corrupt length
+	// doesn't allocate gigabytes.
+	maxLength = 1 << 20
+)
+
+// record is a captured link layer frame.
+type record struct {
+	time     time.Time
+	linkType uint32
+	data     []uint8
+}
+
+type recordReader interface {
+	next() (*record, error)
+}
+
+// newRecordReader detects the format of a pcap or pcapng file.
+func newRecordReader(r io.Reader) (recordReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("not a capture file: %s", err)
+	}
+	if binary.LittleEndian.Uint32(magic) == pcapngSectionHeaderBlock {
+		return &pcapngReader{r: br}, nil
+	}
+	return newPcapReader(br)
+}
+
+type pcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nanos    bool
+	linkType uint32
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	header := make([]uint8, 24)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("pcap header truncated: %s", err)
+	}
+	p := &pcapReader{r: r}
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicroseconds:
+		p.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicroseconds:
+		p.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNanoseconds:
+		p.order, p.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(header) == pcapMagicNanoseconds:
+		p.order, p.nanos = binary.BigEndian, true
+	default:
+		return nil, fmt.Errorf("not a capture file: magic 0x%08x", binary.BigEndian.Uint32(header))
+	}
+	p.linkType = p.order.Uint32(header[20:]) & 0xffff
+	return p, nil
+}
+
+func (p *pcapReader) next() (*record, error) {
+	header := make([]uint8, 16)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("pcap record header truncated")
+		}
+		return nil, err
+	}
+	size := p.order.Uint32(header[8:])
+	if size > maxLength {
+		return nil, fmt.Errorf("pcap record of %d bytes exceeds %d bytes", size, maxLength)
+	}
+	data := make([]uint8, size)
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return nil, fmt.Errorf("pcap record truncated: %s", err)
+	}
+	fraction := int64(p.order.Uint32(header[4:]))
+	if !p.nanos {
+		fraction *= 1000
+	}
+	t := time.Unix(int64(p.order.Uint32(header)), fraction)
+	return &record{t, p.linkType, data}, nil
+}
+
+type pcapngInterface struct {
+	linkType uint32
+	// unitsPerSecond is the timestamp resolution.
+	unitsPerSecond uint64
+}
+
+type pcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	interfaces []*pcapngInterface
+}
+
+func (p *pcapngReader) next() (*record, error) {
+	for {
+		blockType, body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		switch blockType {
+		case pcapngSectionHeaderBlock:
+			p.interfaces = nil
+		case pcapngInterfaceDescription:
+			if len(body) < 8 {
+				return nil, fmt.Errorf("pcapng interface description truncated")
+			}
+			unitsPerSecond, err := p.resolution(body[8:])
+			if err != nil {
+				return nil, err
+			}
+			p.interfaces = append(p.interfaces, &pcapngInterface{
+				linkType:       uint32(p.order.Uint16(body)),
+				unitsPerSecond: unitsPerSecond,
+			})
+		case pcapngEnhancedPacketBlock:
+			if len(body) < 20 {
+				return nil, fmt.Errorf("pcapng packet block truncated")
+			}
+			id := p.order.Uint32(body)
+			if int(id) >= len(p.interfaces) {
+				return nil, fmt.Errorf("pcapng packet of unknown interface %d", id)
+			}
+			size := int(p.order.Uint32(body[12:]))
+			if 20+size > len(body) {
+				return nil, fmt.Errorf("pcapng packet block truncated")
+			}
+			i := p.interfaces[id]
+			ticks := uint64(p.order.Uint32(body[4:]))<<32 | uint64(p.order.Uint32(body[8:]))
+			return &record{timestamp(ticks, i.unitsPerSecond), i.linkType, body[20 : 20+size]}, nil
+		case pcapngSimplePacketBlock:
+			if len(p.interfaces) == 0 || len(body) < 4 {
+				return nil, fmt.Errorf("pcapng simple packet block without interface")
+			}
+			size := int(p.order.Uint32(body))
+			if 4+size > len(body) {
+				size = len(body) - 4
+			}
+			return &record{time.Time{}, p.interfaces[0].linkType, body[4 : 4+size]}, nil
+		}
+	}
+}
+
+// block reads a block, returning its body without the lengths.
+func (p *pcapngReader) block() (uint32, []uint8, error) {
+	header := make([]uint8, 8)
+	if _, err := io.ReadFull(p.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("pcapng block header truncated")
+		}
+		return 0, nil, err
+	}
+	if binary.LittleEndian.Uint32(header) == pcapngSectionHeaderBlock {
+		magic := make([]uint8, 4)
+		if _, err := io.ReadFull(p.r, magic); err != nil {
+			return 0, nil, fmt.Errorf("pcapng section header truncated")
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+			p.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+			p.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("pcapng section header with invalid byte order magic")
+		}
+		header = append(header, magic...)
+	}
+	if p.order == nil {
+		return 0, nil, fmt.Errorf("pcapng block before section header")
+	}
+	size := p.order.Uint32(header[4:])
+	if size < uint32(len(header)+4) || size%4 != 0 || size > maxLength {
+		return 0, nil, fmt.Errorf("pcapng block with invalid length %d", size)
+	}
+	rest := make([]uint8, int(size)-len(header))
+	if _, err := io.ReadFull(p.r, rest); err != nil {
+		return 0, nil, fmt.Errorf("pcapng block truncated: %s", err)
+	}
+	return p.order.Uint32(header), rest[:len(rest)-4], nil
+}
+
+// resolution returns the timestamp units per second of an interface,
+// microseconds unless the if_tsresol option says otherwise.
+func (p *pcapngReader) resolution(options []uint8) (uint64, error) {
+	for len(options) >= 4 {
+		code, size := p.order.Uint16(options), int(p.order.Uint16(options[2:]))
+		if code == 0 || 4+size > len(options) {
+			break
+		}
+		if code == pcapngOptionTimestampResolve && size == 1 {
+			v := options[4]
+			// 2^63 and 10^19 are the largest resolutions which fit
+			if v&0x80 != 0 && v&0x7f < 64 {
+				return 1 << (v & 0x7f), nil
+			}
+			if v&0x80 == 0 && v < 20 {
+				units := uint64(1)
+				for i := uint8(0); i < v; i++ {
+					units *= 10
+				}
+				return units, nil
+			}
+			return 0, fmt.Errorf("pcapng timestamp resolution 0x%02x is out of range", v)
+		}
+		options = options[4+(size+3)/4*4:]
+	}
+	return 1000000, nil
+}
+
+func timestamp(ticks uint64, unitsPerSecond uint64) time.Time {
+	seconds, rest := ticks/unitsPerSecond, ticks%unitsPerSecond
+	var nanos uint64
+	if unitsPerSecond <= 1000000000 {
+		nanos = rest * (1000000000 / unitsPerSecond)
+	} else {
+		nanos = rest / (unitsPerSecond / 1000000000)
+	}
+	return time.Unix(int64(seconds), int64(nanos))
+}
@@ -0,0 +1,175 @@
+// Package capture reads ZCL messages from sniffer captures, pcap or pcapng
+// files of IEEE 802.15.4 frames, raw or encapsulated in ZEP over UDP, as
+// written by Wireshark and most Zigbee sniffers, and writes the messages of
+// a session as captures Wireshark can open.
+package capture
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+)
+
+// Packet is a ZCL message found in a capture.
+type Packet struct {
+	// Number is the position of the frame in the capture, starting at 1, as
+	// Wireshark numbers them.
+	Number    int
+	Time      time.Time
+	ProfileID uint16
+	DstAddr   uint16
+	Message   *zcl.ZclIncomingMessage
+	// Err is set if the ZCL payload couldn't be decoded, Message then holds
+	// what could be.
+	Err error
+}
+
+// Reader walks a capture and yields the APS data frames carrying ZCL.
+// Secured frames are decrypted with the keys added to the reader; the default
+// trust center link key is tried for APS security without being added.
+type Reader struct {
+	records     recordReader
+	zcl         *zcl.Zcl
+	networkKeys []Key
+	linkKeys    []Key
+	// addresses maps network addresses to extended ones, learned from the
+	// frames read so far, to build the nonce of APS secured frames.
+	addresses map[uint16][]uint8
+	number    int
+	encrypted int
+}
+
+func NewReader(r io.Reader, library *cluster.ClusterLibrary) (*Reader, error) {
+	records, err := newRecordReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		records:   records,
+		zcl:       zcl.NewWithLibrary(library),
+		linkKeys:  []Key{DefaultLinkKey},
+		addresses: map[uint16][]uint8{},
+	}, nil
+}
+
+func (r *Reader) AddNetworkKey(key Key) {
+	r.networkKeys = append(r.networkKeys, key)
+}
+
+func (r *Reader) AddLinkKey(key Key) {
+	r.linkKeys = append(r.linkKeys, key)
+}
+
+// Encrypted returns the number of secured frames skipped so far because none
+// of the keys could decrypt them.
+func (r *Reader) Encrypted() int {
+	return r.encrypted
+}
+
+// Next returns the next ZCL message in the capture, or io.EOF at its end.
+func (r *Reader) Next() (*Packet, error) {
+	for {
+		rec, err := r.records.next()
+		if err != nil {
+			return nil, err
+		}
+		r.number++
+		if p := r.packet(rec); p != nil {
+			return p, nil
+		}
+	}
+}
+
+// ReadAll returns the ZCL messages left in the capture.
+func (r *Reader) ReadAll() ([]*Packet, error) {
+	var packets []*Packet
+	for {
+		p, err := r.Next()
+		if err == io.EOF {
+			return packets, nil
+		}
+		if err != nil {
+			return packets, err
+		}
+		packets = append(packets, p)
+	}
+}
+
+func (r *Reader) packet(rec *record) *Packet {
+	data, lqi, ok := unwrap(rec)
+	if !ok {
+		return nil
+	}
+	mac, ok := parseMac(data)
+	if !ok {
+		return nil
+	}
+	nwk, ok := parseNwk(mac.payload)
+	if !ok {
+		return nil
+	}
+	if nwk.src64 != nil {
+		r.addresses[nwk.src] = nwk.src64
+	}
+	payload := mac.payload[nwk.headerSize:]
+	if s := nwk.security; s != nil {
+		// NWK security is applied hop by hop, by the MAC sender
+		source := s.source
+		if source != nil {
+			r.addresses[mac.src] = source
+		} else {
+			source = r.addresses[mac.src]
+		}
+		if payload, ok = decrypt(mac.payload, nwk.headerSize, s, source, r.networkKeys); !ok {
+			r.encrypted++
+			return nil
+		}
+	}
+	aps, ok := parseAps(payload)
+	if !ok {
+		return nil
+	}
+	apsPayload := payload[aps.headerSize:]
+	if s := aps.security; s != nil {
+		// APS security is applied end to end, by the NWK sender
+		source := s.source
+		if source == nil {
+			source = r.addresses[nwk.src]
+		}
+		var keys []Key
+		switch s.control & securityKeyIdMask {
+		case securityKeyIdLink:
+			keys = r.linkKeys
+		case securityKeyIdNetwork:
+			keys = r.networkKeys
+		}
+		if apsPayload, ok = decrypt(payload, aps.headerSize, s, source, keys); !ok {
+			r.encrypted++
+			return nil
+		}
+	}
+	if aps.profileId == 0 {
+		// ZDP, not ZCL
+		return nil
+	}
+	p := &Packet{Number: r.number, Time: rec.time, ProfileID: aps.profileId, DstAddr: nwk.dst}
+	wasBroadcast := nwk.dst >= nwkBroadcast || aps.control&apsDeliveryModeMask == apsDeliveryBroadcast
+	p.Message, p.Err = r.zcl.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		GroupID:        aps.group,
+		ClusterID:      aps.clusterId,
+		SrcAddr:        fmt.Sprintf("0x%04x", nwk.src),
+		SrcEndpoint:    aps.srcEndpoint,
+		DstEndpoint:    aps.dstEndpoint,
+		WasBroadcast:   frame.Flag(wasBroadcast),
+		LinkQuality:    lqi,
+		SecurityUse:    frame.Flag(aps.security != nil),
+		TransSeqNumber: aps.counter,
+		Data:           apsPayload,
+	})
+	return p
+}
@@ -0,0 +1,318 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+type Encapsulation int
+
+const (
+	// EncapsulationIEEE802154 writes IEEE 802.15.4 frames behind a TAP header
+	// carrying the channel and the link quality.
+	EncapsulationIEEE802154 Encapsulation = iota
+	// EncapsulationZep writes ZEP v2 packets over UDP, as remote sniffers
+	// send them.
+	EncapsulationZep
+)
+
+const (
+	pcapngOptionFlags    uint16 = 2
+	pcapngFlagsInbound   uint32 = 1
+	pcapngFlagsOutbound  uint32 = 2
+	defaultChannel              = 11
+	defaultEndpoint             = 1
+	defaultProfileId            = 0x0104
+	nwkBroadcastRxOnIdle        = 0xfffd
+	nwkRadius                   = 30
+	ntpEpochOffset              = 2208988800
+	tapHeaderSize               = 4
+	tapFcsType                  = 0
+	tapChannel                  = 3
+	tapLqi                      = 10
+)
+
+type WriterOptions struct {
+	Encapsulation Encapsulation
+	// Channel is the radio channel recorded with the frames, 11 if zero.
+	Channel uint8
+	PanId   uint16
+	// LocalAddr and LocalEndpoint address the device running the library,
+	// the coordinator and endpoint 1 by default.
+	LocalAddr     uint16
+	LocalEndpoint uint8
+	// ProfileId is the profile of the frames, Home Automation if zero.
+	ProfileId uint16
+}
+
+// Writer records the messages received and the frames sent by the library in
+// a pcapng file Wireshark can open. The MAC, NWK and APS headers are
+// synthesized from the addresses of the messages and are never secured.
+type Writer struct {
+	w           io.Writer
+	options     WriterOptions
+	mutex       sync.Mutex
+	macSequence uint8
+	nwkSequence uint8
+	apsCounter  uint8
+	zepSequence uint32
+}
+
+// apsMessage is a ZCL frame with the addresses to build its headers from.
+type apsMessage struct {
+	src         uint16
+	dst         uint16
+	group       uint16
+	srcEndpoint uint8
+	dstEndpoint uint8
+	clusterId   uint16
+	counter     uint8
+	lqi         uint8
+	data        []uint8
+}
+
+// NewWriter writes the section header and the interface description of the
+// capture.
+func NewWriter(w io.Writer, options WriterOptions) (*Writer, error) {
+	if options.Channel == 0 {
+		options.Channel = defaultChannel
+	}
+	if options.LocalEndpoint == 0 {
+		options.LocalEndpoint = defaultEndpoint
+	}
+	if options.ProfileId == 0 {
+		options.ProfileId = defaultProfileId
+	}
+	writer := &Writer{w: w, options: options}
+	linkType := LinkTypeIEEE802154TAP
+	if options.Encapsulation == EncapsulationZep {
+		linkType = LinkTypeEthernet
+	}
+	section := []uint8{0, 0, 0, 0, 1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	binary.LittleEndian.PutUint32(section, pcapngByteOrderMagic)
+	if err := writer.block(pcapngSectionHeaderBlock, section); err != nil {
+		return nil, err
+	}
+	// nanosecond timestamps
+	description := []uint8{0, 0, 0, 0, 0, 0, 0, 0, 9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(description, uint16(linkType))
+	if err := writer.block(pcapngInterfaceDescription, description); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// WriteIncoming records a message received by the library.
+func (w *Writer) WriteIncoming(t time.Time, m *zcl.ZclIncomingMessage) error {
+	src, err := parseAddress(m.SrcAddr)
+	if err != nil {
+		return err
+	}
+	if m.Data == nil {
+		return fmt.Errorf("message without frame")
+	}
+	data, err := zcl.Encode(m.Data)
+	if err != nil {
+		return err
+	}
+	dst := w.options.LocalAddr
+	if m.WasBroadcast || m.GroupID != 0 {
+		dst = nwkBroadcastRxOnIdle
+	}
+	return w.write(t, pcapngFlagsInbound, &apsMessage{
+		src:         src,
+		dst:         dst,
+		group:       m.GroupID,
+		srcEndpoint: m.SrcEndpoint,
+		dstEndpoint: m.DstEndpoint,
+		clusterId:   m.ClusterID,
+		counter:     m.TransactionSeqNumber,
+		lqi:         m.LinkQuality,
+		data:        data,
+	})
+}
+
+// WriteOutgoing records a frame sent by the library.
+func (w *Writer) WriteOutgoing(t time.Time, address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+	dst, err := parseAddress(address)
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	w.apsCounter++
+	counter := w.apsCounter
+	w.mutex.Unlock()
+	return w.write(t, pcapngFlagsOutbound, &apsMessage{
+		src:         w.options.LocalAddr,
+		dst:         dst,
+		srcEndpoint: w.options.LocalEndpoint,
+		dstEndpoint: endpoint,
+		clusterId:   uint16(clusterId),
+		counter:     counter,
+		lqi:         0xff,
+		data:        frame.Encode(f),
+	})
+}
+
+// RecordSender wraps a function sending frames, e.g. a pollcontrol.Sender,
+// to record the frames it sends successfully.
+func (w *Writer) RecordSender(send func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error) func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+	return func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		if err := send(address, endpoint, clusterId, f); err != nil {
+			return err
+		}
+		return w.WriteOutgoing(time.Now(), address, endpoint, clusterId, f)
+	}
+}
+
+func (w *Writer) write(t time.Time, flags uint32, a *apsMessage) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.macSequence++
+	w.nwkSequence++
+	mac := w.mac(a)
+	var packet []uint8
+	switch w.options.Encapsulation {
+	case EncapsulationZep:
+		w.zepSequence++
+		packet = w.zep(t, a.lqi, mac)
+	default:
+		packet = w.tap(a.lqi, mac)
+	}
+	body := make([]uint8, 20, 20+len(packet)+12)
+	ticks := uint64(t.UnixNano())
+	binary.LittleEndian.PutUint32(body[4:], uint32(ticks>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ticks))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(packet)))
+	body = append(body, pad(packet)...)
+	option := make([]uint8, 12)
+	binary.LittleEndian.PutUint16(option, pcapngOptionFlags)
+	binary.LittleEndian.PutUint16(option[2:], 4)
+	binary.LittleEndian.PutUint32(option[4:], flags)
+	return w.block(pcapngEnhancedPacketBlock, append(body, option...))
+}
+
+// mac builds the IEEE 802.15.4 frame of a message sent in a single hop,
+// without FCS.
+func (w *Writer) mac(a *apsMessage) []uint8 {
+	macDst := a.dst
+	if a.dst >= nwkBroadcast {
+		macDst = macBroadcast
+	}
+	f := make([]uint8, 9, 9+8+8+len(a.data))
+	binary.LittleEndian.PutUint16(f, 0x8841)
+	f[2] = w.macSequence
+	binary.LittleEndian.PutUint16(f[3:], w.options.PanId)
+	binary.LittleEndian.PutUint16(f[5:], macDst)
+	binary.LittleEndian.PutUint16(f[7:], a.src)
+	f = append(f, 0x08, 0x00, uint8(a.dst), uint8(a.dst>>8), uint8(a.src), uint8(a.src>>8), nwkRadius, w.nwkSequence)
+	switch {
+	case a.group != 0:
+		f = append(f, apsFrameTypeData|apsDeliveryGroup, uint8(a.group), uint8(a.group>>8))
+	case a.dst >= nwkBroadcast:
+		f = append(f, apsFrameTypeData|apsDeliveryBroadcast, a.dstEndpoint)
+	default:
+		f = append(f, apsFrameTypeData|apsDeliveryUnicast, a.dstEndpoint)
+	}
+	f = append(f, uint8(a.clusterId), uint8(a.clusterId>>8), uint8(w.options.ProfileId), uint8(w.options.ProfileId>>8), a.srcEndpoint, a.counter)
+	return append(f, a.data...)
+}
+
+func (w *Writer) tap(lqi uint8, mac []uint8) []uint8 {
+	tlvs := []uint8{
+		tapFcsType, 0, 1, 0, 1, 0, 0, 0,
+		tapChannel, 0, 3, 0, w.options.Channel, 0, 0, 0,
+		tapLqi, 0, 1, 0, lqi, 0, 0, 0,
+	}
+	header := []uint8{0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(header[2:], uint16(tapHeaderSize+len(tlvs)))
+	return append(append(append(header, tlvs...), mac...), fcs(mac)...)
+}
+
+// zep wraps a frame in ZEP v2 over UDP over IPv4 over Ethernet, between
+// loopback addresses.
+func (w *Writer) zep(t time.Time, lqi uint8, mac []uint8) []uint8 {
+	zep := make([]uint8, 32, 32+len(mac)+2)
+	copy(zep, "EX")
+	zep[2], zep[3], zep[4] = 2, 1, w.options.Channel
+	zep[8] = lqi
+	binary.BigEndian.PutUint32(zep[9:], uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(zep[13:], uint32(uint64(t.Nanosecond())<<32/1000000000))
+	binary.BigEndian.PutUint32(zep[17:], w.zepSequence)
+	zep[31] = uint8(len(mac) + 2)
+	zep = append(append(zep, mac...), fcs(mac)...)
+	udp := make([]uint8, 8, 8+len(zep))
+	binary.BigEndian.PutUint16(udp, ZepPort)
+	binary.BigEndian.PutUint16(udp[2:], ZepPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(zep)))
+	udp = append(udp, zep...)
+	ip := []uint8{0x45, 0, 0, 0, 0, 0, 0x40, 0, 64, 17, 0, 0, 127, 0, 0, 1, 127, 0, 0, 1}
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(ip)+len(udp)))
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+	ethernet := []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x08, 0x00}
+	return append(append(ethernet, ip...), udp...)
+}
+
+func (w *Writer) block(blockType uint32, body []uint8) error {
+	body = pad(body)
+	size := uint32(len(body) + 12)
+	b := make([]uint8, 8, size)
+	binary.LittleEndian.PutUint32(b, blockType)
+	binary.LittleEndian.PutUint32(b[4:], size)
+	b = append(append(b, body...), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[size-4:], size)
+	_, err := w.w.Write(b)
+	return err
+}
+
+func pad(b []uint8) []uint8 {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// fcs returns the frame check sequence of an IEEE 802.15.4 frame, an ITU-T
+// CRC-16 sent least significant byte first.
+func fcs(frame []uint8) []uint8 {
+	var crc uint16
+	for _, b := range frame {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return []uint8{uint8(crc), uint8(crc >> 8)}
+}
+
+func checksum(header []uint8) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func parseAddress(s string) (uint16, error) {
+	address, err := strconv.ParseUint(s, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid network address %q", s)
+	}
+	return uint16(address), nil
+}
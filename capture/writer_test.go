@@ -0,0 +1,109 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+type WriterSuite struct{}
+
+var _ = Suite(&WriterSuite{})
+
+func incoming(c *C, m *znp.AfIncomingMessage) *zcl.ZclIncomingMessage {
+	message, err := zcl.New().ToZclIncomingMessage(m)
+	c.Assert(err, IsNil)
+	return message
+}
+
+func (s *WriterSuite) roundTrip(c *C, encapsulation Encapsulation) {
+	b := &bytes.Buffer{}
+	w, err := NewWriter(b, WriterOptions{Encapsulation: encapsulation, PanId: 0x1a62})
+	c.Assert(err, IsNil)
+	report := incoming(c, &znp.AfIncomingMessage{
+		ClusterID:      0x0006,
+		SrcAddr:        "0x1234",
+		SrcEndpoint:    11,
+		DstEndpoint:    1,
+		LinkQuality:    180,
+		TransSeqNumber: 7,
+		Data:           []uint8{0x18, 0x05, 0x0a, 0x00, 0x00, 0x10, 0x01},
+	})
+	c.Assert(w.WriteIncoming(captureAt, report), IsNil)
+	group := incoming(c, &znp.AfIncomingMessage{GroupID: 0x0010, ClusterID: 0x0006, SrcAddr: "0x5678", SrcEndpoint: 1, Data: onCommand})
+	c.Assert(w.WriteIncoming(captureAt.Add(time.Second), group), IsNil)
+	on, err := frame.New().FrameType(frame.FrameTypeLocal).Direction(frame.DirectionClientServer).CommandId(0x01).Build()
+	c.Assert(err, IsNil)
+	c.Assert(w.WriteOutgoing(captureAt.Add(2*time.Second), "0x1234", 11, cluster.OnOff, on), IsNil)
+
+	_, packets := read(c, b.Bytes())
+	c.Assert(packets, HasLen, 3)
+
+	c.Assert(packets[0].Time.Equal(captureAt), Equals, true)
+	m := packets[0].Message
+	c.Assert(m.SrcAddr, Equals, "0x1234")
+	c.Assert(m.SrcEndpoint, Equals, uint8(11))
+	c.Assert(m.DstEndpoint, Equals, uint8(1))
+	c.Assert(m.LinkQuality, Equals, uint8(180))
+	c.Assert(m.TransactionSeqNumber, Equals, uint8(7))
+	c.Assert(m.WasBroadcast, Equals, false)
+	c.Assert(m.Data.CommandName, Equals, "ReportAttributes")
+	c.Assert(m.Data.Command, DeepEquals, report.Data.Command)
+	c.Assert(packets[0].ProfileID, Equals, uint16(0x0104))
+
+	c.Assert(packets[1].Time.Equal(captureAt.Add(time.Second)), Equals, true)
+	c.Assert(packets[1].Message.GroupID, Equals, uint16(0x0010))
+	c.Assert(packets[1].Message.WasBroadcast, Equals, true)
+	c.Assert(packets[1].Message.SrcAddr, Equals, "0x5678")
+
+	c.Assert(packets[2].DstAddr, Equals, uint16(0x1234))
+	c.Assert(packets[2].Message.SrcAddr, Equals, "0x0000")
+	c.Assert(packets[2].Message.SrcEndpoint, Equals, uint8(1))
+	c.Assert(packets[2].Message.DstEndpoint, Equals, uint8(11))
+	c.Assert(packets[2].Message.Data.CommandName, Equals, "On")
+}
+
+func (s *WriterSuite) TestRoundTripIEEE802154(c *C) {
+	s.roundTrip(c, EncapsulationIEEE802154)
+}
+
+func (s *WriterSuite) TestRoundTripZep(c *C) {
+	s.roundTrip(c, EncapsulationZep)
+}
+
+func (s *WriterSuite) TestRecordSender(c *C) {
+	b := &bytes.Buffer{}
+	w, err := NewWriter(b, WriterOptions{})
+	c.Assert(err, IsNil)
+	fail := true
+	send := w.RecordSender(func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		if fail {
+			return fmt.Errorf("no route")
+		}
+		return nil
+	})
+	on, _ := frame.New().FrameType(frame.FrameTypeLocal).Direction(frame.DirectionClientServer).CommandId(0x01).Build()
+	c.Assert(send("0x1234", 1, cluster.OnOff, on), ErrorMatches, "no route")
+	fail = false
+	c.Assert(send("0x1234", 1, cluster.OnOff, on), IsNil)
+	_, packets := read(c, b.Bytes())
+	c.Assert(packets, HasLen, 1)
+}
+
+func (s *WriterSuite) TestInvalidAddress(c *C) {
+	w, err := NewWriter(&bytes.Buffer{}, WriterOptions{})
+	c.Assert(err, IsNil)
+	err = w.WriteIncoming(captureAt, &zcl.ZclIncomingMessage{SrcAddr: "coordinator"})
+	c.Assert(err, ErrorMatches, `invalid network address "coordinator"`)
+}
+
+// TestFcs checks the CRC with the check value of CRC-16/KERMIT.
+func (s *WriterSuite) TestFcs(c *C) {
+	c.Assert(fcs([]uint8("123456789")), DeepEquals, []uint8{0x89, 0x21})
+}
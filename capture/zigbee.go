@@ -0,0 +1,201 @@
+package capture
+
+import (
+	"encoding/binary"
+)
+
+const (
+	nwkFrameTypeData     = 0x0000
+	nwkProtocolVersionGP = 3
+	nwkMulticast         = 0x0100
+	nwkSecurity          = 0x0200
+	nwkSourceRoute       = 0x0400
+	nwkDstIEEE           = 0x0800
+	nwkSrcIEEE           = 0x1000
+	nwkBroadcast         = 0xfff8
+
+	apsFrameTypeData      = 0x00
+	apsDeliveryUnicast    = 0x00
+	apsDeliveryBroadcast  = 0x08
+	apsDeliveryGroup      = 0x0c
+	apsSecurity           = 0x20
+	apsExtendedHeader     = 0x80
+	apsFragmentationMask  = 0x03
+	apsDeliveryModeMask   = 0x0c
+	apsFrameTypeMask      = 0x03
+	securityLevel         = 0x05
+	securityLevelMask     = 0x07
+	securityKeyIdMask     = 0x18
+	securityKeyIdNetwork  = 0x08
+	securityKeyIdLink     = 0x00
+	securityExtendedNonce = 0x20
+	micSize               = 4
+)
+
+// securityHeader is the auxiliary header of a NWK or APS secured frame.
+type securityHeader struct {
+	control uint8
+	counter uint32
+	// source is the extended address of the device that secured the frame,
+	// in transmission order, or nil if the header doesn't carry it.
+	source []uint8
+	size   int
+}
+
+type nwkFrame struct {
+	control    uint16
+	dst        uint16
+	src        uint16
+	src64      []uint8
+	headerSize int
+	security   *securityHeader
+}
+
+type apsFrame struct {
+	control     uint8
+	dstEndpoint uint8
+	group       uint16
+	clusterId   uint16
+	profileId   uint16
+	srcEndpoint uint8
+	counter     uint8
+	headerSize  int
+	security    *securityHeader
+}
+
+// parseNwk parses the header of a Zigbee NWK data frame.
+func parseNwk(data []uint8) (*nwkFrame, bool) {
+	if len(data) < 8 {
+		return nil, false
+	}
+	control := binary.LittleEndian.Uint16(data)
+	if control&0x03 != nwkFrameTypeData || (control>>2)&0x0f == nwkProtocolVersionGP {
+		return nil, false
+	}
+	f := &nwkFrame{
+		control: control,
+		dst:     binary.LittleEndian.Uint16(data[2:]),
+		src:     binary.LittleEndian.Uint16(data[4:]),
+	}
+	offset := 8
+	if control&nwkDstIEEE != 0 {
+		offset += 8
+	}
+	if control&nwkSrcIEEE != 0 {
+		if len(data) < offset+8 {
+			return nil, false
+		}
+		f.src64 = data[offset : offset+8]
+		offset += 8
+	}
+	if control&nwkMulticast != 0 {
+		offset++
+	}
+	if control&nwkSourceRoute != 0 {
+		if len(data) < offset+2 {
+			return nil, false
+		}
+		offset += 2 + 2*int(data[offset])
+	}
+	if len(data) < offset {
+		return nil, false
+	}
+	f.headerSize = offset
+	if control&nwkSecurity != 0 {
+		s, ok := parseSecurityHeader(data[offset:])
+		if !ok {
+			return nil, false
+		}
+		f.security = s
+	}
+	return f, true
+}
+
+// parseAps parses the header of an APS data frame, skipping fragments which
+// can't be decoded on their own.
+func parseAps(data []uint8) (*apsFrame, bool) {
+	if len(data) < 1 || data[0]&apsFrameTypeMask != apsFrameTypeData {
+		return nil, false
+	}
+	f := &apsFrame{control: data[0]}
+	offset := 1
+	switch data[0] & apsDeliveryModeMask {
+	case apsDeliveryUnicast, apsDeliveryBroadcast:
+		if len(data) < offset+1 {
+			return nil, false
+		}
+		f.dstEndpoint = data[offset]
+		offset++
+	case apsDeliveryGroup:
+		if len(data) < offset+2 {
+			return nil, false
+		}
+		f.group = binary.LittleEndian.Uint16(data[offset:])
+		offset += 2
+	}
+	if len(data) < offset+6 {
+		return nil, false
+	}
+	f.clusterId = binary.LittleEndian.Uint16(data[offset:])
+	f.profileId = binary.LittleEndian.Uint16(data[offset+2:])
+	f.srcEndpoint = data[offset+4]
+	f.counter = data[offset+5]
+	offset += 6
+	if f.control&apsExtendedHeader != 0 {
+		if len(data) < offset+1 || data[offset]&apsFragmentationMask != 0 {
+			return nil, false
+		}
+		offset++
+	}
+	f.headerSize = offset
+	if f.control&apsSecurity != 0 {
+		s, ok := parseSecurityHeader(data[offset:])
+		if !ok {
+			return nil, false
+		}
+		f.security = s
+	}
+	return f, true
+}
+
+func parseSecurityHeader(data []uint8) (*securityHeader, bool) {
+	if len(data) < 5 {
+		return nil, false
+	}
+	s := &securityHeader{control: data[0], counter: binary.LittleEndian.Uint32(data[1:]), size: 5}
+	if s.control&securityExtendedNonce != 0 {
+		if len(data) < s.size+8 {
+			return nil, false
+		}
+		s.source = data[s.size : s.size+8]
+		s.size += 8
+	}
+	if s.control&securityKeyIdMask == securityKeyIdNetwork {
+		s.size++
+	}
+	return s, len(data) >= s.size
+}
+
+// decrypt opens the payload of a secured frame whose auxiliary header starts
+// at headerSize, trying every key. Frames are sent with the security level
+// cleared, so the level in use, ENC-MIC-32, is restored in the nonce and in
+// the authenticated header.
+func decrypt(data []uint8, headerSize int, s *securityHeader, source []uint8, keys []Key) ([]uint8, bool) {
+	end := headerSize + s.size
+	if len(source) != 8 || len(data) < end+micSize {
+		return nil, false
+	}
+	control := s.control&^securityLevelMask | securityLevel
+	a := append([]uint8{}, data[:end]...)
+	a[headerSize] = control
+	nonce := make([]uint8, 13)
+	copy(nonce, source)
+	binary.LittleEndian.PutUint32(nonce[8:], s.counter)
+	nonce[12] = control
+	for _, key := range keys {
+		if payload, ok := ccmOpen(key, nonce, a, data[end:], micSize); ok {
+			return payload, true
+		}
+	}
+	return nil, false
+}
@@ -1,14 +1,35 @@
 package cluster
 
+import (
+	"sync"
+
+	"github.com/dyrkin/zcl-go/frame"
+)
+
 type AttributeDescriptor struct {
 	Name   string
 	Type   ZclDataType
 	Access Access
+	// Mandatory attributes must be supported by every server of the cluster.
+	Mandatory bool
+	// Default, Min and Max hold values of the Go type an Attribute of Type
+	// decodes to, e.g. uint64 for unsigned integers. Nil means unspecified.
+	Default     interface{}
+	Min         interface{}
+	Max         interface{}
+	Unit        string
+	Description string
+	// Enum and Bitmap name the values of enumerated and bitmap attributes.
+	Enum   Enum
+	Bitmap Bitmap
 }
 
 type CommandDescriptor struct {
 	Name    string
 	Command interface{}
+	// Response marks commands answering another one, e.g.
+	// IdentifyQueryResponse, which get no DefaultResponse.
+	Response bool
 }
 
 type CommandDescriptors struct {
@@ -20,11 +41,14 @@ type Cluster struct {
 	Name                 string
 	AttributeDescriptors map[uint16]*AttributeDescriptor
 	CommandDescriptors   *CommandDescriptors
+	AlarmDescriptors     map[uint8]string
 }
 
 type ClusterLibrary struct {
-	global   map[uint8]*CommandDescriptor
-	clusters map[ClusterId]*Cluster
+	mutex         sync.RWMutex
+	global        map[uint8]*CommandDescriptor
+	clusters      map[ClusterId]*Cluster
+	manufacturers map[uint16]map[ClusterId]*Cluster
 }
 
 type Access uint8
@@ -45,200 +69,623 @@ const (
 	Identify                       ClusterId = 0x0003
 	OnOff                          ClusterId = 0x0006
 	LevelControl                   ClusterId = 0x0008
+	Alarms                         ClusterId = 0x0009
+	AnalogInput                    ClusterId = 0x000c
+	AnalogOutput                   ClusterId = 0x000d
+	AnalogValue                    ClusterId = 0x000e
+	BinaryInput                    ClusterId = 0x000f
+	BinaryOutput                   ClusterId = 0x0010
+	BinaryValue                    ClusterId = 0x0011
 	MultistateInput                ClusterId = 0x0012
+	MultistateOutput               ClusterId = 0x0013
+	MultistateValue                ClusterId = 0x0014
 	OTA                            ClusterId = 0x0019
+	PollControl                    ClusterId = 0x0020
+	GreenPower                     ClusterId = 0x0021
+	TouchlinkCommissioning         ClusterId = 0x1000
+	Tuya                           ClusterId = 0xef00
+	PhilipsHueSwitch               ClusterId = 0xfc00
+	IkeaManufacturerSpecific       ClusterId = 0xfc7c
 )
 
 func New() *ClusterLibrary {
 	return &ClusterLibrary{
-		global: map[uint8]*CommandDescriptor{
-			0x00: {"ReadAttributes", &ReadAttributesCommand{}},
-			0x01: {"ReadAttributesResponse", &ReadAttributesResponse{}},
-			0x02: {"WriteAttributes", &WriteAttributesCommand{}},
-			0x03: {"WriteAttributesUndivided", &WriteAttributesUndividedCommand{}},
-			0x04: {"WriteAttributesResponse", &WriteAttributesResponse{}},
-			0x05: {"WriteAttributesNoResponse", &WriteAttributesNoResponseCommand{}},
-			0x06: {"ConfigureReporting", &ConfigureReportingCommand{}},
-			0x07: {"ConfigureReportingResponse", &ConfigureReportingResponse{}},
-			0x08: {"ReadReportingConfiguration", &ReadReportingConfigurationCommand{}},
-			0x09: {"ReadReportingConfigurationResponse", &ReadReportingConfigurationResponse{}},
-			0x0a: {"ReportAttributes", &ReportAttributesCommand{}},
-			0x0b: {"DefaultResponse", &DefaultResponseCommand{}},
-			0x0c: {"DiscoverAttributes", &DiscoverAttributesCommand{}},
-			0x0d: {"DiscoverAttributesResponse", &DiscoverAttributesResponse{}},
-			0x0e: {"ReadAttributesStructured", &ReadAttributesStructuredCommand{}},
-			0x0f: {"WriteAttributesStructured", &WriteAttributesStructuredCommand{}},
-			0x10: {"WriteAttributesStructuredResponse", &WriteAttributesStructuredResponse{}},
-			0x11: {"DiscoverCommandsReceived", &DiscoverCommandsReceivedCommand{}},
-			0x12: {"DiscoverCommandsReceivedResponse", &DiscoverCommandsReceivedResponse{}},
-			0x13: {"DiscoverCommandsGenerated", &DiscoverCommandsGeneratedCommand{}},
-			0x14: {"DiscoverCommandsGeneratedResponse", &DiscoverCommandsGeneratedResponse{}},
-			0x15: {"DiscoverAttributesExtended", &DiscoverAttributesExtendedCommand{}},
-			0x16: {"DiscoverAttributesExtendedResponse", &DiscoverAttributesExtendedResponse{}},
-		},
+		global: globalCommands(),
 		clusters: map[ClusterId]*Cluster{
 			Basic: {
 				Name: "Basic",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"ZLibraryVersion", ZclDataTypeUint8, Read},
-					0x0001: {"ApplicationVersion", ZclDataTypeUint8, Read},
-					0x0002: {"StackVersion", ZclDataTypeUint8, Read},
-					0x0003: {"HWVersion", ZclDataTypeUint8, Read},
-					0x0004: {"ManufacturerName", ZclDataTypeCharStr, Read},
-					0x0005: {"ModelIdentifier", ZclDataTypeCharStr, Read},
-					0x0006: {"DateCode", ZclDataTypeCharStr, Read},
-					0x0007: {"PowerSource", ZclDataTypeEnum8, Read},
-					0x0010: {"LocationDescription", ZclDataTypeCharStr, Read | Write},
-					0x0011: {"PhysicalEnvironment", ZclDataTypeEnum8, Read | Write},
-					0x0012: {"DeviceEnabled", ZclDataTypeBoolean, Read | Write},
-					0x0013: {"AlarmMask", ZclDataTypeBitmap8, Read | Write},
-					0x0014: {"DisableLocalConfig", ZclDataTypeBitmap8, Read | Write},
-					0x4000: {"SWBuildID", ZclDataTypeCharStr, Read},
+					0x0000: {Name: "ZLibraryVersion", Type: ZclDataTypeUint8, Access: Read, Mandatory: true, Default: uint64(0x08)},
+					0x0001: {Name: "ApplicationVersion", Type: ZclDataTypeUint8, Access: Read},
+					0x0002: {Name: "StackVersion", Type: ZclDataTypeUint8, Access: Read},
+					0x0003: {Name: "HWVersion", Type: ZclDataTypeUint8, Access: Read},
+					0x0004: {Name: "ManufacturerName", Type: ZclDataTypeCharStr, Access: Read},
+					0x0005: {Name: "ModelIdentifier", Type: ZclDataTypeCharStr, Access: Read},
+					0x0006: {Name: "DateCode", Type: ZclDataTypeCharStr, Access: Read},
+					0x0007: {Name: "PowerSource", Type: ZclDataTypeEnum8, Access: Read, Mandatory: true, Default: uint64(0x00), Enum: PowerSourceEnum},
+					0x0010: {Name: "LocationDescription", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0011: {Name: "PhysicalEnvironment", Type: ZclDataTypeEnum8, Access: Read | Write, Default: uint64(0x00), Enum: PhysicalEnvironmentEnum},
+					0x0012: {Name: "DeviceEnabled", Type: ZclDataTypeBoolean, Access: Read | Write, Default: true},
+					0x0013: {Name: "AlarmMask", Type: ZclDataTypeBitmap8, Access: Read | Write, Default: uint64(0x00), Max: uint64(0x03), Bitmap: AlarmMaskBitmap},
+					0x0014: {Name: "DisableLocalConfig", Type: ZclDataTypeBitmap8, Access: Read | Write, Default: uint64(0x00), Max: uint64(0x03), Bitmap: DisableLocalConfigBitmap},
+					0x4000: {Name: "SWBuildID", Type: ZclDataTypeCharStr, Access: Read},
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"ResetToFactoryDefaults", &ResetToFactoryDefaultsCommand{}},
+						0x00: {Name: "ResetToFactoryDefaults", Command: &ResetToFactoryDefaultsCommand{}},
 					},
 				},
+				AlarmDescriptors: map[uint8]string{
+					0x00: "GeneralHardwareFault",
+					0x01: "GeneralSoftwareFault",
+				},
 			},
 			PowerConfiguration: {
 				Name: "PowerConfiguration",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"MainsVoltage", ZclDataTypeUint16, Read},
-					0x0001: {"MainsFrequency", ZclDataTypeUint8, Read},
-					0x0010: {"MainsAlarmMask", ZclDataTypeBitmap8, Read | Write},
-					0x0011: {"MainsVoltageMinThreshold", ZclDataTypeUint16, Read | Write},
-					0x0012: {"MainsVoltageMaxThreshold", ZclDataTypeUint16, Read | Write},
-					0x0013: {"MainsVoltageDwellTripPoint", ZclDataTypeUint16, Read | Write},
-					0x0020: {"BatteryVoltage", ZclDataTypeUint8, Read},
-					0x0021: {"BatteryPercentageRemaining", ZclDataTypeUint8, Read | Reportable},
-					0x0030: {"BatteryManufacturer", ZclDataTypeCharStr, Read | Write},
-					0x0031: {"BatterySize", ZclDataTypeEnum8, Read | Write},
-					0x0032: {"BatteryAHrRating", ZclDataTypeUint16, Read | Write},
-					0x0033: {"BatteryQuantity", ZclDataTypeUint8, Read | Write},
-					0x0034: {"BatteryRatedVoltage", ZclDataTypeUint8, Read | Write},
-					0x0035: {"BatteryAlarmMask", ZclDataTypeBitmap8, Read | Write},
-					0x0036: {"BatteryVoltageMinThreshold", ZclDataTypeUint8, Read | Write},
-					0x0037: {"BatteryVoltageThreshold1", ZclDataTypeUint8, Read | Write},
-					0x0038: {"BatteryVoltageThreshold2", ZclDataTypeUint8, Read | Write},
-					0x0039: {"BatteryVoltageThreshold3", ZclDataTypeUint8, Read | Write},
-					0x003a: {"BatteryPercentageMinThreshold", ZclDataTypeUint8, Read | Write},
-					0x003b: {"BatteryPercentageThreshold1", ZclDataTypeUint8, Read | Write},
-					0x003c: {"BatteryPercentageThreshold2", ZclDataTypeUint8, Read | Write},
-					0x003d: {"BatteryPercentageThreshold3", ZclDataTypeUint8, Read | Write},
-					0x003e: {"BatteryAlarmState", ZclDataTypeBitmap32, Read},
+					0x0000: {Name: "MainsVoltage", Type: ZclDataTypeUint16, Access: Read, Unit: "100 mV"},
+					0x0001: {Name: "MainsFrequency", Type: ZclDataTypeUint8, Access: Read},
+					0x0010: {Name: "MainsAlarmMask", Type: ZclDataTypeBitmap8, Access: Read | Write, Bitmap: MainsAlarmMaskBitmap},
+					0x0011: {Name: "MainsVoltageMinThreshold", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0012: {Name: "MainsVoltageMaxThreshold", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0013: {Name: "MainsVoltageDwellTripPoint", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0020: {Name: "BatteryVoltage", Type: ZclDataTypeUint8, Access: Read, Unit: "100 mV"},
+					0x0021: {Name: "BatteryPercentageRemaining", Type: ZclDataTypeUint8, Access: Read | Reportable, Default: uint64(0), Max: uint64(0xc8), Unit: "0.5 %"},
+					0x0030: {Name: "BatteryManufacturer", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0031: {Name: "BatterySize", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: BatterySizeEnum},
+					0x0032: {Name: "BatteryAHrRating", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0033: {Name: "BatteryQuantity", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0034: {Name: "BatteryRatedVoltage", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0035: {Name: "BatteryAlarmMask", Type: ZclDataTypeBitmap8, Access: Read | Write, Bitmap: BatteryAlarmMaskBitmap},
+					0x0036: {Name: "BatteryVoltageMinThreshold", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0037: {Name: "BatteryVoltageThreshold1", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0038: {Name: "BatteryVoltageThreshold2", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0039: {Name: "BatteryVoltageThreshold3", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x003a: {Name: "BatteryPercentageMinThreshold", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x003b: {Name: "BatteryPercentageThreshold1", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x003c: {Name: "BatteryPercentageThreshold2", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x003d: {Name: "BatteryPercentageThreshold3", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x003e: {Name: "BatteryAlarmState", Type: ZclDataTypeBitmap32, Access: Read},
+				},
+				AlarmDescriptors: map[uint8]string{
+					0x00: "MainsVoltageTooLow",
+					0x01: "MainsVoltageTooHigh",
+					0x10: "BatteryVoltageMinThresholdReached",
+					0x11: "BatteryVoltageThreshold1Reached",
+					0x12: "BatteryVoltageThreshold2Reached",
+					0x13: "BatteryVoltageThreshold3Reached",
+					0x20: "Battery2VoltageMinThresholdReached",
+					0x21: "Battery2VoltageThreshold1Reached",
+					0x22: "Battery2VoltageThreshold2Reached",
+					0x23: "Battery2VoltageThreshold3Reached",
+					0x30: "Battery3VoltageMinThresholdReached",
+					0x31: "Battery3VoltageThreshold1Reached",
+					0x32: "Battery3VoltageThreshold2Reached",
+					0x33: "Battery3VoltageThreshold3Reached",
+					0x3a: "MainsPowerSupplyLost",
 				},
 			},
 			DeviceTemperatureConfiguration: {
 				Name: "DeviceTemperatureConfiguration",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"CurrentTemperature", ZclDataTypeInt16, Read},
-					0x0001: {"MinTempExperienced", ZclDataTypeInt16, Read},
-					0x0002: {"MaxTempExperienced", ZclDataTypeInt16, Read},
-					0x0003: {"OverTempTotalDwell", ZclDataTypeInt16, Read},
-					0x0010: {"DeviceTempAlarmMask", ZclDataTypeBitmap16, Read | Write},
-					0x0011: {"LowTempThreshold", ZclDataTypeInt16, Read | Write},
-					0x0012: {"HighTempThreshold", ZclDataTypeInt16, Read | Write},
-					0x0013: {"LowTempDwellTripPoint", ZclDataTypeUint24, Read | Write},
-					0x0014: {"HighTempDwellTripPoint", ZclDataTypeUint24, Read | Write},
+					0x0000: {Name: "CurrentTemperature", Type: ZclDataTypeInt16, Access: Read, Mandatory: true, Min: int64(-200), Max: int64(200), Unit: "°C"},
+					0x0001: {Name: "MinTempExperienced", Type: ZclDataTypeInt16, Access: Read},
+					0x0002: {Name: "MaxTempExperienced", Type: ZclDataTypeInt16, Access: Read},
+					0x0003: {Name: "OverTempTotalDwell", Type: ZclDataTypeInt16, Access: Read},
+					0x0010: {Name: "DeviceTempAlarmMask", Type: ZclDataTypeBitmap16, Access: Read | Write, Bitmap: DeviceTempAlarmMaskBitmap},
+					0x0011: {Name: "LowTempThreshold", Type: ZclDataTypeInt16, Access: Read | Write},
+					0x0012: {Name: "HighTempThreshold", Type: ZclDataTypeInt16, Access: Read | Write},
+					0x0013: {Name: "LowTempDwellTripPoint", Type: ZclDataTypeUint24, Access: Read | Write},
+					0x0014: {Name: "HighTempDwellTripPoint", Type: ZclDataTypeUint24, Access: Read | Write},
+				},
+				AlarmDescriptors: map[uint8]string{
+					0x00: "DeviceTemperatureTooLow",
+					0x01: "DeviceTemperatureTooHigh",
 				},
 			},
 			Identify: {
 				Name: "Identify",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"IdentifyTime", ZclDataTypeInt16, Read | Write},
+					0x0000: {Name: "IdentifyTime", Type: ZclDataTypeInt16, Access: Read | Write, Mandatory: true, Default: int64(0), Unit: "s"},
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"Identify", &IdentifyCommand{}},
-						0x01: {"IdentifyQuery", &IdentifyQueryCommand{}},
-						0x40: {"TriggerEffect ", &TriggerEffectCommand{}},
+						0x00: {Name: "Identify", Command: &IdentifyCommand{}},
+						0x01: {Name: "IdentifyQuery", Command: &IdentifyQueryCommand{}},
+						0x40: {Name: "TriggerEffect ", Command: &TriggerEffectCommand{}},
 					},
 					Generated: map[uint8]*CommandDescriptor{
-						0x00: {"IdentifyQueryResponse ", &IdentifyQueryResponse{}},
+						0x00: {Name: "IdentifyQueryResponse ", Command: &IdentifyQueryResponse{}, Response: true},
 					},
 				},
 			},
 			OnOff: {
 				Name: "OnOff",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"OnOff", ZclDataTypeBoolean, Read | Reportable | Scene},
-					0x4000: {"GlobalSceneControl", ZclDataTypeBoolean, Read},
-					0x4001: {"OnTime", ZclDataTypeUint16, Read | Write},
-					0x4002: {"OffWaitTime", ZclDataTypeUint16, Read | Write},
+					0x0000: {Name: "OnOff", Type: ZclDataTypeBoolean, Access: Read | Reportable | Scene, Mandatory: true, Default: false},
+					0x4000: {Name: "GlobalSceneControl", Type: ZclDataTypeBoolean, Access: Read, Default: true},
+					0x4001: {Name: "OnTime", Type: ZclDataTypeUint16, Access: Read | Write, Default: uint64(0), Unit: "1/10 s"},
+					0x4002: {Name: "OffWaitTime", Type: ZclDataTypeUint16, Access: Read | Write, Default: uint64(0), Unit: "1/10 s"},
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"Off", &OffCommand{}},
-						0x01: {"On", &OnCommand{}},
-						0x02: {"Toggle ", &ToggleCommand{}},
-						0x40: {"OffWithEffect ", &OffWithEffectCommand{}},
-						0x41: {"OnWithRecallGlobalScene ", &OnWithRecallGlobalSceneCommand{}},
-						0x42: {"OnWithTimedOff ", &OnWithTimedOffCommand{}},
+						0x00: {Name: "Off", Command: &OffCommand{}},
+						0x01: {Name: "On", Command: &OnCommand{}},
+						0x02: {Name: "Toggle ", Command: &ToggleCommand{}},
+						0x40: {Name: "OffWithEffect ", Command: &OffWithEffectCommand{}},
+						0x41: {Name: "OnWithRecallGlobalScene ", Command: &OnWithRecallGlobalSceneCommand{}},
+						0x42: {Name: "OnWithTimedOff ", Command: &OnWithTimedOffCommand{}},
 					},
 				},
 			},
 			LevelControl: {
 				Name: "LevelControl",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"CurrentLevel", ZclDataTypeUint8, Read | Reportable},
-					0x0001: {"RemainingTime", ZclDataTypeUint16, Read},
-					0x0010: {"OnOffTransitionTime", ZclDataTypeUint16, Read | Write},
-					0x0011: {"OnLevel", ZclDataTypeUint8, Read | Write},
-					0x0012: {"OnTransitionTime", ZclDataTypeUint16, Read | Write},
-					0x0013: {"OffTransitionTime", ZclDataTypeUint16, Read | Write},
-					0x0014: {"DefaultMoveRate", ZclDataTypeUint16, Read | Write},
+					0x0000: {Name: "CurrentLevel", Type: ZclDataTypeUint8, Access: Read | Reportable, Mandatory: true, Default: uint64(0x00), Max: uint64(0xfe)},
+					0x0001: {Name: "RemainingTime", Type: ZclDataTypeUint16, Access: Read, Default: uint64(0), Unit: "1/10 s"},
+					0x0010: {Name: "OnOffTransitionTime", Type: ZclDataTypeUint16, Access: Read | Write, Default: uint64(0), Unit: "1/10 s"},
+					0x0011: {Name: "OnLevel", Type: ZclDataTypeUint8, Access: Read | Write, Default: uint64(0xff)},
+					0x0012: {Name: "OnTransitionTime", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0013: {Name: "OffTransitionTime", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0014: {Name: "DefaultMoveRate", Type: ZclDataTypeUint16, Access: Read | Write},
+				},
+				CommandDescriptors: &CommandDescriptors{
+					Received: map[uint8]*CommandDescriptor{
+						0x00: {Name: "MoveToLevel ", Command: &MoveToLevelCommand{}},
+						0x01: {Name: "Move", Command: &MoveCommand{}},
+						0x02: {Name: "Step ", Command: &StepCommand{}},
+						0x03: {Name: "Stop ", Command: &StopCommand{}},
+						0x04: {Name: "MoveToLevel/OnOff", Command: &MoveToLevelOnOffCommand{}},
+						0x05: {Name: "Move/OnOff", Command: &MoveOnOffCommand{}},
+						0x06: {Name: "Step/OnOff", Command: &StepOnOffCommand{}},
+						0x07: {Name: "Stop/OnOff", Command: &StopOnOffCommand{}},
+					},
+				},
+			},
+			Alarms: {
+				Name: "Alarms",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0000: {Name: "AlarmCount", Type: ZclDataTypeUint16, Access: Read},
 				},
 				CommandDescriptors: &CommandDescriptors{
 					Received: map[uint8]*CommandDescriptor{
-						0x00: {"MoveToLevel ", &MoveToLevelCommand{}},
-						0x01: {"Move", &MoveCommand{}},
-						0x02: {"Step ", &StepCommand{}},
-						0x03: {"Stop ", &StopCommand{}},
-						0x04: {"MoveToLevel/OnOff", &MoveToLevelOnOffCommand{}},
-						0x05: {"Move/OnOff", &MoveOnOffCommand{}},
-						0x06: {"Step/OnOff", &StepOnOffCommand{}},
-						0x07: {"Stop/OnOff", &StopOnOffCommand{}},
+						0x00: {Name: "ResetAlarm", Command: &ResetAlarmCommand{}},
+						0x01: {Name: "ResetAllAlarms", Command: &ResetAllAlarmsCommand{}},
+						0x02: {Name: "GetAlarm", Command: &GetAlarmCommand{}},
+						0x03: {Name: "ResetAlarmLog", Command: &ResetAlarmLogCommand{}},
 					},
+					Generated: map[uint8]*CommandDescriptor{
+						0x00: {Name: "Alarm", Command: &AlarmCommand{}},
+						0x01: {Name: "GetAlarmResponse", Command: &GetAlarmResponse{}, Response: true},
+					},
+				},
+			},
+			AnalogInput: {
+				Name: "AnalogInput",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0041: {Name: "MaxPresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x0045: {Name: "MinPresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write | Reportable},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x006A: {Name: "Resolution", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0075: {Name: "EngineeringUnits", Type: ZclDataTypeEnum16, Access: Read | Write},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			AnalogOutput: {
+				Name: "AnalogOutput",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0041: {Name: "MaxPresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x0045: {Name: "MinPresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x006A: {Name: "Resolution", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0075: {Name: "EngineeringUnits", Type: ZclDataTypeEnum16, Access: Read | Write},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			AnalogValue: {
+				Name: "AnalogValue",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeSinglePrec, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeSinglePrec, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0075: {Name: "EngineeringUnits", Type: ZclDataTypeEnum16, Access: Read | Write},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			BinaryInput: {
+				Name: "BinaryInput",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0004: {Name: "ActiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x002E: {Name: "InactiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0054: {Name: "Polarity", Type: ZclDataTypeEnum8, Access: Read, Enum: PolarityEnum},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeBoolean, Access: Read | Write | Reportable},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			BinaryOutput: {
+				Name: "BinaryOutput",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0004: {Name: "ActiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x002E: {Name: "InactiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0042: {Name: "MinimumOffTime", Type: ZclDataTypeUint32, Access: Read | Write},
+					0x0043: {Name: "MinimumOnTime", Type: ZclDataTypeUint32, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0054: {Name: "Polarity", Type: ZclDataTypeEnum8, Access: Read, Enum: PolarityEnum},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeBoolean, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			BinaryValue: {
+				Name: "BinaryValue",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0004: {Name: "ActiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x002E: {Name: "InactiveText", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x0042: {Name: "MinimumOffTime", Type: ZclDataTypeUint32, Access: Read | Write},
+					0x0043: {Name: "MinimumOnTime", Type: ZclDataTypeUint32, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeBoolean, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
 				},
 			},
 			MultistateInput: {
 				Name: "MultistateInput",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x000E: {"StateText", ZclDataTypeArray, Read | Write},
-					0x001C: {"Description", ZclDataTypeCharStr, Read | Write},
-					0x004A: {"NumberOfStates", ZclDataTypeUint16, Read | Write},
-					0x0051: {"OutOfService", ZclDataTypeBoolean, Read | Write},
-					0x0055: {"PresentValue", ZclDataTypeUint16, Read | Write},
-					0x0067: {"Reliability", ZclDataTypeEnum8, Read | Write},
-					0x006F: {"StatusFlags", ZclDataTypeBitmap8, Read},
-					0x0100: {"ApplicationType", ZclDataTypeUint32, Read},
+					0x000E: {Name: "StateText", Type: ZclDataTypeArray, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x004A: {Name: "NumberOfStates", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeUint16, Access: Read | Write | Reportable},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			MultistateOutput: {
+				Name: "MultistateOutput",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x000E: {Name: "StateText", Type: ZclDataTypeArray, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x004A: {Name: "NumberOfStates", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeUint16, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			MultistateValue: {
+				Name: "MultistateValue",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x000E: {Name: "StateText", Type: ZclDataTypeArray, Access: Read | Write},
+					0x001C: {Name: "Description", Type: ZclDataTypeCharStr, Access: Read | Write},
+					0x004A: {Name: "NumberOfStates", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0051: {Name: "OutOfService", Type: ZclDataTypeBoolean, Access: Read | Write},
+					0x0055: {Name: "PresentValue", Type: ZclDataTypeUint16, Access: Read | Write | Reportable},
+					0x0057: {Name: "PriorityArray", Type: ZclDataTypeArray, Access: Read | Write},
+					0x0067: {Name: "Reliability", Type: ZclDataTypeEnum8, Access: Read | Write, Enum: ReliabilityEnum},
+					0x0068: {Name: "RelinquishDefault", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x006F: {Name: "StatusFlags", Type: ZclDataTypeBitmap8, Access: Read | Reportable, Bitmap: StatusFlagsBitmap},
+					0x0100: {Name: "ApplicationType", Type: ZclDataTypeUint32, Access: Read},
 				},
 			},
 			OTA: {
 				Name: "OTA",
 				AttributeDescriptors: map[uint16]*AttributeDescriptor{
-					0x0000: {"UpgradeServerID", ZclDataTypeIeeeAddr, Read},
-					0x0001: {"FileOffset", ZclDataTypeUint32, Read},
-					0x0002: {"CurrentFileVersion", ZclDataTypeUint32, Read},
-					0x0003: {"CurrentZigBeeStackVersion", ZclDataTypeUint16, Read},
-					0x0004: {"DownloadedFileVersion", ZclDataTypeUint32, Read},
-					0x0005: {"DownloadedZigBeeStackVersion", ZclDataTypeUint16, Read},
-					0x0006: {"ImageUpgradeStatus", ZclDataTypeEnum8, Read},
-					0x0007: {"ManufacturerID", ZclDataTypeUint16, Read},
-					0x0008: {"ImageTypeID ", ZclDataTypeUint16, Read},
-					0x0009: {"MinimumBlockPeriod ", ZclDataTypeUint16, Read},
-					0x000a: {"ImageStamp ", ZclDataTypeUint32, Read},
+					0x0000: {Name: "UpgradeServerID", Type: ZclDataTypeIeeeAddr, Access: Read},
+					0x0001: {Name: "FileOffset", Type: ZclDataTypeUint32, Access: Read},
+					0x0002: {Name: "CurrentFileVersion", Type: ZclDataTypeUint32, Access: Read},
+					0x0003: {Name: "CurrentZigBeeStackVersion", Type: ZclDataTypeUint16, Access: Read},
+					0x0004: {Name: "DownloadedFileVersion", Type: ZclDataTypeUint32, Access: Read},
+					0x0005: {Name: "DownloadedZigBeeStackVersion", Type: ZclDataTypeUint16, Access: Read},
+					0x0006: {Name: "ImageUpgradeStatus", Type: ZclDataTypeEnum8, Access: Read, Enum: ImageUpgradeStatusEnum},
+					0x0007: {Name: "ManufacturerID", Type: ZclDataTypeUint16, Access: Read},
+					0x0008: {Name: "ImageTypeID ", Type: ZclDataTypeUint16, Access: Read},
+					0x0009: {Name: "MinimumBlockPeriod ", Type: ZclDataTypeUint16, Access: Read},
+					0x000a: {Name: "ImageStamp ", Type: ZclDataTypeUint32, Access: Read},
+				},
+			},
+			PollControl: {
+				Name: "PollControl",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0000: {Name: "CheckInInterval", Type: ZclDataTypeUint32, Access: Read | Write},
+					0x0001: {Name: "LongPollInterval", Type: ZclDataTypeUint32, Access: Read},
+					0x0002: {Name: "ShortPollInterval", Type: ZclDataTypeUint16, Access: Read},
+					0x0003: {Name: "FastPollTimeout", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0004: {Name: "CheckInIntervalMin", Type: ZclDataTypeUint32, Access: Read},
+					0x0005: {Name: "LongPollIntervalMin", Type: ZclDataTypeUint32, Access: Read},
+					0x0006: {Name: "FastPollTimeoutMax", Type: ZclDataTypeUint16, Access: Read},
+				},
+				CommandDescriptors: &CommandDescriptors{
+					Received: map[uint8]*CommandDescriptor{
+						0x00: {Name: "CheckInResponse", Command: &CheckInResponse{}, Response: true},
+						0x01: {Name: "FastPollStop", Command: &FastPollStopCommand{}},
+						0x02: {Name: "SetLongPollInterval", Command: &SetLongPollIntervalCommand{}},
+						0x03: {Name: "SetShortPollInterval", Command: &SetShortPollIntervalCommand{}},
+					},
+					Generated: map[uint8]*CommandDescriptor{
+						0x00: {Name: "CheckIn", Command: &CheckInCommand{}},
+					},
+				},
+			},
+			GreenPower: {
+				Name: "GreenPower",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x0000: {Name: "MaxSinkTableEntries", Type: ZclDataTypeUint8, Access: Read},
+					0x0001: {Name: "SinkTable", Type: ZclDataTypeLongOctetStr, Access: Read},
+					0x0002: {Name: "CommunicationMode", Type: ZclDataTypeBitmap8, Access: Read | Write},
+					0x0003: {Name: "CommissioningExitMode", Type: ZclDataTypeBitmap8, Access: Read | Write},
+					0x0004: {Name: "CommissioningWindow", Type: ZclDataTypeUint16, Access: Read | Write},
+					0x0005: {Name: "SecurityLevel", Type: ZclDataTypeBitmap8, Access: Read | Write},
+					0x0006: {Name: "Functionality", Type: ZclDataTypeBitmap24, Access: Read},
+					0x0007: {Name: "ActiveFunctionality", Type: ZclDataTypeBitmap24, Access: Read},
+					0x0010: {Name: "MaxProxyTableEntries", Type: ZclDataTypeUint8, Access: Read},
+					0x0011: {Name: "ProxyTable", Type: ZclDataTypeLongOctetStr, Access: Read},
+					0x0012: {Name: "NotificationRetryNumber", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0013: {Name: "NotificationRetryTimer", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0014: {Name: "MaxSearchCounter", Type: ZclDataTypeUint8, Access: Read | Write},
+					0x0015: {Name: "BlockedGPDID", Type: ZclDataTypeLongOctetStr, Access: Read},
+					0x0016: {Name: "ClientFunctionality", Type: ZclDataTypeBitmap24, Access: Read},
+					0x0017: {Name: "ClientActiveFunctionality", Type: ZclDataTypeBitmap24, Access: Read},
+					0x0020: {Name: "SharedSecurityKeyType", Type: ZclDataTypeBitmap8, Access: Read | Write},
+					0x0021: {Name: "SharedSecurityKey", Type: ZclDataType_128BitSecKey, Access: Read | Write},
+					0x0022: {Name: "LinkKey", Type: ZclDataType_128BitSecKey, Access: Read | Write},
+				},
+				CommandDescriptors: &CommandDescriptors{
+					Received: map[uint8]*CommandDescriptor{
+						0x00: {Name: "GpNotification", Command: &GpNotificationCommand{}},
+						0x01: {Name: "GpPairingSearch", Command: &GpPairingSearchCommand{}},
+						0x04: {Name: "GpCommissioningNotification", Command: &GpCommissioningNotificationCommand{}},
+						0x05: {Name: "GpSinkCommissioningMode", Command: &GpSinkCommissioningModeCommand{}},
+						0x0a: {Name: "GpSinkTableRequest", Command: &GpSinkTableRequestCommand{}},
+						0x0b: {Name: "GpProxyTableResponse", Command: &GpProxyTableResponse{}, Response: true},
+					},
+					Generated: map[uint8]*CommandDescriptor{
+						0x00: {Name: "GpNotificationResponse", Command: &GpNotificationResponse{}, Response: true},
+						0x01: {Name: "GpPairing", Command: &GpPairingCommand{}},
+						0x02: {Name: "GpProxyCommissioningMode", Command: &GpProxyCommissioningModeCommand{}},
+						0x06: {Name: "GpResponse", Command: &GpResponseCommand{}, Response: true},
+						0x0a: {Name: "GpSinkTableResponse", Command: &GpSinkTableResponse{}, Response: true},
+						0x0b: {Name: "GpProxyTableRequest", Command: &GpProxyTableRequestCommand{}},
+					},
+				},
+			},
+			TouchlinkCommissioning: {
+				Name:                 "TouchlinkCommissioning",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{},
+				CommandDescriptors: &CommandDescriptors{
+					Received: map[uint8]*CommandDescriptor{
+						0x00: {Name: "ScanRequest", Command: &ScanRequestCommand{}},
+						0x02: {Name: "DeviceInformationRequest", Command: &DeviceInformationRequestCommand{}},
+						0x06: {Name: "IdentifyRequest", Command: &IdentifyRequestCommand{}},
+						0x07: {Name: "ResetToFactoryNewRequest", Command: &ResetToFactoryNewRequestCommand{}},
+						0x10: {Name: "NetworkStartRequest", Command: &NetworkStartRequestCommand{}},
+						0x12: {Name: "NetworkJoinRouterRequest", Command: &NetworkJoinRouterRequestCommand{}},
+						0x14: {Name: "NetworkJoinEndDeviceRequest", Command: &NetworkJoinEndDeviceRequestCommand{}},
+						0x16: {Name: "NetworkUpdateRequest", Command: &NetworkUpdateRequestCommand{}},
+						0x41: {Name: "GetGroupIdentifiersRequest", Command: &GetGroupIdentifiersRequestCommand{}},
+						0x42: {Name: "GetEndpointListRequest", Command: &GetEndpointListRequestCommand{}},
+					},
+					Generated: map[uint8]*CommandDescriptor{
+						0x01: {Name: "ScanResponse", Command: &ScanResponse{}, Response: true},
+						0x03: {Name: "DeviceInformationResponse", Command: &DeviceInformationResponse{}, Response: true},
+						0x11: {Name: "NetworkStartResponse", Command: &NetworkStartResponse{}, Response: true},
+						0x13: {Name: "NetworkJoinRouterResponse", Command: &NetworkJoinRouterResponse{}, Response: true},
+						0x15: {Name: "NetworkJoinEndDeviceResponse", Command: &NetworkJoinEndDeviceResponse{}, Response: true},
+						0x40: {Name: "EndpointInformation", Command: &EndpointInformationCommand{}},
+						0x41: {Name: "GetGroupIdentifiersResponse", Command: &GetGroupIdentifiersResponse{}, Response: true},
+						0x42: {Name: "GetEndpointListResponse", Command: &GetEndpointListResponse{}, Response: true},
+					},
+				},
+			},
+			Tuya: {
+				Name:                 "Tuya",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{},
+				CommandDescriptors: &CommandDescriptors{
+					Received: map[uint8]*CommandDescriptor{
+						0x00: {Name: "TuyaDataRequest", Command: &TuyaDataCommand{}},
+						0x03: {Name: "TuyaDataQuery", Command: &TuyaDataQueryCommand{}},
+					},
+					Generated: map[uint8]*CommandDescriptor{
+						0x01: {Name: "TuyaDataResponse", Command: &TuyaDataCommand{}, Response: true},
+						0x02: {Name: "TuyaDataReport", Command: &TuyaDataCommand{}},
+					},
 				},
 			},
 		},
+		manufacturers: manufacturerClusters(),
+	}
+}
+
+// NewEmpty returns a library which knows the global commands only, to be
+// populated with RegisterCluster, e.g. from ZCL XML definitions.
+func NewEmpty() *ClusterLibrary {
+	return &ClusterLibrary{
+		global:        globalCommands(),
+		clusters:      map[ClusterId]*Cluster{},
+		manufacturers: map[uint16]map[ClusterId]*Cluster{},
+	}
+}
+
+func globalCommands() map[uint8]*CommandDescriptor {
+	return map[uint8]*CommandDescriptor{
+		0x00: {Name: "ReadAttributes", Command: &ReadAttributesCommand{}},
+		0x01: {Name: "ReadAttributesResponse", Command: &ReadAttributesResponse{}, Response: true},
+		0x02: {Name: "WriteAttributes", Command: &WriteAttributesCommand{}},
+		0x03: {Name: "WriteAttributesUndivided", Command: &WriteAttributesUndividedCommand{}},
+		0x04: {Name: "WriteAttributesResponse", Command: &WriteAttributesResponse{}, Response: true},
+		0x05: {Name: "WriteAttributesNoResponse", Command: &WriteAttributesNoResponseCommand{}},
+		0x06: {Name: "ConfigureReporting", Command: &ConfigureReportingCommand{}},
+		0x07: {Name: "ConfigureReportingResponse", Command: &ConfigureReportingResponse{}, Response: true},
+		0x08: {Name: "ReadReportingConfiguration", Command: &ReadReportingConfigurationCommand{}},
+		0x09: {Name: "ReadReportingConfigurationResponse", Command: &ReadReportingConfigurationResponse{}, Response: true},
+		0x0a: {Name: "ReportAttributes", Command: &ReportAttributesCommand{}},
+		0x0b: {Name: "DefaultResponse", Command: &DefaultResponseCommand{}, Response: true},
+		0x0c: {Name: "DiscoverAttributes", Command: &DiscoverAttributesCommand{}},
+		0x0d: {Name: "DiscoverAttributesResponse", Command: &DiscoverAttributesResponse{}, Response: true},
+		0x0e: {Name: "ReadAttributesStructured", Command: &ReadAttributesStructuredCommand{}},
+		0x0f: {Name: "WriteAttributesStructured", Command: &WriteAttributesStructuredCommand{}},
+		0x10: {Name: "WriteAttributesStructuredResponse", Command: &WriteAttributesStructuredResponse{}, Response: true},
+		0x11: {Name: "DiscoverCommandsReceived", Command: &DiscoverCommandsReceivedCommand{}},
+		0x12: {Name: "DiscoverCommandsReceivedResponse", Command: &DiscoverCommandsReceivedResponse{}, Response: true},
+		0x13: {Name: "DiscoverCommandsGenerated", Command: &DiscoverCommandsGeneratedCommand{}},
+		0x14: {Name: "DiscoverCommandsGeneratedResponse", Command: &DiscoverCommandsGeneratedResponse{}, Response: true},
+		0x15: {Name: "DiscoverAttributesExtended", Command: &DiscoverAttributesExtendedCommand{}},
+		0x16: {Name: "DiscoverAttributesExtendedResponse", Command: &DiscoverAttributesExtendedResponse{}, Response: true},
 	}
 }
 
+// Clusters returns the standard clusters. The map is a copy which later
+// registrations don't change, the clusters must not be modified.
 func (cl *ClusterLibrary) Clusters() map[ClusterId]*Cluster {
-	return cl.clusters
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	return copyClusters(cl.clusters)
 }
 
 func (cl *ClusterLibrary) Global() map[uint8]*CommandDescriptor {
-	return cl.global
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	return copyCommands(cl.global)
+}
+
+// Manufacturers returns the vendor clusters by manufacturer code, copied like
+// Clusters.
+func (cl *ClusterLibrary) Manufacturers() map[uint16]map[ClusterId]*Cluster {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	manufacturers := make(map[uint16]map[ClusterId]*Cluster, len(cl.manufacturers))
+	for manufacturerCode, clusters := range cl.manufacturers {
+		manufacturers[manufacturerCode] = copyClusters(clusters)
+	}
+	return manufacturers
+}
+
+// Candidates returns the definitions of a cluster in lookup order: the vendor
+// definition for manufacturerCode first, if there is one, then the standard one.
+// Pass 0 as manufacturerCode for frames which aren't manufacturer specific.
+func (cl *ClusterLibrary) Candidates(clusterId ClusterId, manufacturerCode uint16) []*Cluster {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	return cl.candidates(clusterId, manufacturerCode)
+}
+
+func (cl *ClusterLibrary) AttributeDescriptor(clusterId ClusterId, manufacturerCode uint16, attributeId uint16) (*AttributeDescriptor, bool) {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	for _, c := range cl.candidates(clusterId, manufacturerCode) {
+		if attributeDescriptor, ok := c.AttributeDescriptors[attributeId]; ok {
+			return attributeDescriptor, true
+		}
+	}
+	return nil, false
+}
+
+func (cl *ClusterLibrary) CommandDescriptor(clusterId ClusterId, manufacturerCode uint16, direction frame.Direction, commandId uint8) (*CommandDescriptor, bool) {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	for _, c := range cl.candidates(clusterId, manufacturerCode) {
+		if commandDescriptor, ok := c.commandDescriptors(direction)[commandId]; ok {
+			return commandDescriptor, true
+		}
+	}
+	return nil, false
+}
+
+func (cl *ClusterLibrary) AlarmDescription(clusterId ClusterId, alarmCode uint8) (string, bool) {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	if c, ok := cl.clusters[clusterId]; ok {
+		if description, ok := c.AlarmDescriptors[alarmCode]; ok {
+			return description, true
+		}
+	}
+	return "", false
+}
+
+func (cl *ClusterLibrary) candidates(clusterId ClusterId, manufacturerCode uint16) []*Cluster {
+	var candidates []*Cluster
+	if manufacturerCode != 0 {
+		if c, ok := cl.manufacturers[manufacturerCode][clusterId]; ok {
+			candidates = append(candidates, c)
+		}
+	}
+	if c, ok := cl.clusters[clusterId]; ok {
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// clone copies the definitions of a cluster which registrations modify.
+func (c *Cluster) clone() *Cluster {
+	clone := *c
+	clone.AttributeDescriptors = make(map[uint16]*AttributeDescriptor, len(c.AttributeDescriptors))
+	for id, ad := range c.AttributeDescriptors {
+		clone.AttributeDescriptors[id] = ad
+	}
+	if c.CommandDescriptors != nil {
+		clone.CommandDescriptors = &CommandDescriptors{
+			Received:  copyCommands(c.CommandDescriptors.Received),
+			Generated: copyCommands(c.CommandDescriptors.Generated),
+		}
+	}
+	return &clone
+}
+
+func copyClusters(clusters map[ClusterId]*Cluster) map[ClusterId]*Cluster {
+	copied := make(map[ClusterId]*Cluster, len(clusters))
+	for id, c := range clusters {
+		copied[id] = c
+	}
+	return copied
+}
+
+func copyCommands(commands map[uint8]*CommandDescriptor) map[uint8]*CommandDescriptor {
+	if commands == nil {
+		return nil
+	}
+	copied := make(map[uint8]*CommandDescriptor, len(commands))
+	for id, cd := range commands {
+		copied[id] = cd
+	}
+	return copied
+}
+
+func (c *Cluster) commandDescriptors(direction frame.Direction) map[uint8]*CommandDescriptor {
+	if c.CommandDescriptors == nil {
+		return nil
+	}
+	if direction == frame.DirectionServerClient {
+		return c.CommandDescriptors.Generated
+	}
+	return c.CommandDescriptors.Received
 }
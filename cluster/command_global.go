@@ -1,8 +1,11 @@
 package cluster
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 	"strconv"
 
 	"github.com/dyrkin/bin/util"
@@ -61,10 +64,12 @@ type WriteAttributesNoResponseCommand struct {
 	WriteAttributeRecords []*WriteAttributeRecord
 }
 
+// WriteAttributeStatus is a record of a failed write, or the single success
+// status of a write with no failures, which is sent without an attribute id.
 type WriteAttributeStatus struct {
 	Status        ZclStatus
 	AttributeName string `transient:"true"`
-	AttributeID   uint16
+	AttributeID   uint16 `cond:"uint:Status!=0"`
 }
 
 type WriteAttributesResponse struct {
@@ -86,11 +91,14 @@ type ConfigureReportingCommand struct {
 	AttributeReportingConfigurationRecords []*AttributeReportingConfigurationRecord
 }
 
+// AttributeStatusRecord is a record which couldn't be configured, or the
+// single success status of a configuration with no failures, which is sent
+// without the direction and attribute id.
 type AttributeStatusRecord struct {
 	Status        ZclStatus
-	Direction     ReportDirection
-	AttributeName string `transient:"true"`
-	AttributeID   uint16
+	Direction     ReportDirection `cond:"uint:Status!=0"`
+	AttributeName string          `transient:"true"`
+	AttributeID   uint16          `cond:"uint:Status!=0"`
 }
 
 type ConfigureReportingResponse struct {
@@ -133,8 +141,9 @@ type ReportAttributesCommand struct {
 }
 
 type DefaultResponseCommand struct {
-	CommandID uint8
-	Status    ZclStatus
+	CommandID   uint8
+	CommandName string `transient:"true"`
+	Status      ZclStatus
 }
 
 type DiscoverAttributesCommand struct {
@@ -190,9 +199,19 @@ type DiscoverCommandsReceivedCommand struct {
 	MaximumCommandIdentifiers uint8
 }
 
+// DiscoveredCommand is a command identifier from a discover commands response
+// resolved against the cluster definition. Known is false for commands the
+// library doesn't define.
+type DiscoveredCommand struct {
+	CommandID uint8
+	Name      string
+	Known     bool
+}
+
 type DiscoverCommandsReceivedResponse struct {
 	DiscoveryComplete  uint8
 	CommandIdentifiers []uint8
+	Commands           []*DiscoveredCommand `transient:"true"`
 }
 
 type DiscoverCommandsGeneratedCommand struct {
@@ -203,6 +222,7 @@ type DiscoverCommandsGeneratedCommand struct {
 type DiscoverCommandsGeneratedResponse struct {
 	DiscoveryComplete  uint8
 	CommandIdentifiers []uint8
+	Commands           []*DiscoveredCommand `transient:"true"`
 }
 
 type DiscoverAttributesExtendedCommand struct {
@@ -236,6 +256,10 @@ func (a *Attribute) Serialize(w io.Writer) {
 
 func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{}) {
 	c.Uint8(uint8(dataType))
+	writeValue(c, dataType, value)
+}
+
+func writeValue(c *composer.Composer, dataType ZclDataType, value interface{}) {
 	switch dataType {
 	case ZclDataTypeNoData:
 	case ZclDataTypeData8:
@@ -348,8 +372,14 @@ func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{
 		b := value.(uint64)
 		c.Uint(binary.LittleEndian, b, 2)
 	case ZclDataTypeSemiPrec:
+		b := value.(float32)
+		c.Uint16le(float32ToHalf(b))
 	case ZclDataTypeSinglePrec:
+		b := value.(float32)
+		c.Float32le(b)
 	case ZclDataTypeDoublePrec:
+		b := value.(float64)
+		c.Float64le(b)
 	case ZclDataTypeOctetStr:
 		b := value.(string)
 		c.Uint8(uint8(len(b)))
@@ -409,6 +439,43 @@ func writeAttribute(c *composer.Composer, dataType ZclDataType, value interface{
 	}
 }
 
+// DecodeAttribute decodes a data type prefixed attribute value, failing if
+// the buffer ends before the value does. Trailing bytes are ignored.
+func DecodeAttribute(buf []uint8) (attribute *Attribute, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			attribute, err = nil, fmt.Errorf("malformed attribute: %v", r)
+		}
+	}()
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("attribute data type missing")
+	}
+	r := &shortReadDetector{r: bytes.NewReader(buf)}
+	attribute = &Attribute{}
+	attribute.Deserialize(r)
+	if r.short {
+		return nil, fmt.Errorf("%s attribute truncated after %d bytes", attribute.DataType, len(buf))
+	}
+	return attribute, nil
+}
+
+// shortReadDetector remembers whether a read returned less than requested.
+type shortReadDetector struct {
+	r     io.Reader
+	short bool
+}
+
+func (d *shortReadDetector) Read(p []byte) (int, error) {
+	n, err := io.ReadFull(d.r, p)
+	if n < len(p) {
+		d.short = true
+	}
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
 func (a *Attribute) Deserialize(r io.Reader) {
 	c := composer.NewWithR(r)
 	a.DataType, a.Value = readAttribute(c)
@@ -417,7 +484,11 @@ func (a *Attribute) Deserialize(r io.Reader) {
 func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{}) {
 	dt, _ := c.ReadByte()
 	dataType = ZclDataType(dt)
+	value = readValue(c, dataType)
+	return
+}
 
+func readValue(c *composer.Composer, dataType ZclDataType) (value interface{}) {
 	switch dataType {
 	case ZclDataTypeNoData:
 		value = nil
@@ -489,28 +560,32 @@ func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{
 	case ZclDataTypeUint64:
 		value = c.ReadUint(binary.LittleEndian, 8)
 	case ZclDataTypeInt8:
-		value = c.ReadInt(binary.LittleEndian, 1)
+		value = readInt(c, 1)
 	case ZclDataTypeInt16:
-		value = c.ReadInt(binary.LittleEndian, 2)
+		value = readInt(c, 2)
 	case ZclDataTypeInt24:
-		value = c.ReadInt(binary.LittleEndian, 3)
+		value = readInt(c, 3)
 	case ZclDataTypeInt32:
-		value = c.ReadInt(binary.LittleEndian, 4)
+		value = readInt(c, 4)
 	case ZclDataTypeInt40:
-		value = c.ReadInt(binary.LittleEndian, 5)
+		value = readInt(c, 5)
 	case ZclDataTypeInt48:
-		value = c.ReadInt(binary.LittleEndian, 6)
+		value = readInt(c, 6)
 	case ZclDataTypeInt56:
-		value = c.ReadInt(binary.LittleEndian, 7)
+		value = readInt(c, 7)
 	case ZclDataTypeInt64:
-		value = c.ReadInt(binary.LittleEndian, 8)
+		value = readInt(c, 8)
 	case ZclDataTypeEnum8:
 		value = c.ReadUint(binary.LittleEndian, 1)
 	case ZclDataTypeEnum16:
 		value = c.ReadUint(binary.LittleEndian, 2)
 	case ZclDataTypeSemiPrec:
+		v, _ := c.ReadUint16le()
+		value = halfToFloat32(v)
 	case ZclDataTypeSinglePrec:
+		value, _ = c.ReadFloat32le()
 	case ZclDataTypeDoublePrec:
+		value, _ = c.ReadFloat64le()
 	case ZclDataTypeOctetStr:
 		len, _ := c.ReadByte()
 		value, _ = c.ReadString(int(len))
@@ -566,9 +641,64 @@ func readAttribute(c *composer.Composer) (dataType ZclDataType, value interface{
 	return
 }
 
+// readInt reads a little endian two's complement integer of the given size,
+// sign extended to 64 bits.
+func readInt(c *composer.Composer, size int) int64 {
+	shift := uint(64 - size*8)
+	return int64(c.ReadUint(binary.LittleEndian, size)<<shift) >> shift
+}
+
 func flag(boolean bool) uint8 {
 	if boolean {
 		return 1
 	}
 	return 0
 }
+
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1f
+	mantissa := uint32(h) & 0x3ff
+	switch exponent {
+	case 0:
+		if mantissa == 0 {
+			return math.Float32frombits(sign)
+		}
+		exponent = 127 - 15 + 1
+		for mantissa&0x400 == 0 {
+			mantissa <<= 1
+			exponent--
+		}
+		return math.Float32frombits(sign | exponent<<23 | (mantissa&0x3ff)<<13)
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | (exponent+127-15)<<23 | mantissa<<13)
+}
+
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int32(bits>>23&0xff) - 127 + 15
+	mantissa := bits & 0x7fffff
+	switch {
+	case bits>>23&0xff == 0xff:
+		if mantissa != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exponent >= 0x1f:
+		return sign | 0x7c00
+	case exponent <= 0:
+		if exponent < -10 {
+			return sign
+		}
+		mantissa |= 0x800000
+		return sign | uint16(mantissa>>uint32(14-exponent))
+	}
+	half := sign | uint16(exponent)<<10 | uint16(mantissa>>13)
+	if mantissa&0x1000 != 0 {
+		half++
+	}
+	return half
+}
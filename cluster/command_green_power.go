@@ -0,0 +1,352 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+
+	"github.com/dyrkin/bin/util"
+	"github.com/dyrkin/composer"
+)
+
+const (
+	GpApplicationIdSrcId uint8 = 0x00
+	GpApplicationIdIeee  uint8 = 0x02
+)
+
+const (
+	GpCommunicationModeFullUnicast          uint8 = 0x00
+	GpCommunicationModeDerivedGroupcast     uint8 = 0x01
+	GpCommunicationModePreCommissionedGroup uint8 = 0x02
+	GpCommunicationModeLightweightUnicast   uint8 = 0x03
+)
+
+type GpNotificationOptions struct {
+	ApplicationID           uint16 `bits:"0b0000000000000111" bitmask:"start"`
+	AlsoUnicast             uint16 `bits:"0b0000000000001000"`
+	AlsoDerivedGroup        uint16 `bits:"0b0000000000010000"`
+	AlsoCommissionedGroup   uint16 `bits:"0b0000000000100000"`
+	SecurityLevel           uint16 `bits:"0b0000000011000000"`
+	SecurityKeyType         uint16 `bits:"0b0000011100000000"`
+	RxAfterTx               uint16 `bits:"0b0000100000000000"`
+	GpTxQueueFull           uint16 `bits:"0b0001000000000000"`
+	BidirectionalCapability uint16 `bits:"0b0010000000000000"`
+	ProxyInfoPresent        uint16 `bits:"0b0100000000000000"`
+	Reserved                uint16 `bits:"0b1000000000000000" bitmask:"end"`
+}
+
+type GpNotificationCommand struct {
+	Options                 *GpNotificationOptions
+	GpdSrcID                uint32 `cond:"uint:Options.ApplicationID==0"`
+	GpdIEEEAddress          string `hex:"8" cond:"uint:Options.ApplicationID==2"`
+	Endpoint                uint8  `cond:"uint:Options.ApplicationID==2"`
+	GpdSecurityFrameCounter uint32
+	GpdCommandID            uint8
+	GpdCommandPayload       []uint8 `size:"1"`
+	GppShortAddress         uint16  `cond:"uint:Options.ProxyInfoPresent==1"`
+	GppGpdLink              uint8   `cond:"uint:Options.ProxyInfoPresent==1"`
+}
+
+type GpPairingSearchOptions struct {
+	ApplicationID                     uint16 `bits:"0b0000000000000111" bitmask:"start"`
+	RequestUnicastSinks               uint16 `bits:"0b0000000000001000"`
+	RequestDerivedGroupcastSinks      uint16 `bits:"0b0000000000010000"`
+	RequestCommissionedGroupcastSinks uint16 `bits:"0b0000000000100000"`
+	RequestGpdSecurityFrameCounter    uint16 `bits:"0b0000000001000000"`
+	RequestGpdSecurityKey             uint16 `bits:"0b0000000010000000"`
+	Reserved                          uint16 `bits:"0b1111111100000000" bitmask:"end"`
+}
+
+type GpPairingSearchCommand struct {
+	Options        *GpPairingSearchOptions
+	GpdSrcID       uint32 `cond:"uint:Options.ApplicationID==0"`
+	GpdIEEEAddress string `hex:"8" cond:"uint:Options.ApplicationID==2"`
+	Endpoint       uint8  `cond:"uint:Options.ApplicationID==2"`
+}
+
+type GpCommissioningNotificationOptions struct {
+	ApplicationID            uint16 `bits:"0b0000000000000111" bitmask:"start"`
+	RxAfterTx                uint16 `bits:"0b0000000000001000"`
+	SecurityLevel            uint16 `bits:"0b0000000000110000"`
+	SecurityKeyType          uint16 `bits:"0b0000000111000000"`
+	SecurityProcessingFailed uint16 `bits:"0b0000001000000000"`
+	BidirectionalCapability  uint16 `bits:"0b0000010000000000"`
+	ProxyInfoPresent         uint16 `bits:"0b0000100000000000"`
+	Reserved                 uint16 `bits:"0b1111000000000000" bitmask:"end"`
+}
+
+type GpCommissioningNotificationCommand struct {
+	Options                 *GpCommissioningNotificationOptions
+	GpdSrcID                uint32 `cond:"uint:Options.ApplicationID==0"`
+	GpdIEEEAddress          string `hex:"8" cond:"uint:Options.ApplicationID==2"`
+	Endpoint                uint8  `cond:"uint:Options.ApplicationID==2"`
+	GpdSecurityFrameCounter uint32
+	GpdCommandID            uint8
+	GpdCommandPayload       []uint8 `size:"1"`
+	GppShortAddress         uint16  `cond:"uint:Options.ProxyInfoPresent==1"`
+	GppGpdLink              uint8   `cond:"uint:Options.ProxyInfoPresent==1"`
+	Mic                     uint32  `cond:"uint:Options.SecurityProcessingFailed==1"`
+}
+
+type GpSinkCommissioningModeOptions struct {
+	Action               uint8 `bits:"0b00000001" bitmask:"start"`
+	InvolveGpmInSecurity uint8 `bits:"0b00000010"`
+	InvolveGpmInPairing  uint8 `bits:"0b00000100"`
+	InvolveProxies       uint8 `bits:"0b00001000"`
+	Reserved             uint8 `bits:"0b11110000" bitmask:"end"`
+}
+
+type GpSinkCommissioningModeCommand struct {
+	Options            *GpSinkCommissioningModeOptions
+	GpmAddrForSecurity uint16
+	GpmAddrForPairing  uint16
+	SinkEndpoint       uint8
+}
+
+type GpTableRequestOptions struct {
+	ApplicationID uint8 `bits:"0b00000111" bitmask:"start"`
+	RequestType   uint8 `bits:"0b00011000"`
+	Reserved      uint8 `bits:"0b11100000" bitmask:"end"`
+}
+
+type GpSinkTableRequestCommand struct {
+	Options        *GpTableRequestOptions
+	GpdSrcID       uint32 `cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==0"`
+	GpdIEEEAddress string `hex:"8" cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==2"`
+	Endpoint       uint8  `cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==2"`
+	Index          uint8  `cond:"uint:Options.RequestType==1"`
+}
+
+type GpProxyTableRequestCommand struct {
+	Options        *GpTableRequestOptions
+	GpdSrcID       uint32 `cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==0"`
+	GpdIEEEAddress string `hex:"8" cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==2"`
+	Endpoint       uint8  `cond:"uint:Options.RequestType==0;uint:Options.ApplicationID==2"`
+	Index          uint8  `cond:"uint:Options.RequestType==1"`
+}
+
+type GpSinkTableResponse struct {
+	Status                       ZclStatus
+	TotalNumberOfNonEmptyEntries uint8
+	StartIndex                   uint8
+	EntriesCount                 uint8
+	Entries                      []uint8
+}
+
+type GpProxyTableResponse struct {
+	Status                       ZclStatus
+	TotalNumberOfNonEmptyEntries uint8
+	StartIndex                   uint8
+	EntriesCount                 uint8
+	Entries                      []uint8
+}
+
+type GpNotificationResponseOptions struct {
+	ApplicationID  uint8 `bits:"0b00000111" bitmask:"start"`
+	FirstToForward uint8 `bits:"0b00001000"`
+	NoPairing      uint8 `bits:"0b00010000"`
+	Reserved       uint8 `bits:"0b11100000" bitmask:"end"`
+}
+
+type GpNotificationResponse struct {
+	Options                 *GpNotificationResponseOptions
+	GpdSrcID                uint32 `cond:"uint:Options.ApplicationID==0"`
+	GpdIEEEAddress          string `hex:"8" cond:"uint:Options.ApplicationID==2"`
+	Endpoint                uint8  `cond:"uint:Options.ApplicationID==2"`
+	GpdSecurityFrameCounter uint32
+}
+
+type GpPairingOptions struct {
+	ApplicationID                  uint8
+	AddSink                        uint8
+	RemoveGpd                      uint8
+	CommunicationMode              uint8
+	GpdFixed                       uint8
+	GpdMacSequenceNumberCapability uint8
+	SecurityLevel                  uint8
+	SecurityKeyType                uint8
+	GpdSecurityFrameCounterPresent uint8
+	GpdSecurityKeyPresent          uint8
+	AssignedAliasPresent           uint8
+	GroupcastRadiusPresent         uint8
+}
+
+// GpPairingCommand serializes itself because the presence of the sink address
+// fields depends on the communication mode, which bin conditions can't express.
+type GpPairingCommand struct {
+	Options                 *GpPairingOptions
+	GpdSrcID                uint32
+	GpdIEEEAddress          string
+	Endpoint                uint8
+	SinkIEEEAddress         string
+	SinkNWKAddress          uint16
+	SinkGroupID             uint16
+	DeviceID                uint8
+	GpdSecurityFrameCounter uint32
+	GpdKey                  [16]uint8
+	AssignedAlias           uint16
+	GroupcastRadius         uint8
+}
+
+type GpProxyCommissioningModeOptions struct {
+	Action                          uint8 `bits:"0b00000001" bitmask:"start"`
+	OnCommissioningWindowExpiration uint8 `bits:"0b00000010"`
+	OnFirstPairingSuccess           uint8 `bits:"0b00000100"`
+	OnGpProxyCommissioningModeExit  uint8 `bits:"0b00001000"`
+	ChannelPresent                  uint8 `bits:"0b00010000"`
+	UnicastCommunication            uint8 `bits:"0b00100000"`
+	Reserved                        uint8 `bits:"0b11000000" bitmask:"end"`
+}
+
+type GpProxyCommissioningModeCommand struct {
+	Options             *GpProxyCommissioningModeOptions
+	CommissioningWindow uint16 `cond:"uint:Options.OnCommissioningWindowExpiration==1"`
+	Channel             uint8  `cond:"uint:Options.ChannelPresent==1"`
+}
+
+type GpResponseOptions struct {
+	ApplicationID           uint8 `bits:"0b00000111" bitmask:"start"`
+	TransmitOnEndpointMatch uint8 `bits:"0b00001000"`
+	Reserved                uint8 `bits:"0b11110000" bitmask:"end"`
+}
+
+type GpResponseCommand struct {
+	Options                *GpResponseOptions
+	TempMasterShortAddress uint16
+	TempMasterTxChannel    uint8
+	GpdSrcID               uint32 `cond:"uint:Options.ApplicationID==0"`
+	GpdIEEEAddress         string `hex:"8" cond:"uint:Options.ApplicationID==2"`
+	Endpoint               uint8  `cond:"uint:Options.ApplicationID==2"`
+	GpdCommandID           uint8
+	GpdCommandPayload      []uint8 `size:"1"`
+}
+
+func (n *GpNotificationCommand) GpdCommand() (interface{}, error) {
+	return ParseGpdCommand(n.GpdCommandID, n.GpdCommandPayload)
+}
+
+func (n *GpCommissioningNotificationCommand) GpdCommand() (interface{}, error) {
+	return ParseGpdCommand(n.GpdCommandID, n.GpdCommandPayload)
+}
+
+func (o *GpPairingOptions) value() uint64 {
+	return uint64(o.ApplicationID&0x07) |
+		uint64(o.AddSink&0x01)<<3 |
+		uint64(o.RemoveGpd&0x01)<<4 |
+		uint64(o.CommunicationMode&0x03)<<5 |
+		uint64(o.GpdFixed&0x01)<<7 |
+		uint64(o.GpdMacSequenceNumberCapability&0x01)<<8 |
+		uint64(o.SecurityLevel&0x03)<<9 |
+		uint64(o.SecurityKeyType&0x07)<<11 |
+		uint64(o.GpdSecurityFrameCounterPresent&0x01)<<14 |
+		uint64(o.GpdSecurityKeyPresent&0x01)<<15 |
+		uint64(o.AssignedAliasPresent&0x01)<<16 |
+		uint64(o.GroupcastRadiusPresent&0x01)<<17
+}
+
+func newGpPairingOptions(v uint64) *GpPairingOptions {
+	return &GpPairingOptions{
+		ApplicationID:                  uint8(v & 0x07),
+		AddSink:                        uint8(v >> 3 & 0x01),
+		RemoveGpd:                      uint8(v >> 4 & 0x01),
+		CommunicationMode:              uint8(v >> 5 & 0x03),
+		GpdFixed:                       uint8(v >> 7 & 0x01),
+		GpdMacSequenceNumberCapability: uint8(v >> 8 & 0x01),
+		SecurityLevel:                  uint8(v >> 9 & 0x03),
+		SecurityKeyType:                uint8(v >> 11 & 0x07),
+		GpdSecurityFrameCounterPresent: uint8(v >> 14 & 0x01),
+		GpdSecurityKeyPresent:          uint8(v >> 15 & 0x01),
+		AssignedAliasPresent:           uint8(v >> 16 & 0x01),
+		GroupcastRadiusPresent:         uint8(v >> 17 & 0x01),
+	}
+}
+
+func (o *GpPairingOptions) unicast() bool {
+	return o.CommunicationMode == GpCommunicationModeFullUnicast ||
+		o.CommunicationMode == GpCommunicationModeLightweightUnicast
+}
+
+func (p *GpPairingCommand) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	options := p.Options
+	if options == nil {
+		options = &GpPairingOptions{}
+	}
+	c.Uint(binary.LittleEndian, options.value(), 3)
+	switch options.ApplicationID {
+	case GpApplicationIdSrcId:
+		c.Uint32le(p.GpdSrcID)
+	case GpApplicationIdIeee:
+		c.Uint64le(ieeeAddressToUint(p.GpdIEEEAddress))
+		c.Uint8(p.Endpoint)
+	}
+	if options.RemoveGpd == 0 {
+		if options.unicast() {
+			c.Uint64le(ieeeAddressToUint(p.SinkIEEEAddress))
+			c.Uint16le(p.SinkNWKAddress)
+		} else {
+			c.Uint16le(p.SinkGroupID)
+		}
+	}
+	if options.AddSink == 1 {
+		c.Uint8(p.DeviceID)
+	}
+	if options.GpdSecurityFrameCounterPresent == 1 {
+		c.Uint32le(p.GpdSecurityFrameCounter)
+	}
+	if options.GpdSecurityKeyPresent == 1 {
+		c.Bytes(p.GpdKey[:])
+	}
+	if options.AssignedAliasPresent == 1 {
+		c.Uint16le(p.AssignedAlias)
+	}
+	if options.GroupcastRadiusPresent == 1 {
+		c.Uint8(p.GroupcastRadius)
+	}
+	c.Flush()
+}
+
+func (p *GpPairingCommand) Deserialize(r io.Reader) {
+	c := composer.NewWithR(r)
+	p.Options = newGpPairingOptions(c.ReadUint(binary.LittleEndian, 3))
+	switch p.Options.ApplicationID {
+	case GpApplicationIdSrcId:
+		p.GpdSrcID, _ = c.ReadUint32le()
+	case GpApplicationIdIeee:
+		v, _ := c.ReadUint64le()
+		p.GpdIEEEAddress, _ = util.UintToHexString(v, 8)
+		p.Endpoint, _ = c.ReadUint8()
+	}
+	if p.Options.RemoveGpd == 0 {
+		if p.Options.unicast() {
+			v, _ := c.ReadUint64le()
+			p.SinkIEEEAddress, _ = util.UintToHexString(v, 8)
+			p.SinkNWKAddress, _ = c.ReadUint16le()
+		} else {
+			p.SinkGroupID, _ = c.ReadUint16le()
+		}
+	}
+	if p.Options.AddSink == 1 {
+		p.DeviceID, _ = c.ReadUint8()
+	}
+	if p.Options.GpdSecurityFrameCounterPresent == 1 {
+		p.GpdSecurityFrameCounter, _ = c.ReadUint32le()
+	}
+	if p.Options.GpdSecurityKeyPresent == 1 {
+		_ = c.ReadBuf(p.GpdKey[:])
+	}
+	if p.Options.AssignedAliasPresent == 1 {
+		p.AssignedAlias, _ = c.ReadUint16le()
+	}
+	if p.Options.GroupcastRadiusPresent == 1 {
+		p.GroupcastRadius, _ = c.ReadUint8()
+	}
+}
+
+func ieeeAddressToUint(address string) uint64 {
+	if len(address) < 3 {
+		return 0
+	}
+	v, _ := strconv.ParseUint(address[2:], 16, 64)
+	return v
+}
@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"github.com/dyrkin/bin"
+	. "gopkg.in/check.v1"
+)
+
+type GreenPowerSuite struct{}
+
+var _ = Suite(&GreenPowerSuite{})
+
+func (s *GreenPowerSuite) TestDecodeGpNotification(c *C) {
+	res := &GpNotificationCommand{}
+	payload := []byte{
+		0x80, 0x48, //options
+		0x28, 0x10, 0x54, 0x01, //gpd src id
+		0x26, 0x00, 0x00, 0x00, //frame counter
+		0x22, 0x00, //toggle without payload
+		0x34, 0x12, 0xc5, //proxy info
+	}
+	bin.Decode(payload, res)
+	c.Assert(res, DeepEquals, &GpNotificationCommand{
+		Options:                 &GpNotificationOptions{SecurityLevel: 2, RxAfterTx: 1, ProxyInfoPresent: 1},
+		GpdSrcID:                0x01541028,
+		GpdSecurityFrameCounter: 0x26,
+		GpdCommandID:            0x22,
+		GpdCommandPayload:       []uint8{},
+		GppShortAddress:         0x1234,
+		GppGpdLink:              0xc5,
+	})
+	c.Assert(bin.Encode(res), DeepEquals, payload)
+
+	event, err := res.GpdCommand()
+	c.Assert(err, IsNil)
+	c.Assert(event, DeepEquals, &GpdToggle{})
+}
+
+func (s *GreenPowerSuite) TestEncodeGpPairing(c *C) {
+	pairing := &GpPairingCommand{
+		Options: &GpPairingOptions{
+			AddSink:                        1,
+			CommunicationMode:              GpCommunicationModePreCommissionedGroup,
+			GpdSecurityFrameCounterPresent: 1,
+		},
+		GpdSrcID:                0x01541028,
+		SinkGroupID:             0x0b84,
+		DeviceID:                0x02,
+		GpdSecurityFrameCounter: 0x26,
+	}
+	payload := bin.Encode(pairing)
+	c.Assert(payload, DeepEquals, []byte{
+		0x48, 0x40, 0x00, //options
+		0x28, 0x10, 0x54, 0x01, //gpd src id
+		0x84, 0x0b, //sink group
+		0x02,                   //device id
+		0x26, 0x00, 0x00, 0x00, //frame counter
+	})
+
+	res := &GpPairingCommand{}
+	bin.Decode(payload, res)
+	c.Assert(res, DeepEquals, pairing)
+}
+
+func (s *GreenPowerSuite) TestParseGpdCommands(c *C) {
+	event, _ := ParseGpdCommand(0x12, nil)
+	c.Assert(event, DeepEquals, &GpdRecallScene{2})
+
+	event, _ = ParseGpdCommand(0x64, nil)
+	c.Assert(event, DeepEquals, &GpdButton{Button: 2, Buttons: 2, Press: true})
+
+	event, _ = ParseGpdCommand(0x37, []uint8{0x10, 0x0a, 0x00})
+	c.Assert(event, DeepEquals, &GpdStep{Up: true, WithOnOff: true, StepSize: 0x10, TransitionTime: 0x0a})
+
+	event, _ = ParseGpdCommand(0xb2, []uint8{0x01})
+	c.Assert(event, DeepEquals, &GpdRawCommand{0xb2, []uint8{0x01}})
+
+	_, err := ParseGpdCommand(0x69, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *GreenPowerSuite) TestParseGpdCommissioning(c *C) {
+	key := [16]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	payload := append([]uint8{0x02, 0x81, 0xf2}, key[:]...)
+	payload = append(payload, 0x11, 0x22, 0x33, 0x44, 0x05, 0x00, 0x00, 0x00)
+
+	event, err := ParseGpdCommand(0xe0, payload)
+	c.Assert(err, IsNil)
+	c.Assert(event, DeepEquals, &GpdCommissioning{
+		DeviceID:           0x02,
+		Options:            &GpdCommissioningOptions{MacSequenceNumberCapability: 1, ExtendedOptionsPresent: 1},
+		ExtendedOptions:    &GpdCommissioningExtendedOptions{SecurityLevelCapabilities: 2, KeyType: 4, GpdKeyPresent: 1, GpdKeyEncryption: 1, GpdOutgoingCounterPresent: 1},
+		GpdKey:             key,
+		GpdKeyMic:          0x44332211,
+		GpdOutgoingCounter: 5,
+	})
+}
+
+func (s *GreenPowerSuite) TestParseTruncatedGpdCommissioning(c *C) {
+	for _, payload := range [][]uint8{
+		{0x02},
+		{0x02, 0x81},
+		{0x02, 0x81, 0x20, 0x00, 0x01},
+		{0x02, 0x81, 0x80, 0x05, 0x00},
+	} {
+		_, err := ParseGpdCommand(0xe0, payload)
+		c.Assert(err, ErrorMatches, "gpd commissioning command is too short", Commentf("% x", payload))
+	}
+
+	event, err := ParseGpdCommand(0xe0, []uint8{0x02, 0x81, 0x80, 0x05, 0x00, 0x00, 0x00})
+	c.Assert(err, IsNil)
+	c.Assert(event.(*GpdCommissioning).GpdOutgoingCounter, Equals, uint32(5))
+}
@@ -72,3 +72,43 @@ type StepOnOffCommand struct {
 }
 
 type StopOnOffCommand struct{}
+
+type ResetAlarmCommand struct {
+	AlarmCode         uint8
+	ClusterIdentifier uint16
+}
+
+type ResetAllAlarmsCommand struct{}
+
+type GetAlarmCommand struct{}
+
+type ResetAlarmLogCommand struct{}
+
+type AlarmCommand struct {
+	AlarmCode         uint8
+	ClusterIdentifier uint16
+}
+
+type GetAlarmResponse struct {
+	Status            ZclStatus
+	AlarmCode         uint8  `cond:"uint:Status==0"`
+	ClusterIdentifier uint16 `cond:"uint:Status==0"`
+	TimeStamp         uint32 `cond:"uint:Status==0"`
+}
+
+type CheckInCommand struct{}
+
+type CheckInResponse struct {
+	StartFastPolling uint8
+	FastPollTimeout  uint16
+}
+
+type FastPollStopCommand struct{}
+
+type SetLongPollIntervalCommand struct {
+	NewLongPollInterval uint32
+}
+
+type SetShortPollIntervalCommand struct {
+	NewShortPollInterval uint16
+}
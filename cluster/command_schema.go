@@ -0,0 +1,235 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/dyrkin/composer"
+)
+
+// FieldCondition makes a field present only if (Field & Mask) == Value for an
+// earlier field, or != Value if Negate is set. A zero Mask compares the whole
+// value.
+type FieldCondition struct {
+	Field  string
+	Mask   uint64
+	Value  uint64
+	Negate bool
+}
+
+type FieldDescriptor struct {
+	Name      string
+	Type      ZclDataType
+	Condition *FieldCondition
+	// List repeats the field until the end of the payload.
+	List bool
+	// CountField repeats the field as many times as the value of an earlier field.
+	CountField string
+}
+
+// CommandSchema describes a command payload field by field, for commands
+// which don't have a Go struct, e.g. ones loaded at runtime.
+type CommandSchema struct {
+	Fields []*FieldDescriptor
+}
+
+type Field struct {
+	Name  string
+	Type  ZclDataType
+	Value interface{}
+}
+
+// DynamicCommand is a command decoded with a CommandSchema. Fields keep the
+// order of the payload, so the command can be re-encoded as it was received.
+// Repeated fields hold a []interface{}.
+type DynamicCommand struct {
+	Schema *CommandSchema `transient:"true" json:"-"`
+	Fields []*Field
+}
+
+// validate rejects repeated fields of types which take no bytes, which would
+// repeat forever until the end of a payload.
+func (s *CommandSchema) validate() error {
+	for _, fd := range s.Fields {
+		if (fd.List || fd.CountField != "") && zeroWidth(fd.Type) {
+			return fmt.Errorf("repeated field %s has zero width type %s", fd.Name, fd.Type)
+		}
+	}
+	return nil
+}
+
+// NewCommand returns a DynamicCommand to be decoded or filled with Set.
+func (s *CommandSchema) NewCommand() *DynamicCommand {
+	return &DynamicCommand{Schema: s}
+}
+
+func (d *DynamicCommand) Get(name string) (interface{}, bool) {
+	if f := d.field(name); f != nil {
+		return f.Value, true
+	}
+	return nil, false
+}
+
+// Set replaces the value of a field or appends a new one.
+func (d *DynamicCommand) Set(name string, dataType ZclDataType, value interface{}) {
+	if f := d.field(name); f != nil {
+		f.Type, f.Value = dataType, value
+		return
+	}
+	d.Fields = append(d.Fields, &Field{name, dataType, value})
+}
+
+func (d *DynamicCommand) Serialize(w io.Writer) {
+	c := composer.NewWithW(w)
+	for _, f := range d.ordered() {
+		if values, ok := f.Value.([]interface{}); ok {
+			for _, v := range values {
+				writeValue(c, f.Type, v)
+			}
+		} else {
+			writeValue(c, f.Type, f.Value)
+		}
+	}
+	c.Flush()
+}
+
+// Deserialize decodes a payload with the schema. A truncated payload panics,
+// which decoding reports as a malformed command.
+func (d *DynamicCommand) Deserialize(r io.Reader) {
+	d.Fields = nil
+	if d.Schema == nil {
+		return
+	}
+	payload, _ := io.ReadAll(r)
+	buf := &payloadReader{Reader: bytes.NewReader(payload)}
+	c := composer.NewWithR(buf)
+	read := func(fd *FieldDescriptor) interface{} {
+		value := readValue(c, fd.Type)
+		if buf.short {
+			panic(fmt.Errorf("field %s is truncated", fd.Name))
+		}
+		return value
+	}
+	for _, fd := range d.Schema.Fields {
+		if buf.Len() == 0 || !d.present(fd.Condition) {
+			continue
+		}
+		if (fd.List || fd.CountField != "") && zeroWidth(fd.Type) {
+			panic(fmt.Errorf("repeated field %s has zero width type %s", fd.Name, fd.Type))
+		}
+		switch {
+		case fd.List:
+			values := []interface{}{}
+			for buf.Len() > 0 {
+				before := buf.Len()
+				values = append(values, read(fd))
+				if buf.Len() == before {
+					break
+				}
+			}
+			d.Set(fd.Name, fd.Type, values)
+		case fd.CountField != "":
+			count, _ := d.Get(fd.CountField)
+			values := []interface{}{}
+			for i := uint64(0); i < toUint64(count); i++ {
+				before := buf.Len()
+				values = append(values, read(fd))
+				if buf.Len() == before {
+					break
+				}
+			}
+			d.Set(fd.Name, fd.Type, values)
+		default:
+			d.Set(fd.Name, fd.Type, read(fd))
+		}
+	}
+}
+
+// payloadReader records reads past the end of the payload, which readValue
+// doesn't report.
+type payloadReader struct {
+	*bytes.Reader
+	short bool
+}
+
+func (r *payloadReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n < len(p) {
+		r.short = true
+	}
+	return n, err
+}
+
+// zeroWidth reports whether values of a data type are read from no bytes, so
+// they can't be repeated until the end of a payload.
+func zeroWidth(dataType ZclDataType) bool {
+	switch dataType {
+	case ZclDataTypeNoData, ZclDataTypeStruct, ZclDataTypeUnknown:
+		return true
+	}
+	return false
+}
+
+func (d *DynamicCommand) field(name string) *Field {
+	for _, f := range d.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// ordered returns the fields in schema order, fields unknown to the schema last.
+func (d *DynamicCommand) ordered() []*Field {
+	if d.Schema == nil {
+		return d.Fields
+	}
+	var fields []*Field
+	known := map[string]bool{}
+	for _, fd := range d.Schema.Fields {
+		known[fd.Name] = true
+		if f := d.field(fd.Name); f != nil {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range d.Fields {
+		if !known[f.Name] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (d *DynamicCommand) present(condition *FieldCondition) bool {
+	if condition == nil {
+		return true
+	}
+	value, ok := d.Get(condition.Field)
+	if !ok {
+		return false
+	}
+	v := toUint64(value)
+	if condition.Mask != 0 {
+		v &= condition.Mask
+	}
+	return (v == condition.Value) != condition.Negate
+}
+
+func toUint64(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint64:
+		return v
+	case int64:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case bool:
+		return uint64(flag(v))
+	}
+	return 0
+}
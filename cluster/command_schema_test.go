@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"github.com/dyrkin/bin"
+	. "gopkg.in/check.v1"
+)
+
+type CommandSchemaSuite struct{}
+
+var _ = Suite(&CommandSchemaSuite{})
+
+var groupSchema = &CommandSchema{
+	Fields: []*FieldDescriptor{
+		{Name: "Options", Type: ZclDataTypeBitmap8},
+		{Name: "Name", Type: ZclDataTypeCharStr, Condition: &FieldCondition{Field: "Options", Mask: 0x01, Value: 0x01}},
+		{Name: "Count", Type: ZclDataTypeUint8},
+		{Name: "GroupIds", Type: ZclDataTypeUint16, CountField: "Count"},
+		{Name: "Extra", Type: ZclDataTypeInt8, List: true},
+	},
+}
+
+func (s *CommandSchemaSuite) TestDecode(c *C) {
+	payload := []uint8{0x03, 0x02, 0x41, 0x42, 0x02, 0x01, 0x00, 0x02, 0x00, 0xff, 0x01}
+	command := groupSchema.NewCommand()
+	bin.Decode(payload, command)
+
+	c.Assert(command.Fields, HasLen, 5)
+	c.Assert(command.Fields[1], DeepEquals, &Field{"Name", ZclDataTypeCharStr, "AB"})
+	groupIds, ok := command.Get("GroupIds")
+	c.Assert(ok, Equals, true)
+	c.Assert(groupIds, DeepEquals, []interface{}{uint64(1), uint64(2)})
+	extra, _ := command.Get("Extra")
+	c.Assert(extra, DeepEquals, []interface{}{int64(-1), int64(1)})
+
+	c.Assert(bin.Encode(command), DeepEquals, payload)
+}
+
+func (s *CommandSchemaSuite) TestDecodeSkipsAbsentFields(c *C) {
+	command := groupSchema.NewCommand()
+	bin.Decode([]uint8{0x00, 0x00}, command)
+
+	c.Assert(command.Fields, HasLen, 2)
+	_, ok := command.Get("Name")
+	c.Assert(ok, Equals, false)
+	c.Assert(command.Fields[1].Name, Equals, "Count")
+}
+
+func (s *CommandSchemaSuite) TestEncodeInSchemaOrder(c *C) {
+	command := groupSchema.NewCommand()
+	command.Set("Count", ZclDataTypeUint8, uint64(1))
+	command.Set("GroupIds", ZclDataTypeUint16, []interface{}{uint64(0x1234)})
+	command.Set("Options", ZclDataTypeBitmap8, uint64(0))
+
+	c.Assert(bin.Encode(command), DeepEquals, []uint8{0x00, 0x01, 0x34, 0x12})
+}
+
+func (s *CommandSchemaSuite) TestDecodeRejectsZeroWidthRepeatedFields(c *C) {
+	for _, dataType := range []ZclDataType{ZclDataTypeNoData, ZclDataTypeStruct, ZclDataTypeUnknown} {
+		for _, fd := range []*FieldDescriptor{
+			{Name: "Values", Type: dataType, List: true},
+			{Name: "Values", Type: dataType, CountField: "Count"},
+		} {
+			schema := &CommandSchema{Fields: []*FieldDescriptor{{Name: "Count", Type: ZclDataTypeUint32}, fd}}
+			c.Assert(schema.validate(), ErrorMatches, "repeated field Values has zero width type .*")
+			command := schema.NewCommand()
+			c.Assert(func() { bin.Decode([]uint8{0xff, 0xff, 0xff, 0xff, 0x01}, command) }, PanicMatches, ".*repeated field Values has zero width type .*")
+		}
+	}
+}
+
+func (s *CommandSchemaSuite) TestDecodeTruncatedPayload(c *C) {
+	command := groupSchema.NewCommand()
+	c.Assert(func() { bin.Decode([]uint8{0x00, 0x03, 0x01, 0x00, 0x02}, command) }, PanicMatches, ".*field GroupIds is truncated.*")
+
+	command = groupSchema.NewCommand()
+	c.Assert(func() { bin.Decode([]uint8{0x01, 0x05, 0x41}, command) }, PanicMatches, ".*field Name is truncated.*")
+}
@@ -0,0 +1,221 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+const (
+	TouchlinkKeyIndexDevelopment   uint8 = 0x00
+	TouchlinkKeyIndexMaster        uint8 = 0x04
+	TouchlinkKeyIndexCertification uint8 = 0x0f
+)
+
+type TouchlinkZigbeeInformation struct {
+	LogicalType  uint8 `bits:"0b00000011" bitmask:"start"`
+	RxOnWhenIdle uint8 `bits:"0b00000100"`
+	Reserved     uint8 `bits:"0b11111000" bitmask:"end"`
+}
+
+type TouchlinkInformation struct {
+	FactoryNew               uint8 `bits:"0b00000001" bitmask:"start"`
+	AddressAssignment        uint8 `bits:"0b00000010"`
+	Reserved1                uint8 `bits:"0b00001100"`
+	TouchlinkInitiator       uint8 `bits:"0b00010000"`
+	TouchlinkPriorityRequest uint8 `bits:"0b00100000"`
+	Reserved2                uint8 `bits:"0b11000000" bitmask:"end"`
+}
+
+type ScanRequestCommand struct {
+	InterPANTransactionID uint32
+	ZigbeeInformation     *TouchlinkZigbeeInformation
+	TouchlinkInformation  *TouchlinkInformation
+}
+
+type ScanResponse struct {
+	InterPANTransactionID uint32
+	RSSICorrection        uint8
+	ZigbeeInformation     *TouchlinkZigbeeInformation
+	TouchlinkInformation  *TouchlinkInformation
+	KeyBitmask            uint16
+	ResponseID            uint32
+	ExtendedPANID         string `hex:"8"`
+	NetworkUpdateID       uint8
+	LogicalChannel        uint8
+	PANID                 uint16
+	NetworkAddress        uint16
+	NumberOfSubDevices    uint8
+	TotalGroupIdentifiers uint8
+	EndpointID            uint8  `cond:"uint:NumberOfSubDevices==1"`
+	ProfileID             uint16 `cond:"uint:NumberOfSubDevices==1"`
+	DeviceID              uint16 `cond:"uint:NumberOfSubDevices==1"`
+	Version               uint8  `cond:"uint:NumberOfSubDevices==1"`
+	GroupIdentifierCount  uint8  `cond:"uint:NumberOfSubDevices==1"`
+}
+
+type DeviceInformationRequestCommand struct {
+	InterPANTransactionID uint32
+	StartIndex            uint8
+}
+
+type DeviceInformationRecord struct {
+	IEEEAddress          string `hex:"8"`
+	EndpointID           uint8
+	ProfileID            uint16
+	DeviceID             uint16
+	Version              uint8
+	GroupIdentifierCount uint8
+	Sort                 uint8
+}
+
+type DeviceInformationResponse struct {
+	InterPANTransactionID    uint32
+	NumberOfSubDevices       uint8
+	StartIndex               uint8
+	DeviceInformationRecords []*DeviceInformationRecord `size:"1"`
+}
+
+type IdentifyRequestCommand struct {
+	InterPANTransactionID uint32
+	IdentifyDuration      uint16
+}
+
+type ResetToFactoryNewRequestCommand struct {
+	InterPANTransactionID uint32
+}
+
+type NetworkStartRequestCommand struct {
+	InterPANTransactionID         uint32
+	ExtendedPANID                 string `hex:"8"`
+	KeyIndex                      uint8
+	EncryptedNetworkKey           [16]uint8
+	LogicalChannel                uint8
+	PANID                         uint16
+	NetworkAddress                uint16
+	GroupIdentifiersBegin         uint16
+	GroupIdentifiersEnd           uint16
+	FreeNetworkAddressRangeBegin  uint16
+	FreeNetworkAddressRangeEnd    uint16
+	FreeGroupIdentifierRangeBegin uint16
+	FreeGroupIdentifierRangeEnd   uint16
+	InitiatorIEEEAddress          string `hex:"8"`
+	InitiatorNetworkAddress       uint16
+}
+
+type NetworkStartResponse struct {
+	InterPANTransactionID uint32
+	Status                ZclStatus
+	ExtendedPANID         string `hex:"8"`
+	NetworkUpdateID       uint8
+	LogicalChannel        uint8
+	PANID                 uint16
+}
+
+type NetworkJoinRouterRequestCommand struct {
+	InterPANTransactionID         uint32
+	ExtendedPANID                 string `hex:"8"`
+	KeyIndex                      uint8
+	EncryptedNetworkKey           [16]uint8
+	NetworkUpdateID               uint8
+	LogicalChannel                uint8
+	PANID                         uint16
+	NetworkAddress                uint16
+	GroupIdentifiersBegin         uint16
+	GroupIdentifiersEnd           uint16
+	FreeNetworkAddressRangeBegin  uint16
+	FreeNetworkAddressRangeEnd    uint16
+	FreeGroupIdentifierRangeBegin uint16
+	FreeGroupIdentifierRangeEnd   uint16
+}
+
+type NetworkJoinRouterResponse struct {
+	InterPANTransactionID uint32
+	Status                ZclStatus
+}
+
+type NetworkJoinEndDeviceRequestCommand struct {
+	InterPANTransactionID         uint32
+	ExtendedPANID                 string `hex:"8"`
+	KeyIndex                      uint8
+	EncryptedNetworkKey           [16]uint8
+	NetworkUpdateID               uint8
+	LogicalChannel                uint8
+	PANID                         uint16
+	NetworkAddress                uint16
+	GroupIdentifiersBegin         uint16
+	GroupIdentifiersEnd           uint16
+	FreeNetworkAddressRangeBegin  uint16
+	FreeNetworkAddressRangeEnd    uint16
+	FreeGroupIdentifierRangeBegin uint16
+	FreeGroupIdentifierRangeEnd   uint16
+}
+
+type NetworkJoinEndDeviceResponse struct {
+	InterPANTransactionID uint32
+	Status                ZclStatus
+}
+
+type NetworkUpdateRequestCommand struct {
+	InterPANTransactionID uint32
+	ExtendedPANID         string `hex:"8"`
+	NetworkUpdateID       uint8
+	LogicalChannel        uint8
+	PANID                 uint16
+	NetworkAddress        uint16
+}
+
+type EndpointInformationCommand struct {
+	IEEEAddress    string `hex:"8"`
+	NetworkAddress uint16
+	EndpointID     uint8
+	ProfileID      uint16
+	DeviceID       uint16
+	Version        uint8
+}
+
+type GetGroupIdentifiersRequestCommand struct {
+	StartIndex uint8
+}
+
+type GroupInformationRecord struct {
+	GroupID   uint16
+	GroupType uint8
+}
+
+type GetGroupIdentifiersResponse struct {
+	Total                   uint8
+	StartIndex              uint8
+	GroupInformationRecords []*GroupInformationRecord `size:"1"`
+}
+
+type GetEndpointListRequestCommand struct {
+	StartIndex uint8
+}
+
+type EndpointInformationRecord struct {
+	NetworkAddress uint16
+	EndpointID     uint8
+	ProfileID      uint16
+	DeviceID       uint16
+	Version        uint8
+}
+
+type GetEndpointListResponse struct {
+	Total                      uint8
+	StartIndex                 uint8
+	EndpointInformationRecords []*EndpointInformationRecord `size:"1"`
+}
+
+// NewInterPANTransactionID returns a random non-zero transaction identifier
+// for a touchlink session.
+func NewInterPANTransactionID() uint32 {
+	var b [4]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(err)
+		}
+		if id := binary.LittleEndian.Uint32(b[:]); id != 0 {
+			return id
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package cluster
+
+import (
+	"github.com/dyrkin/bin"
+	. "gopkg.in/check.v1"
+)
+
+type TouchlinkSuite struct{}
+
+var _ = Suite(&TouchlinkSuite{})
+
+func (s *TouchlinkSuite) TestDecodeScanResponse(c *C) {
+	res := &ScanResponse{}
+	payload := []byte{
+		0x78, 0x56, 0x34, 0x12, //inter-pan transaction id
+		0x00,       //rssi correction
+		0x06,       //end device, rx on when idle
+		0x13,       //factory new, address assignment, initiator
+		0x12, 0x00, //key bitmask
+		0xef, 0xbe, 0xad, 0xde, //response id
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, //extended pan id
+		0x00, 0x0b, 0xcd, 0xab, 0x01, 0x00, //update id, channel, pan id, network address
+		0x01, 0x00, //sub devices, group identifiers
+		0x0b, 0x5e, 0xc0, 0x00, 0x02, 0x02, 0x00, //single sub device
+	}
+	bin.Decode(payload, res)
+	c.Assert(res, DeepEquals, &ScanResponse{
+		InterPANTransactionID: 0x12345678,
+		ZigbeeInformation:     &TouchlinkZigbeeInformation{LogicalType: 2, RxOnWhenIdle: 1},
+		TouchlinkInformation:  &TouchlinkInformation{FactoryNew: 1, AddressAssignment: 1, TouchlinkInitiator: 1},
+		KeyBitmask:            0x0012,
+		ResponseID:            0xdeadbeef,
+		ExtendedPANID:         "0x0102030405060708",
+		LogicalChannel:        11,
+		PANID:                 0xabcd,
+		NetworkAddress:        0x0001,
+		NumberOfSubDevices:    1,
+		EndpointID:            0x0b,
+		ProfileID:             0xc05e,
+		DeviceID:              0x0200,
+		Version:               0x02,
+	})
+	c.Assert(bin.Encode(res), DeepEquals, payload)
+}
+
+func (s *TouchlinkSuite) TestEncodeResetToFactoryNewRequest(c *C) {
+	res := bin.Encode(&ResetToFactoryNewRequestCommand{0x12345678})
+	c.Assert(res, DeepEquals, []byte{0x78, 0x56, 0x34, 0x12})
+	c.Assert(NewInterPANTransactionID(), Not(Equals), uint32(0))
+}
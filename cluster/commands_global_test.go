@@ -66,3 +66,37 @@ func (s *CommandsGlobalSuite) TestEncodeReadAttributesResponse(c *C) {
 	}
 	c.Assert(res, DeepEquals, expected)
 }
+
+func (s *CommandsGlobalSuite) TestFloatAttributes(c *C) {
+	res := &ReportAttributesCommand{}
+	payload := []byte{
+		0x55, 0x00, byte(ZclDataTypeSinglePrec), 0x00, 0x00, 0xb4, 0x41, //22.5
+		0x41, 0x00, byte(ZclDataTypeSemiPrec), 0x00, 0x3e, //1.5
+		0x45, 0x00, byte(ZclDataTypeDoublePrec), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0xc0, //-2.5
+	}
+	bin.Decode(payload, res)
+	expected := &ReportAttributesCommand{
+		[]*AttributeReport{
+			{"", 0x55, &Attribute{ZclDataTypeSinglePrec, float32(22.5)}},
+			{"", 0x41, &Attribute{ZclDataTypeSemiPrec, float32(1.5)}},
+			{"", 0x45, &Attribute{ZclDataTypeDoublePrec, float64(-2.5)}},
+		},
+	}
+	c.Assert(res, DeepEquals, expected)
+	c.Assert(bin.Encode(expected), DeepEquals, payload)
+}
+
+func (s *CommandsGlobalSuite) TestDecodeAttribute(c *C) {
+	attribute, err := DecodeAttribute([]uint8{byte(ZclDataTypeCharStr), 0x02, 0x41, 0x42, 0xff})
+	c.Assert(err, IsNil)
+	c.Assert(attribute, DeepEquals, &Attribute{ZclDataTypeCharStr, "AB"})
+
+	_, err = DecodeAttribute([]uint8{byte(ZclDataTypeCharStr), 0x05, 0x41})
+	c.Assert(err, ErrorMatches, "CharStr attribute truncated after 3 bytes")
+	_, err = DecodeAttribute([]uint8{byte(ZclDataTypeUint32), 0x01, 0x02})
+	c.Assert(err, NotNil)
+	_, err = DecodeAttribute([]uint8{byte(ZclDataTypeArray), 0xff, 0xff})
+	c.Assert(err, NotNil)
+	_, err = DecodeAttribute(nil)
+	c.Assert(err, ErrorMatches, "attribute data type missing")
+}
@@ -1,5 +1,7 @@
 package cluster
 
+import "fmt"
+
 type ZclDataType uint8
 
 const (
@@ -61,6 +63,77 @@ const (
 	ZclDataTypeUnknown       ZclDataType = 0xff
 )
 
+var zclDataTypeNames = map[ZclDataType]string{
+	ZclDataTypeNoData:        "NoData",
+	ZclDataTypeData8:         "Data8",
+	ZclDataTypeData16:        "Data16",
+	ZclDataTypeData24:        "Data24",
+	ZclDataTypeData32:        "Data32",
+	ZclDataTypeData40:        "Data40",
+	ZclDataTypeData48:        "Data48",
+	ZclDataTypeData56:        "Data56",
+	ZclDataTypeData64:        "Data64",
+	ZclDataTypeBoolean:       "Boolean",
+	ZclDataTypeBitmap8:       "Bitmap8",
+	ZclDataTypeBitmap16:      "Bitmap16",
+	ZclDataTypeBitmap24:      "Bitmap24",
+	ZclDataTypeBitmap32:      "Bitmap32",
+	ZclDataTypeBitmap40:      "Bitmap40",
+	ZclDataTypeBitmap48:      "Bitmap48",
+	ZclDataTypeBitmap56:      "Bitmap56",
+	ZclDataTypeBitmap64:      "Bitmap64",
+	ZclDataTypeUint8:         "Uint8",
+	ZclDataTypeUint16:        "Uint16",
+	ZclDataTypeUint24:        "Uint24",
+	ZclDataTypeUint32:        "Uint32",
+	ZclDataTypeUint40:        "Uint40",
+	ZclDataTypeUint48:        "Uint48",
+	ZclDataTypeUint56:        "Uint56",
+	ZclDataTypeUint64:        "Uint64",
+	ZclDataTypeInt8:          "Int8",
+	ZclDataTypeInt16:         "Int16",
+	ZclDataTypeInt24:         "Int24",
+	ZclDataTypeInt32:         "Int32",
+	ZclDataTypeInt40:         "Int40",
+	ZclDataTypeInt48:         "Int48",
+	ZclDataTypeInt56:         "Int56",
+	ZclDataTypeInt64:         "Int64",
+	ZclDataTypeEnum8:         "Enum8",
+	ZclDataTypeEnum16:        "Enum16",
+	ZclDataTypeSemiPrec:      "SemiPrec",
+	ZclDataTypeSinglePrec:    "SinglePrec",
+	ZclDataTypeDoublePrec:    "DoublePrec",
+	ZclDataTypeOctetStr:      "OctetStr",
+	ZclDataTypeCharStr:       "CharStr",
+	ZclDataTypeLongOctetStr:  "LongOctetStr",
+	ZclDataTypeLongCharStr:   "LongCharStr",
+	ZclDataTypeArray:         "Array",
+	ZclDataTypeStruct:        "Struct",
+	ZclDataTypeSet:           "Set",
+	ZclDataTypeBag:           "Bag",
+	ZclDataTypeTod:           "Tod",
+	ZclDataTypeDate:          "Date",
+	ZclDataTypeUtc:           "Utc",
+	ZclDataTypeClusterId:     "ClusterId",
+	ZclDataTypeAttrId:        "AttrId",
+	ZclDataTypeBacOid:        "BacOid",
+	ZclDataTypeIeeeAddr:      "IeeeAddr",
+	ZclDataType_128BitSecKey: "128BitSecKey",
+	ZclDataTypeUnknown:       "Unknown",
+}
+
+func (t ZclDataType) Known() bool {
+	_, ok := zclDataTypeNames[t]
+	return ok
+}
+
+func (t ZclDataType) String() string {
+	if name, ok := zclDataTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ZclDataType(0x%02x)", uint8(t))
+}
+
 type ZclStatus uint8
 
 const (
@@ -96,10 +169,11 @@ const (
 	ZclStatusRequireMoreImage         ZclStatus = 0x99
 
 	// 0xbd-bf are reserved.
-	ZclStatusHardwareFailure  ZclStatus = 0xc0
-	ZclStatusSoftwareFailure  ZclStatus = 0xc1
-	ZclStatusCalibrationError ZclStatus = 0xc2
-	// 0xc3-0xff are reserved.
+	ZclStatusHardwareFailure    ZclStatus = 0xc0
+	ZclStatusSoftwareFailure    ZclStatus = 0xc1
+	ZclStatusCalibrationError   ZclStatus = 0xc2
+	ZclStatusUnsupportedCluster ZclStatus = 0xc3
+	// 0xc4-0xff are reserved.
 	ZclStatusCmdHasRsp ZclStatus = 0xFF // Non-standard status (used for Default Rsp)
 )
 
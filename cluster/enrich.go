@@ -0,0 +1,62 @@
+package cluster
+
+import "github.com/dyrkin/zcl-go/frame"
+
+// Resolver resolves identifiers carried by a command to names, in the context
+// of the cluster, manufacturer code and direction of the frame it came in.
+type Resolver interface {
+	Direction() frame.Direction
+	AttributeName(attributeId uint16) string
+	// CommandDescriptor resolves a cluster specific command sent in direction.
+	CommandDescriptor(direction frame.Direction, commandId uint8) (*CommandDescriptor, bool)
+	GlobalCommandDescriptor(commandId uint8) (*CommandDescriptor, bool)
+}
+
+// Enricher is implemented by commands which resolve names beyond the
+// convention applied to every decoded command: a struct with AttributeID and
+// AttributeName fields gets the name of the attribute.
+type Enricher interface {
+	Enrich(r Resolver)
+}
+
+// Enrich resolves the command the default response answers, which was sent in
+// the opposite direction. The frame type of that command isn't carried, so
+// the name is left empty if both a cluster specific and a global command have
+// its id. Responses are never answered by a default response and don't count.
+func (c *DefaultResponseCommand) Enrich(r Resolver) {
+	local, ok := r.CommandDescriptor(r.Direction().Opposite(), c.CommandID)
+	if ok && local.Response {
+		local = nil
+	}
+	global, ok := r.GlobalCommandDescriptor(c.CommandID)
+	if ok && global.Response {
+		global = nil
+	}
+	switch {
+	case local != nil && global == nil:
+		c.CommandName = local.Name
+	case global != nil && local == nil:
+		c.CommandName = global.Name
+	}
+}
+
+// Enrich resolves the commands the responder receives, which are sent towards it.
+func (c *DiscoverCommandsReceivedResponse) Enrich(r Resolver) {
+	c.Commands = discoveredCommands(r, r.Direction().Opposite(), c.CommandIdentifiers)
+}
+
+// Enrich resolves the commands the responder generates.
+func (c *DiscoverCommandsGeneratedResponse) Enrich(r Resolver) {
+	c.Commands = discoveredCommands(r, r.Direction(), c.CommandIdentifiers)
+}
+
+func discoveredCommands(r Resolver, direction frame.Direction, commandIds []uint8) []*DiscoveredCommand {
+	commands := make([]*DiscoveredCommand, len(commandIds))
+	for i, commandId := range commandIds {
+		commands[i] = &DiscoveredCommand{CommandID: commandId}
+		if cd, ok := r.CommandDescriptor(direction, commandId); ok {
+			commands[i].Name, commands[i].Known = cd.Name, true
+		}
+	}
+	return commands
+}
@@ -0,0 +1,291 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Enum maps the values of an enumerated attribute to their names.
+type Enum map[uint64]string
+
+// Bitmap maps the masks of the flags of a bitmap attribute to their names.
+type Bitmap map[uint64]string
+
+// Symbol returns the name of an enumerated value.
+func (e Enum) Symbol(value uint64) (string, bool) {
+	symbol, ok := e[value]
+	return symbol, ok
+}
+
+// Value returns the value of a name, the lowest one if a name is used twice.
+func (e Enum) Value(symbol string) (uint64, bool) {
+	found := false
+	var value uint64
+	for v, s := range e {
+		if s == symbol && (!found || v < value) {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// Flags returns the names of the flags set in value, ordered by mask. Bits
+// which aren't covered by a flag are returned as a hex number.
+func (b Bitmap) Flags(value uint64) []string {
+	flags := []string{}
+	rest := value
+	for _, mask := range b.masks() {
+		if mask != 0 && value&mask == mask {
+			flags = append(flags, b[mask])
+			rest &^= mask
+		}
+	}
+	if rest != 0 {
+		flags = append(flags, fmt.Sprintf("0x%x", rest))
+	}
+	return flags
+}
+
+// Value returns the value with the named flags set. Hex numbers are accepted
+// for bits which don't have a name.
+func (b Bitmap) Value(flags []string) (uint64, error) {
+	var value uint64
+	for _, flag := range flags {
+		mask, ok := b.mask(flag)
+		if !ok {
+			return 0, fmt.Errorf("unknown flag %q", flag)
+		}
+		value |= mask
+	}
+	return value, nil
+}
+
+func (b Bitmap) mask(flag string) (uint64, bool) {
+	for _, mask := range b.masks() {
+		if b[mask] == flag {
+			return mask, true
+		}
+	}
+	if strings.HasPrefix(flag, "0x") {
+		if mask, err := strconv.ParseUint(flag[2:], 16, 64); err == nil {
+			return mask, true
+		}
+	}
+	return 0, false
+}
+
+func (b Bitmap) masks() []uint64 {
+	masks := make([]uint64, 0, len(b))
+	for mask := range b {
+		masks = append(masks, mask)
+	}
+	sort.Slice(masks, func(i, j int) bool { return masks[i] < masks[j] })
+	return masks
+}
+
+// Render returns the name of an enumerated value or the names of the flags of
+// a bitmap value. Other values, and enumerated values without a name, are
+// returned as they are.
+func (ad *AttributeDescriptor) Render(value interface{}) interface{} {
+	v, ok := value.(uint64)
+	if !ok {
+		return value
+	}
+	if ad.Enum != nil {
+		if symbol, ok := ad.Enum.Symbol(v); ok {
+			return symbol
+		}
+	}
+	if ad.Bitmap != nil {
+		return ad.Bitmap.Flags(v)
+	}
+	return value
+}
+
+// Encode builds an attribute value from the name of an enumerated value or
+// the names of the flags of a bitmap.
+func (ad *AttributeDescriptor) Encode(symbols ...string) (*Attribute, error) {
+	switch {
+	case ad.Enum != nil:
+		if len(symbols) != 1 {
+			return nil, fmt.Errorf("attribute %s takes a single value, got %d", ad.Name, len(symbols))
+		}
+		v, ok := ad.Enum.Value(symbols[0])
+		if !ok {
+			return nil, fmt.Errorf("unknown value %q of attribute %s", symbols[0], ad.Name)
+		}
+		return &Attribute{ad.Type, v}, nil
+	case ad.Bitmap != nil:
+		v, err := ad.Bitmap.Value(symbols)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %s", ad.Name, err)
+		}
+		return &Attribute{ad.Type, v}, nil
+	}
+	return nil, fmt.Errorf("attribute %s is neither an enum nor a bitmap", ad.Name)
+}
+
+var PowerSourceEnum = Enum{
+	0x00: "Unknown",
+	0x01: "SinglePhaseMains",
+	0x02: "ThreePhaseMains",
+	0x03: "Battery",
+	0x04: "DCSource",
+	0x05: "EmergencyMainsConstantlyPowered",
+	0x06: "EmergencyMainsAndTransferSwitch",
+}
+
+var PhysicalEnvironmentEnum = Enum{
+	0x00: "Unspecified",
+	0x01: "Atrium",
+	0x02: "Bar",
+	0x03: "Courtyard",
+	0x04: "Bathroom",
+	0x05: "Bedroom",
+	0x06: "BilliardRoom",
+	0x07: "UtilityRoom",
+	0x08: "Cellar",
+	0x09: "StorageCloset",
+	0x0a: "Theater",
+	0x0b: "Office",
+	0x0c: "Deck",
+	0x0d: "Den",
+	0x0e: "DiningRoom",
+	0x0f: "ElectricalRoom",
+	0x10: "Elevator",
+	0x11: "Entry",
+	0x12: "FamilyRoom",
+	0x13: "MainFloor",
+	0x14: "Upstairs",
+	0x15: "Downstairs",
+	0x16: "Basement",
+	0x17: "Gallery",
+	0x18: "GameRoom",
+	0x19: "Garage",
+	0x1a: "Gym",
+	0x1b: "Hallway",
+	0x1c: "House",
+	0x1d: "Kitchen",
+	0x1e: "LaundryRoom",
+	0x1f: "Library",
+	0x20: "MasterBedroom",
+	0x21: "MudRoom",
+	0x22: "Nursery",
+	0x23: "Pantry",
+	0x24: "Office",
+	0x25: "Outside",
+	0x26: "Pool",
+	0x27: "Porch",
+	0x28: "SewingRoom",
+	0x29: "SittingRoom",
+	0x2a: "Stairway",
+	0x2b: "Yard",
+	0x2c: "Attic",
+	0x2d: "HotTub",
+	0x2e: "LivingRoom",
+	0x2f: "Sauna",
+	0x30: "Workshop",
+	0x31: "GuestBedroom",
+	0x32: "GuestBath",
+	0x33: "PowderRoom",
+	0x34: "BackYard",
+	0x35: "FrontYard",
+	0x36: "Patio",
+	0x37: "Driveway",
+	0x38: "SunRoom",
+	0x39: "LivingRoom",
+	0x3a: "Spa",
+	0x3b: "Whirlpool",
+	0x3c: "Shed",
+	0x3d: "EquipmentStorage",
+	0x3e: "CraftRoom",
+	0x3f: "Fountain",
+	0x40: "Pond",
+	0x41: "ReceptionRoom",
+	0x42: "BreakfastRoom",
+	0x43: "Nook",
+	0x44: "Garden",
+	0x45: "Balcony",
+	0x46: "PanicRoom",
+	0x47: "Terrace",
+	0x48: "Roof",
+	0xff: "Unknown",
+}
+
+var ImageUpgradeStatusEnum = Enum{
+	0x00: "Normal",
+	0x01: "DownloadInProgress",
+	0x02: "DownloadComplete",
+	0x03: "WaitingToUpgrade",
+	0x04: "CountDown",
+	0x05: "WaitForMore",
+}
+
+var BatterySizeEnum = Enum{
+	0x00: "NoBattery",
+	0x01: "BuiltIn",
+	0x02: "Other",
+	0x03: "AA",
+	0x04: "AAA",
+	0x05: "C",
+	0x06: "D",
+	0x07: "CR2",
+	0x08: "CR123A",
+	0xff: "Unknown",
+}
+
+var ReliabilityEnum = Enum{
+	0x00: "NoFaultDetected",
+	0x01: "NoSensor",
+	0x02: "OverRange",
+	0x03: "UnderRange",
+	0x04: "OpenLoop",
+	0x05: "ShortedLoop",
+	0x06: "NoOutput",
+	0x07: "UnreliableOther",
+	0x08: "ProcessError",
+	0x09: "MultiStateFault",
+	0x0a: "ConfigurationError",
+}
+
+var PolarityEnum = Enum{
+	0x00: "Normal",
+	0x01: "Reverse",
+}
+
+var AlarmMaskBitmap = Bitmap{
+	0x01: "GeneralHardwareFault",
+	0x02: "GeneralSoftwareFault",
+}
+
+var DisableLocalConfigBitmap = Bitmap{
+	0x01: "DisableResetToFactoryDefaults",
+	0x02: "DisableDeviceConfiguration",
+}
+
+var MainsAlarmMaskBitmap = Bitmap{
+	0x01: "MainsVoltageTooLow",
+	0x02: "MainsVoltageTooHigh",
+	0x04: "MainsPowerSupplyLost",
+}
+
+var BatteryAlarmMaskBitmap = Bitmap{
+	0x01: "BatteryVoltageTooLow",
+	0x02: "BatteryAlarm1",
+	0x04: "BatteryAlarm2",
+	0x08: "BatteryAlarm3",
+}
+
+var DeviceTempAlarmMaskBitmap = Bitmap{
+	0x01: "DeviceTemperatureTooLow",
+	0x02: "DeviceTemperatureTooHigh",
+}
+
+var StatusFlagsBitmap = Bitmap{
+	0x01: "InAlarm",
+	0x02: "Fault",
+	0x04: "Overridden",
+	0x08: "OutOfService",
+}
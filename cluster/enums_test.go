@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type EnumsSuite struct{}
+
+var _ = Suite(&EnumsSuite{})
+
+func (s *EnumsSuite) TestRender(c *C) {
+	library := New()
+	powerSource, _ := library.AttributeDescriptor(Basic, 0, 0x0007)
+	c.Assert(powerSource.Render(uint64(0x03)), Equals, "Battery")
+	c.Assert(powerSource.Render(uint64(0x42)), Equals, uint64(0x42))
+
+	alarmMask, _ := library.AttributeDescriptor(Basic, 0, 0x0013)
+	c.Assert(alarmMask.Render(uint64(0x01)), DeepEquals, []string{"GeneralHardwareFault"})
+	c.Assert(alarmMask.Render(uint64(0x07)), DeepEquals, []string{"GeneralHardwareFault", "GeneralSoftwareFault", "0x4"})
+	c.Assert(alarmMask.Render(uint64(0)), DeepEquals, []string{})
+
+	ad, _ := library.AttributeDescriptor(Basic, 0, 0x0005)
+	c.Assert(ad.Render("Model"), Equals, "Model")
+}
+
+func (s *EnumsSuite) TestEncode(c *C) {
+	library := New()
+	environment, _ := library.AttributeDescriptor(Basic, 0, 0x0011)
+	attribute, err := environment.Encode("Kitchen")
+	c.Assert(err, IsNil)
+	c.Assert(attribute, DeepEquals, &Attribute{ZclDataTypeEnum8, uint64(0x1d)})
+	attribute, _ = environment.Encode("Office")
+	c.Assert(attribute.Value, Equals, uint64(0x0b))
+	_, err = environment.Encode("Dungeon")
+	c.Assert(err, ErrorMatches, `unknown value "Dungeon" of attribute PhysicalEnvironment`)
+
+	mask, _ := library.AttributeDescriptor(PowerConfiguration, 0, 0x0035)
+	attribute, err = mask.Encode("BatteryVoltageTooLow", "BatteryAlarm2")
+	c.Assert(err, IsNil)
+	c.Assert(attribute, DeepEquals, &Attribute{ZclDataTypeBitmap8, uint64(0x05)})
+	_, err = mask.Encode("Flood")
+	c.Assert(err, ErrorMatches, `attribute BatteryAlarmMask: unknown flag "Flood"`)
+
+	ad, _ := library.AttributeDescriptor(Basic, 0, 0x0005)
+	_, err = ad.Encode("Model")
+	c.Assert(err, ErrorMatches, "attribute ModelIdentifier is neither an enum nor a bitmap")
+}
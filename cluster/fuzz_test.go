@@ -0,0 +1,28 @@
+package cluster
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzAttributeDeserialize(f *testing.F) {
+	f.Add([]uint8{byte(ZclDataTypeCharStr), 0x0c, 0x6c, 0x75, 0x6d, 0x69, 0x2e, 0x73, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x5f})
+	f.Add([]uint8{byte(ZclDataTypeInt16), 0x2b, 0x09})
+	f.Add([]uint8{byte(ZclDataTypeBoolean), 0x01})
+	f.Add([]uint8{byte(ZclDataTypeArray), 0x02, 0x00, byte(ZclDataTypeInt24), 0xf8, 0xff, 0xff, byte(ZclDataTypeInt24), 0xf7, 0xff, 0xff})
+	f.Add([]uint8{byte(ZclDataTypeSinglePrec), 0x00, 0x00, 0xc8, 0x41})
+	f.Add([]uint8{byte(ZclDataTypeIeeeAddr), 0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01})
+	f.Fuzz(func(t *testing.T, data []uint8) {
+		attribute := &Attribute{}
+		attribute.Deserialize(bytes.NewReader(data))
+		decoded, err := DecodeAttribute(data)
+		if err != nil {
+			return
+		}
+		encoded := &bytes.Buffer{}
+		decoded.Serialize(encoded)
+		if encoded.Len() > len(data) {
+			t.Fatalf("decoded %d bytes as %x, re-encoded as %x", len(data), data, encoded.Bytes())
+		}
+	})
+}
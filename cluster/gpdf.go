@@ -0,0 +1,256 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/dyrkin/bin"
+)
+
+type GpdCommandId uint8
+
+const (
+	GpdCommandIdentify                  GpdCommandId = 0x00
+	GpdCommandRecallScene0              GpdCommandId = 0x10
+	GpdCommandRecallScene7              GpdCommandId = 0x17
+	GpdCommandStoreScene0               GpdCommandId = 0x18
+	GpdCommandStoreScene7               GpdCommandId = 0x1f
+	GpdCommandOff                       GpdCommandId = 0x20
+	GpdCommandOn                        GpdCommandId = 0x21
+	GpdCommandToggle                    GpdCommandId = 0x22
+	GpdCommandRelease                   GpdCommandId = 0x23
+	GpdCommandMoveUp                    GpdCommandId = 0x30
+	GpdCommandMoveDown                  GpdCommandId = 0x31
+	GpdCommandStepUp                    GpdCommandId = 0x32
+	GpdCommandStepDown                  GpdCommandId = 0x33
+	GpdCommandLevelControlStop          GpdCommandId = 0x34
+	GpdCommandMoveUpWithOnOff           GpdCommandId = 0x35
+	GpdCommandMoveDownWithOnOff         GpdCommandId = 0x36
+	GpdCommandStepUpWithOnOff           GpdCommandId = 0x37
+	GpdCommandStepDownWithOnOff         GpdCommandId = 0x38
+	GpdCommandPress1Of1                 GpdCommandId = 0x60
+	GpdCommandRelease1Of1               GpdCommandId = 0x61
+	GpdCommandPress1Of2                 GpdCommandId = 0x62
+	GpdCommandRelease1Of2               GpdCommandId = 0x63
+	GpdCommandPress2Of2                 GpdCommandId = 0x64
+	GpdCommandRelease2Of2               GpdCommandId = 0x65
+	GpdCommandShortPress1Of1            GpdCommandId = 0x66
+	GpdCommandShortPress1Of2            GpdCommandId = 0x67
+	GpdCommandShortPress2Of2            GpdCommandId = 0x68
+	GpdCommandPress8BitVector           GpdCommandId = 0x69
+	GpdCommandRelease8BitVector         GpdCommandId = 0x6a
+	GpdCommandCommissioning             GpdCommandId = 0xe0
+	GpdCommandDecommissioning           GpdCommandId = 0xe1
+	GpdCommandSuccess                   GpdCommandId = 0xe2
+	GpdCommandChannelRequest            GpdCommandId = 0xe3
+	GpdCommandApplicationDescription    GpdCommandId = 0xe4
+	GpdCommandCommissioningReply        GpdCommandId = 0xf0
+	GpdCommandChannelConfiguration      GpdCommandId = 0xf3
+	GpdCommandManufacturerSpecificFirst GpdCommandId = 0xb0
+	GpdCommandManufacturerSpecificLast  GpdCommandId = 0xbf
+)
+
+type GpdIdentify struct{}
+
+type GpdRecallScene struct {
+	Scene uint8
+}
+
+type GpdStoreScene struct {
+	Scene uint8
+}
+
+type GpdOff struct{}
+
+type GpdOn struct{}
+
+type GpdToggle struct{}
+
+type GpdRelease struct{}
+
+type GpdMove struct {
+	Up        bool
+	WithOnOff bool
+	Rate      uint8
+}
+
+type GpdStep struct {
+	Up             bool
+	WithOnOff      bool
+	StepSize       uint8
+	TransitionTime uint16
+}
+
+type GpdLevelControlStop struct{}
+
+// GpdButton is a press or release of one of the buttons of a one or two
+// button switch. Button is 1-based, Buttons is the number of buttons.
+type GpdButton struct {
+	Button  uint8
+	Buttons uint8
+	Press   bool
+	Short   bool
+}
+
+// GpdButtonVector is a press or release of a combination of up to eight
+// contacts, reported as a bit vector.
+type GpdButtonVector struct {
+	Press    bool
+	Contacts uint8
+}
+
+type GpdCommissioningOptions struct {
+	MacSequenceNumberCapability   uint8 `bits:"0b00000001" bitmask:"start"`
+	RxOnCapability                uint8 `bits:"0b00000010"`
+	ApplicationInformationPresent uint8 `bits:"0b00000100"`
+	Reserved                      uint8 `bits:"0b00001000"`
+	PanIdRequest                  uint8 `bits:"0b00010000"`
+	GpSecurityKeyRequest          uint8 `bits:"0b00100000"`
+	FixedLocation                 uint8 `bits:"0b01000000"`
+	ExtendedOptionsPresent        uint8 `bits:"0b10000000" bitmask:"end"`
+}
+
+type GpdCommissioningExtendedOptions struct {
+	SecurityLevelCapabilities uint8 `bits:"0b00000011" bitmask:"start"`
+	KeyType                   uint8 `bits:"0b00011100"`
+	GpdKeyPresent             uint8 `bits:"0b00100000"`
+	GpdKeyEncryption          uint8 `bits:"0b01000000"`
+	GpdOutgoingCounterPresent uint8 `bits:"0b10000000" bitmask:"end"`
+}
+
+type GpdCommissioning struct {
+	DeviceID           uint8
+	Options            *GpdCommissioningOptions
+	ExtendedOptions    *GpdCommissioningExtendedOptions `cond:"uint:Options.ExtendedOptionsPresent==1"`
+	GpdKey             [16]uint8                        `cond:"uint:Options.ExtendedOptionsPresent==1;uint:ExtendedOptions.GpdKeyPresent==1"`
+	GpdKeyMic          uint32                           `cond:"uint:Options.ExtendedOptionsPresent==1;uint:ExtendedOptions.GpdKeyPresent==1;uint:ExtendedOptions.GpdKeyEncryption==1"`
+	GpdOutgoingCounter uint32                           `cond:"uint:Options.ExtendedOptionsPresent==1;uint:ExtendedOptions.GpdOutgoingCounterPresent==1"`
+}
+
+type GpdDecommissioning struct{}
+
+type GpdSuccess struct{}
+
+type GpdChannelRequest struct {
+	NextChannel       uint8
+	SecondNextChannel uint8
+}
+
+// GpdRawCommand carries GPD commands that have no typed representation,
+// e.g. manufacturer-specific ones.
+type GpdRawCommand struct {
+	CommandID GpdCommandId
+	Payload   []uint8
+}
+
+// ParseGpdCommand turns the command id and payload embedded in a Green Power
+// Device Frame into a typed event.
+func ParseGpdCommand(commandId uint8, payload []uint8) (interface{}, error) {
+	id := GpdCommandId(commandId)
+	switch {
+	case id == GpdCommandIdentify:
+		return &GpdIdentify{}, nil
+	case id >= GpdCommandRecallScene0 && id <= GpdCommandRecallScene7:
+		return &GpdRecallScene{uint8(id - GpdCommandRecallScene0)}, nil
+	case id >= GpdCommandStoreScene0 && id <= GpdCommandStoreScene7:
+		return &GpdStoreScene{uint8(id - GpdCommandStoreScene0)}, nil
+	case id == GpdCommandOff:
+		return &GpdOff{}, nil
+	case id == GpdCommandOn:
+		return &GpdOn{}, nil
+	case id == GpdCommandToggle:
+		return &GpdToggle{}, nil
+	case id == GpdCommandRelease:
+		return &GpdRelease{}, nil
+	case id == GpdCommandMoveUp, id == GpdCommandMoveDown,
+		id == GpdCommandMoveUpWithOnOff, id == GpdCommandMoveDownWithOnOff:
+		move := &GpdMove{
+			Up:        id == GpdCommandMoveUp || id == GpdCommandMoveUpWithOnOff,
+			WithOnOff: id == GpdCommandMoveUpWithOnOff || id == GpdCommandMoveDownWithOnOff,
+		}
+		if len(payload) > 0 {
+			move.Rate = payload[0]
+		}
+		return move, nil
+	case id == GpdCommandStepUp, id == GpdCommandStepDown,
+		id == GpdCommandStepUpWithOnOff, id == GpdCommandStepDownWithOnOff:
+		if len(payload) < 1 {
+			return nil, fmt.Errorf("gpd step command 0x%02x is too short", commandId)
+		}
+		step := &GpdStep{
+			Up:        id == GpdCommandStepUp || id == GpdCommandStepUpWithOnOff,
+			WithOnOff: id == GpdCommandStepUpWithOnOff || id == GpdCommandStepDownWithOnOff,
+			StepSize:  payload[0],
+		}
+		if len(payload) >= 3 {
+			step.TransitionTime = binary.LittleEndian.Uint16(payload[1:3])
+		}
+		return step, nil
+	case id == GpdCommandLevelControlStop:
+		return &GpdLevelControlStop{}, nil
+	case id == GpdCommandPress1Of1:
+		return &GpdButton{Button: 1, Buttons: 1, Press: true}, nil
+	case id == GpdCommandRelease1Of1:
+		return &GpdButton{Button: 1, Buttons: 1}, nil
+	case id == GpdCommandPress1Of2:
+		return &GpdButton{Button: 1, Buttons: 2, Press: true}, nil
+	case id == GpdCommandRelease1Of2:
+		return &GpdButton{Button: 1, Buttons: 2}, nil
+	case id == GpdCommandPress2Of2:
+		return &GpdButton{Button: 2, Buttons: 2, Press: true}, nil
+	case id == GpdCommandRelease2Of2:
+		return &GpdButton{Button: 2, Buttons: 2}, nil
+	case id == GpdCommandShortPress1Of1:
+		return &GpdButton{Button: 1, Buttons: 1, Press: true, Short: true}, nil
+	case id == GpdCommandShortPress1Of2:
+		return &GpdButton{Button: 1, Buttons: 2, Press: true, Short: true}, nil
+	case id == GpdCommandShortPress2Of2:
+		return &GpdButton{Button: 2, Buttons: 2, Press: true, Short: true}, nil
+	case id == GpdCommandPress8BitVector, id == GpdCommandRelease8BitVector:
+		if len(payload) < 1 {
+			return nil, fmt.Errorf("gpd 8-bit vector command 0x%02x is too short", commandId)
+		}
+		return &GpdButtonVector{id == GpdCommandPress8BitVector, payload[0]}, nil
+	case id == GpdCommandCommissioning:
+		if len(payload) < commissioningLength(payload) {
+			return nil, fmt.Errorf("gpd commissioning command is too short")
+		}
+		commissioning := &GpdCommissioning{}
+		bin.Decode(payload, commissioning)
+		return commissioning, nil
+	case id == GpdCommandDecommissioning:
+		return &GpdDecommissioning{}, nil
+	case id == GpdCommandSuccess:
+		return &GpdSuccess{}, nil
+	case id == GpdCommandChannelRequest:
+		if len(payload) < 1 {
+			return nil, fmt.Errorf("gpd channel request command is too short")
+		}
+		return &GpdChannelRequest{payload[0] & 0x0f, payload[0] >> 4}, nil
+	}
+	return &GpdRawCommand{id, payload}, nil
+}
+
+// commissioningLength is the length of the fields of a GPD commissioning
+// command its options announce, as far as the payload holds them.
+func commissioningLength(payload []uint8) int {
+	length := 2
+	if len(payload) < length || payload[1]&0x80 == 0 {
+		return length
+	}
+	length++
+	if len(payload) < length {
+		return length
+	}
+	extended := payload[2]
+	if extended&0x20 > 0 {
+		length += 16
+		if extended&0x40 > 0 {
+			length += 4
+		}
+	}
+	if extended&0x80 > 0 {
+		length += 4
+	}
+	return length
+}
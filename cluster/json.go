@@ -0,0 +1,390 @@
+package cluster
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dyrkin/zcl-go/internal/yamljson"
+)
+
+// MarshalText encodes a data type by name, so that JSON attributes are
+// tagged with e.g. "Uint8" instead of 0x20.
+func (t ZclDataType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *ZclDataType) UnmarshalText(text []byte) error {
+	name := string(text)
+	for dataType, n := range zclDataTypeNames {
+		if n == name {
+			*t = dataType
+			return nil
+		}
+	}
+	if strings.HasPrefix(name, "ZclDataType(") && strings.HasSuffix(name, ")") {
+		if v, err := strconv.ParseUint(name[12:len(name)-1], 0, 8); err == nil {
+			*t = ZclDataType(v)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown data type %q", name)
+}
+
+// attributeJSON is the JSON form of an Attribute. Values which don't map
+// to a JSON type are encoded as strings: byte arrays, octet strings and keys
+// as 0x prefixed hex, floats which aren't numbers as "NaN", "+Inf", "-Inf".
+// Character strings which aren't valid UTF-8 go to Hex instead of Value.
+type attributeJSON struct {
+	DataType ZclDataType
+	Value    interface{}
+	Hex      string `json:",omitempty"`
+}
+
+type attributeValueJSON struct {
+	DataType ZclDataType
+	Value    json.RawMessage
+	Hex      string
+}
+
+func (a *Attribute) MarshalJSON() ([]byte, error) {
+	j := &attributeJSON{DataType: a.DataType}
+	switch a.DataType {
+	case ZclDataTypeCharStr, ZclDataTypeLongCharStr:
+		if s, ok := a.Value.(string); ok && !utf8.ValidString(s) {
+			j.Hex = toHex([]byte(s))
+			return json.Marshal(j)
+		}
+	}
+	j.Value = jsonValue(a.DataType, a.Value)
+	return json.Marshal(j)
+}
+
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	j := &attributeValueJSON{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return err
+	}
+	a.DataType = j.DataType
+	if j.Hex != "" {
+		b, err := fromHex(j.Hex, -1)
+		if err != nil {
+			return fmt.Errorf("%s attribute: %s", j.DataType, err)
+		}
+		a.Value = string(b)
+		return nil
+	}
+	value, err := valueFromJSON(j.DataType, j.Value)
+	if err != nil {
+		return fmt.Errorf("%s attribute: %s", j.DataType, err)
+	}
+	a.Value = value
+	return nil
+}
+
+func (a *Attribute) MarshalYAML() (interface{}, error) {
+	return yamljson.Marshal(a)
+}
+
+func (a *Attribute) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return yamljson.Unmarshal(unmarshal, a)
+}
+
+// fieldJSON is the JSON form of a Field. Repeated fields hold a list of
+// values of the field type.
+type fieldJSON struct {
+	Name  string
+	Type  ZclDataType
+	Value json.RawMessage
+}
+
+func (f *Field) MarshalJSON() ([]byte, error) {
+	var value interface{}
+	if values, ok := f.Value.([]interface{}); ok {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = jsonValue(f.Type, v)
+		}
+		value = list
+	} else {
+		value = jsonValue(f.Type, f.Value)
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&fieldJSON{f.Name, f.Type, raw})
+}
+
+func (f *Field) UnmarshalJSON(data []byte) error {
+	j := &fieldJSON{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return err
+	}
+	f.Name, f.Type = j.Name, j.Type
+	var list []json.RawMessage
+	if !isList(f.Type) && json.Unmarshal(j.Value, &list) == nil && list != nil {
+		values := make([]interface{}, len(list))
+		for i, raw := range list {
+			v, err := valueFromJSON(f.Type, raw)
+			if err != nil {
+				return fmt.Errorf("field %s: %s", f.Name, err)
+			}
+			values[i] = v
+		}
+		f.Value = values
+		return nil
+	}
+	v, err := valueFromJSON(f.Type, j.Value)
+	if err != nil {
+		return fmt.Errorf("field %s: %s", f.Name, err)
+	}
+	f.Value = v
+	return nil
+}
+
+func isList(dataType ZclDataType) bool {
+	return dataType == ZclDataTypeArray || dataType == ZclDataTypeSet || dataType == ZclDataTypeBag
+}
+
+// jsonValue returns the value encoding/json encodes the way attributeJSON
+// describes.
+func jsonValue(dataType ZclDataType, value interface{}) interface{} {
+	switch v := value.(type) {
+	case [1]byte:
+		return toHex(v[:])
+	case [2]byte:
+		return toHex(v[:])
+	case [3]byte:
+		return toHex(v[:])
+	case [4]byte:
+		return toHex(v[:])
+	case [5]byte:
+		return toHex(v[:])
+	case [6]byte:
+		return toHex(v[:])
+	case [7]byte:
+		return toHex(v[:])
+	case [8]byte:
+		return toHex(v[:])
+	case [16]byte:
+		return toHex(v[:])
+	case float32:
+		return jsonFloat(float64(v), v)
+	case float64:
+		return jsonFloat(v, v)
+	case string:
+		if dataType == ZclDataTypeOctetStr || dataType == ZclDataTypeLongOctetStr {
+			return toHex([]byte(v))
+		}
+	}
+	return value
+}
+
+func jsonFloat(f float64, value interface{}) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return value
+}
+
+func valueFromJSON(dataType ZclDataType, raw json.RawMessage) (interface{}, error) {
+	switch dataType {
+	case ZclDataTypeData8, ZclDataTypeData16, ZclDataTypeData24, ZclDataTypeData32,
+		ZclDataTypeData40, ZclDataTypeData48, ZclDataTypeData56, ZclDataTypeData64:
+		size := int(dataType-ZclDataTypeData8) + 1
+		b, err := hexFromJSON(raw, size)
+		if err != nil {
+			return nil, err
+		}
+		array := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0)))).Elem()
+		reflect.Copy(array, reflect.ValueOf(b))
+		return array.Interface(), nil
+	case ZclDataType_128BitSecKey:
+		b, err := hexFromJSON(raw, 16)
+		if err != nil {
+			return nil, err
+		}
+		var key [16]byte
+		copy(key[:], b)
+		return key, nil
+	case ZclDataTypeBoolean:
+		return unmarshalValue(raw, new(bool))
+	case ZclDataTypeBitmap8, ZclDataTypeBitmap16, ZclDataTypeBitmap24, ZclDataTypeBitmap32,
+		ZclDataTypeBitmap40, ZclDataTypeBitmap48, ZclDataTypeBitmap56, ZclDataTypeBitmap64,
+		ZclDataTypeUint8, ZclDataTypeUint16, ZclDataTypeUint24, ZclDataTypeUint32,
+		ZclDataTypeUint40, ZclDataTypeUint48, ZclDataTypeUint56, ZclDataTypeUint64,
+		ZclDataTypeEnum8, ZclDataTypeEnum16:
+		return unmarshalValue(raw, new(uint64))
+	case ZclDataTypeInt8, ZclDataTypeInt16, ZclDataTypeInt24, ZclDataTypeInt32,
+		ZclDataTypeInt40, ZclDataTypeInt48, ZclDataTypeInt56, ZclDataTypeInt64:
+		return unmarshalValue(raw, new(int64))
+	case ZclDataTypeSemiPrec, ZclDataTypeSinglePrec:
+		f, err := floatFromJSON(raw, 32)
+		return float32(f), err
+	case ZclDataTypeDoublePrec:
+		return floatFromJSON(raw, 64)
+	case ZclDataTypeOctetStr, ZclDataTypeLongOctetStr:
+		b, err := hexFromJSON(raw, -1)
+		return string(b), err
+	case ZclDataTypeCharStr, ZclDataTypeLongCharStr, ZclDataTypeIeeeAddr:
+		return unmarshalValue(raw, new(string))
+	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag:
+		return unmarshalValue(raw, new([]*Attribute))
+	case ZclDataTypeTod:
+		v := &TimeOfDay{}
+		return v, json.Unmarshal(raw, v)
+	case ZclDataTypeDate:
+		v := &Date{}
+		return v, json.Unmarshal(raw, v)
+	case ZclDataTypeUtc, ZclDataTypeBacOid:
+		return unmarshalValue(raw, new(uint32))
+	case ZclDataTypeClusterId, ZclDataTypeAttrId:
+		return unmarshalValue(raw, new(uint16))
+	}
+	return nil, nil
+}
+
+// unmarshalValue decodes raw into the value v points to and returns it.
+func unmarshalValue(raw json.RawMessage, v interface{}) (interface{}, error) {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(v).Elem().Interface(), nil
+}
+
+func floatFromJSON(raw json.RawMessage, bitSize int) (float64, error) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		switch s {
+		case "NaN":
+			return math.NaN(), nil
+		case "+Inf":
+			return math.Inf(1), nil
+		case "-Inf":
+			return math.Inf(-1), nil
+		}
+		return 0, fmt.Errorf("invalid float %q", s)
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return 0, err
+	}
+	if bitSize == 32 {
+		f, _ = strconv.ParseFloat(string(raw), 32)
+	}
+	return f, nil
+}
+
+func hexFromJSON(raw json.RawMessage, size int) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return fromHex(s, size)
+}
+
+func toHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// fromHex decodes a 0x prefixed hex string of size bytes, any size if negative.
+func fromHex(s string, size int) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("hex value %q must start with 0x", s)
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 && len(b) != size {
+		return nil, fmt.Errorf("hex value %q has %d bytes, want %d", s, len(b), size)
+	}
+	return b, nil
+}
+
+var commandTypes = struct {
+	sync.RWMutex
+	once  sync.Once
+	types map[string]reflect.Type
+}{types: map[string]reflect.Type{}}
+
+// CommandTypeName returns the name a command is tagged with in JSON, the
+// package qualified name of its type, e.g. "cluster.OnCommand".
+func CommandTypeName(command interface{}) string {
+	if command == nil {
+		return ""
+	}
+	t := reflect.TypeOf(command)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// RegisterCommandType makes a command type known to NewCommandOfType. The
+// commands of the built-in clusters and the ones registered in any library
+// are known already.
+func RegisterCommandType(prototype interface{}) {
+	if prototype == nil {
+		return
+	}
+	t := reflect.TypeOf(prototype)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	commandTypes.Lock()
+	defer commandTypes.Unlock()
+	commandTypes.types[t.String()] = t
+}
+
+// NewCommandOfType returns a pointer to a new command of the type named by
+// CommandTypeName.
+func NewCommandOfType(name string) (interface{}, bool) {
+	commandTypes.once.Do(registerBuiltinCommandTypes)
+	commandTypes.RLock()
+	defer commandTypes.RUnlock()
+	t, ok := commandTypes.types[name]
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(t).Interface(), true
+}
+
+func registerBuiltinCommandTypes() {
+	library := New()
+	for _, cd := range library.global {
+		RegisterCommandType(cd.Command)
+	}
+	for _, c := range library.clusters {
+		registerClusterCommandTypes(c)
+	}
+	for _, clusters := range library.manufacturers {
+		for _, c := range clusters {
+			registerClusterCommandTypes(c)
+		}
+	}
+}
+
+func registerClusterCommandTypes(c *Cluster) {
+	if c.CommandDescriptors == nil {
+		return
+	}
+	for _, cd := range c.CommandDescriptors.Received {
+		RegisterCommandType(cd.Command)
+	}
+	for _, cd := range c.CommandDescriptors.Generated {
+		RegisterCommandType(cd.Command)
+	}
+}
@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"encoding/json"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+type JSONSuite struct{}
+
+var _ = Suite(&JSONSuite{})
+
+func (s *JSONSuite) TestAttributeRoundTrip(c *C) {
+	attributes := []*Attribute{
+		{ZclDataTypeNoData, nil},
+		{ZclDataTypeData24, [3]byte{0x12, 0x13, 0x14}},
+		{ZclDataTypeBoolean, true},
+		{ZclDataTypeBitmap16, uint64(0x8001)},
+		{ZclDataTypeUint64, uint64(math.MaxUint64)},
+		{ZclDataTypeInt24, int64(-9)},
+		{ZclDataTypeEnum8, uint64(3)},
+		{ZclDataTypeSemiPrec, float32(1.5)},
+		{ZclDataTypeSinglePrec, float32(22.1)},
+		{ZclDataTypeDoublePrec, math.Inf(-1)},
+		{ZclDataTypeOctetStr, "\x00\xff"},
+		{ZclDataTypeCharStr, "lumi.sensor"},
+		{ZclDataTypeCharStr, "\x01\xff\x42"},
+		{ZclDataTypeLongCharStr, ""},
+		{ZclDataTypeArray, []*Attribute{{ZclDataTypeInt24, int64(-8)}, {ZclDataTypeUint8, uint64(1)}}},
+		{ZclDataTypeTod, &TimeOfDay{12, 30, 15, 0}},
+		{ZclDataTypeDate, &Date{119, 3, 27, 3}},
+		{ZclDataTypeUtc, uint32(1553700000)},
+		{ZclDataTypeClusterId, uint16(0x0006)},
+		{ZclDataTypeIeeeAddr, "0x00158d0001a2b3c4"},
+		{ZclDataType_128BitSecKey, [16]byte{0x5a, 0x69, 0x67, 0x42, 0x65, 0x65}},
+		{ZclDataType(0x05), nil},
+	}
+	for _, attribute := range attributes {
+		data, err := json.Marshal(attribute)
+		c.Assert(err, IsNil)
+		decoded := &Attribute{}
+		c.Assert(json.Unmarshal(data, decoded), IsNil, Commentf("%s", data))
+		c.Assert(decoded, DeepEquals, attribute, Commentf("%s", data))
+	}
+}
+
+func (s *JSONSuite) TestAttributeEncoding(c *C) {
+	data, _ := json.Marshal(&Attribute{ZclDataTypeData16, [2]byte{0x01, 0x02}})
+	c.Assert(string(data), Equals, `{"DataType":"Data16","Value":"0x0102"}`)
+	data, _ = json.Marshal(&Attribute{ZclDataTypeCharStr, "\xff"})
+	c.Assert(string(data), Equals, `{"DataType":"CharStr","Value":null,"Hex":"0xff"}`)
+	data, _ = json.Marshal(&Attribute{ZclDataTypeSinglePrec, float32(math.NaN())})
+	c.Assert(string(data), Equals, `{"DataType":"SinglePrec","Value":"NaN"}`)
+
+	c.Assert(json.Unmarshal([]byte(`{"DataType":"Data16","Value":"0x01"}`), &Attribute{}), ErrorMatches, `Data16 attribute: hex value "0x01" has 1 bytes, want 2`)
+	c.Assert(json.Unmarshal([]byte(`{"DataType":"Uint9","Value":1}`), &Attribute{}), ErrorMatches, `unknown data type "Uint9"`)
+}
+
+func (s *JSONSuite) TestDynamicCommandRoundTrip(c *C) {
+	command := &DynamicCommand{Fields: []*Field{
+		{"Status", ZclDataTypeEnum8, uint64(1)},
+		{"Key", ZclDataTypeData16, [2]byte{0xab, 0xcd}},
+		{"Values", ZclDataTypeInt16, []interface{}{int64(-1), int64(2)}},
+	}}
+	data, err := json.Marshal(command)
+	c.Assert(err, IsNil)
+	decoded := &DynamicCommand{}
+	c.Assert(json.Unmarshal(data, decoded), IsNil)
+	c.Assert(decoded, DeepEquals, command)
+}
+
+func (s *JSONSuite) TestCommandTypes(c *C) {
+	c.Assert(CommandTypeName(&OnCommand{}), Equals, "cluster.OnCommand")
+	command, ok := NewCommandOfType("cluster.ReadAttributesResponse")
+	c.Assert(ok, Equals, true)
+	c.Assert(command, DeepEquals, &ReadAttributesResponse{})
+	_, ok = NewCommandOfType("cluster.Unknown")
+	c.Assert(ok, Equals, false)
+}
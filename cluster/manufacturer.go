@@ -0,0 +1,64 @@
+package cluster
+
+const (
+	ManufacturerPhilips uint16 = 0x100b
+	ManufacturerXiaomi  uint16 = 0x115f
+	ManufacturerIkea    uint16 = 0x117c
+)
+
+type TuyaDataPoint struct {
+	Dp       uint8
+	DataType uint8
+	Data     []uint8 `size:"2" endianness:"be"`
+}
+
+type TuyaDataCommand struct {
+	Status        uint8
+	TransactionID uint8
+	DataPoints    []*TuyaDataPoint
+}
+
+type TuyaDataQueryCommand struct{}
+
+type HueNotificationCommand struct {
+	Button   uint8
+	Unknown1 [3]uint8
+	Type     uint8
+	Unknown2 uint8
+	Time     uint8
+	Unknown3 uint8
+}
+
+func manufacturerClusters() map[uint16]map[ClusterId]*Cluster {
+	return map[uint16]map[ClusterId]*Cluster{
+		ManufacturerXiaomi: {
+			Basic: {
+				Name: "Basic",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{
+					0x00f7: {Name: "XiaomiInfoTLV", Type: ZclDataTypeOctetStr, Access: Read | Reportable},
+					0xff01: {Name: "XiaomiInfo", Type: ZclDataTypeCharStr, Access: Read | Reportable},
+					0xff02: {Name: "XiaomiInfoStruct", Type: ZclDataTypeStruct, Access: Read | Reportable},
+				},
+			},
+		},
+		ManufacturerPhilips: {
+			PhilipsHueSwitch: {
+				Name:                 "PhilipsHueSwitch",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{},
+				CommandDescriptors: &CommandDescriptors{
+					Generated: map[uint8]*CommandDescriptor{
+						0x00: {Name: "HueNotification", Command: &HueNotificationCommand{}},
+					},
+				},
+			},
+		},
+		ManufacturerIkea: {
+			// IKEA doesn't document the attributes and commands of this cluster,
+			// it's defined for frames to be resolved to the vendor cluster
+			IkeaManufacturerSpecific: {
+				Name:                 "IkeaManufacturerSpecific",
+				AttributeDescriptors: map[uint16]*AttributeDescriptor{},
+			},
+		},
+	}
+}
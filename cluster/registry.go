@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// RegisterCluster adds a cluster definition to the library. A non-zero
+// manufacturerCode registers a vendor definition which is resolved before the
+// standard one for manufacturer specific frames. Registering a cluster which
+// already exists is an error, use Override to replace it.
+func (cl *ClusterLibrary) RegisterCluster(clusterId ClusterId, manufacturerCode uint16, c *Cluster) error {
+	if err := validateCluster(c); err != nil {
+		return fmt.Errorf("invalid cluster 0x%04x: %s", uint16(clusterId), err)
+	}
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	clusters := cl.clustersFor(manufacturerCode, true)
+	if _, ok := clusters[clusterId]; ok {
+		return fmt.Errorf("cluster 0x%04x is already registered%s", uint16(clusterId), manufacturerSuffix(manufacturerCode))
+	}
+	c = c.clone()
+	clusters[clusterId] = c
+	registerClusterCommandTypes(c)
+	return nil
+}
+
+// Override replaces a cluster definition, registering it if it doesn't exist.
+func (cl *ClusterLibrary) Override(clusterId ClusterId, manufacturerCode uint16, c *Cluster) error {
+	if err := validateCluster(c); err != nil {
+		return fmt.Errorf("invalid cluster 0x%04x: %s", uint16(clusterId), err)
+	}
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	c = c.clone()
+	cl.clustersFor(manufacturerCode, true)[clusterId] = c
+	registerClusterCommandTypes(c)
+	return nil
+}
+
+// RegisterAttribute adds an attribute to an existing cluster. Vendor attributes
+// of standard clusters are registered with a non-zero manufacturerCode.
+func (cl *ClusterLibrary) RegisterAttribute(clusterId ClusterId, manufacturerCode uint16, attributeId uint16, ad *AttributeDescriptor) error {
+	return cl.registerAttribute(clusterId, manufacturerCode, attributeId, ad, false)
+}
+
+// OverrideAttribute replaces an attribute of an existing cluster, registering
+// it if it doesn't exist.
+func (cl *ClusterLibrary) OverrideAttribute(clusterId ClusterId, manufacturerCode uint16, attributeId uint16, ad *AttributeDescriptor) error {
+	return cl.registerAttribute(clusterId, manufacturerCode, attributeId, ad, true)
+}
+
+func (cl *ClusterLibrary) registerAttribute(clusterId ClusterId, manufacturerCode uint16, attributeId uint16, ad *AttributeDescriptor, replace bool) error {
+	if err := validateAttribute(ad); err != nil {
+		return fmt.Errorf("invalid attribute 0x%04x: %s", attributeId, err)
+	}
+	return cl.update(clusterId, manufacturerCode, func(c *Cluster) error {
+		if _, ok := c.AttributeDescriptors[attributeId]; ok && !replace {
+			return fmt.Errorf("attribute 0x%04x is already registered in cluster 0x%04x%s", attributeId, uint16(clusterId), manufacturerSuffix(manufacturerCode))
+		}
+		c.AttributeDescriptors[attributeId] = ad
+		return nil
+	})
+}
+
+// RegisterCommand adds a cluster specific command to an existing cluster.
+// Commands sent from client to server are registered with
+// frame.DirectionClientServer, commands generated by the server with
+// frame.DirectionServerClient.
+func (cl *ClusterLibrary) RegisterCommand(clusterId ClusterId, manufacturerCode uint16, direction frame.Direction, commandId uint8, cd *CommandDescriptor) error {
+	return cl.registerCommand(clusterId, manufacturerCode, direction, commandId, cd, false)
+}
+
+// OverrideCommand replaces a cluster specific command of an existing cluster,
+// registering it if it doesn't exist.
+func (cl *ClusterLibrary) OverrideCommand(clusterId ClusterId, manufacturerCode uint16, direction frame.Direction, commandId uint8, cd *CommandDescriptor) error {
+	return cl.registerCommand(clusterId, manufacturerCode, direction, commandId, cd, true)
+}
+
+func (cl *ClusterLibrary) registerCommand(clusterId ClusterId, manufacturerCode uint16, direction frame.Direction, commandId uint8, cd *CommandDescriptor, replace bool) error {
+	if err := validateCommand(cd); err != nil {
+		return fmt.Errorf("invalid command 0x%02x: %s", commandId, err)
+	}
+	return cl.update(clusterId, manufacturerCode, func(c *Cluster) error {
+		if c.CommandDescriptors == nil {
+			c.CommandDescriptors = &CommandDescriptors{}
+		}
+		commandDescriptors := &c.CommandDescriptors.Received
+		if direction == frame.DirectionServerClient {
+			commandDescriptors = &c.CommandDescriptors.Generated
+		}
+		if _, ok := (*commandDescriptors)[commandId]; ok && !replace {
+			return fmt.Errorf("command 0x%02x is already registered in cluster 0x%04x%s", commandId, uint16(clusterId), manufacturerSuffix(manufacturerCode))
+		}
+		if *commandDescriptors == nil {
+			*commandDescriptors = map[uint8]*CommandDescriptor{}
+		}
+		(*commandDescriptors)[commandId] = cd
+		RegisterCommandType(cd.Command)
+		return nil
+	})
+}
+
+func (cl *ClusterLibrary) clustersFor(manufacturerCode uint16, create bool) map[ClusterId]*Cluster {
+	if manufacturerCode == 0 {
+		return cl.clusters
+	}
+	clusters, ok := cl.manufacturers[manufacturerCode]
+	if !ok && create {
+		clusters = map[ClusterId]*Cluster{}
+		cl.manufacturers[manufacturerCode] = clusters
+	}
+	return clusters
+}
+
+// update adds definitions to a cluster. Registered clusters are never
+// modified, since readers use them without holding the lock: modify changes a
+// copy which then replaces the cluster. A vendor extension of a standard
+// cluster is created on demand.
+func (cl *ClusterLibrary) update(clusterId ClusterId, manufacturerCode uint16, modify func(c *Cluster) error) error {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	c, ok := cl.clustersFor(manufacturerCode, false)[clusterId]
+	if ok {
+		c = c.clone()
+	} else {
+		standard, ok := cl.clusters[clusterId]
+		if !ok || manufacturerCode == 0 {
+			return fmt.Errorf("cluster 0x%04x is not registered%s", uint16(clusterId), manufacturerSuffix(manufacturerCode))
+		}
+		c = &Cluster{Name: standard.Name, AttributeDescriptors: map[uint16]*AttributeDescriptor{}}
+	}
+	if err := modify(c); err != nil {
+		return err
+	}
+	cl.clustersFor(manufacturerCode, true)[clusterId] = c
+	return nil
+}
+
+func validateCluster(c *Cluster) error {
+	if c == nil {
+		return fmt.Errorf("cluster is nil")
+	}
+	if c.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	for id, ad := range c.AttributeDescriptors {
+		if err := validateAttribute(ad); err != nil {
+			return fmt.Errorf("attribute 0x%04x: %s", id, err)
+		}
+	}
+	if c.CommandDescriptors != nil {
+		for id, cd := range c.CommandDescriptors.Received {
+			if err := validateCommand(cd); err != nil {
+				return fmt.Errorf("received command 0x%02x: %s", id, err)
+			}
+		}
+		for id, cd := range c.CommandDescriptors.Generated {
+			if err := validateCommand(cd); err != nil {
+				return fmt.Errorf("generated command 0x%02x: %s", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateAttribute(ad *AttributeDescriptor) error {
+	if ad == nil {
+		return fmt.Errorf("attribute descriptor is nil")
+	}
+	if ad.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	if !ad.Type.Known() || ad.Type == ZclDataTypeUnknown {
+		return fmt.Errorf("unknown data type %s", ad.Type)
+	}
+	return nil
+}
+
+func validateCommand(cd *CommandDescriptor) error {
+	if cd == nil {
+		return fmt.Errorf("command descriptor is nil")
+	}
+	if cd.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	t := reflect.TypeOf(cd.Command)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("command must be a pointer to a struct, got %v", t)
+	}
+	if dynamic, ok := cd.Command.(*DynamicCommand); ok && dynamic.Schema != nil {
+		return dynamic.Schema.validate()
+	}
+	return nil
+}
+
+func manufacturerSuffix(manufacturerCode uint16) string {
+	if manufacturerCode == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" for manufacturer 0x%04x", manufacturerCode)
+}
@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+type RegistrySuite struct{}
+
+var _ = Suite(&RegistrySuite{})
+
+type vendorCommand struct {
+	Value uint8
+}
+
+func (s *RegistrySuite) TestRegisterCluster(c *C) {
+	library := New()
+	vendor := &Cluster{
+		Name: "Vendor",
+		AttributeDescriptors: map[uint16]*AttributeDescriptor{
+			0x0000: {Name: "Mode", Type: ZclDataTypeEnum8, Access: Read | Write},
+		},
+	}
+	c.Assert(library.RegisterCluster(0xfc01, 0x1234, vendor), IsNil)
+	c.Assert(library.RegisterCluster(0xfc01, 0x1234, vendor), ErrorMatches, "cluster 0xfc01 is already registered for manufacturer 0x1234")
+
+	ad, ok := library.AttributeDescriptor(0xfc01, 0x1234, 0x0000)
+	c.Assert(ok, Equals, true)
+	c.Assert(ad.Name, Equals, "Mode")
+	_, ok = library.AttributeDescriptor(0xfc01, 0, 0x0000)
+	c.Assert(ok, Equals, false)
+
+	c.Assert(library.RegisterCluster(OnOff, 0, vendor), ErrorMatches, "cluster 0x0006 is already registered")
+	c.Assert(library.Override(OnOff, 0, vendor), IsNil)
+	c.Assert(library.Clusters()[OnOff].Name, Equals, "Vendor")
+}
+
+func (s *RegistrySuite) TestRegisterAttribute(c *C) {
+	library := New()
+	c.Assert(library.RegisterAttribute(OnOff, 0, 0x0000, &AttributeDescriptor{Name: "Duplicate", Type: ZclDataTypeBoolean, Access: Read}),
+		ErrorMatches, "attribute 0x0000 is already registered in cluster 0x0006")
+	c.Assert(library.RegisterAttribute(OnOff, 0, 0x5000, &AttributeDescriptor{Name: "Broken", Type: ZclDataType(0x05), Access: Read}),
+		ErrorMatches, "invalid attribute 0x5000: unknown data type ZclDataType\\(0x05\\)")
+	c.Assert(library.RegisterAttribute(0xfc02, 0, 0x0000, &AttributeDescriptor{Name: "Missing", Type: ZclDataTypeUint8, Access: Read}),
+		ErrorMatches, "cluster 0xfc02 is not registered")
+
+	c.Assert(library.RegisterAttribute(OnOff, 0x1234, 0x0000, &AttributeDescriptor{Name: "VendorOnOff", Type: ZclDataTypeUint8, Access: Read}), IsNil)
+	ad, _ := library.AttributeDescriptor(OnOff, 0x1234, 0x0000)
+	c.Assert(ad.Name, Equals, "VendorOnOff")
+	ad, _ = library.AttributeDescriptor(OnOff, 0x1234, 0x4001)
+	c.Assert(ad.Name, Equals, "OnTime")
+	ad, _ = library.AttributeDescriptor(OnOff, 0, 0x0000)
+	c.Assert(ad.Name, Equals, "OnOff")
+
+	c.Assert(library.OverrideAttribute(OnOff, 0, 0x0000, &AttributeDescriptor{Name: "State", Type: ZclDataTypeBoolean, Access: Read}), IsNil)
+	ad, _ = library.AttributeDescriptor(OnOff, 0, 0x0000)
+	c.Assert(ad.Name, Equals, "State")
+}
+
+func (s *RegistrySuite) TestRegisterCommand(c *C) {
+	library := New()
+	c.Assert(library.RegisterCommand(OnOff, 0, frame.DirectionClientServer, 0x50, &CommandDescriptor{Name: "Vendor", Command: vendorCommand{}}),
+		ErrorMatches, "invalid command 0x50: command must be a pointer to a struct, got cluster.vendorCommand")
+	c.Assert(library.RegisterCommand(OnOff, 0, frame.DirectionClientServer, 0x00, &CommandDescriptor{Name: "Vendor", Command: &vendorCommand{}}),
+		ErrorMatches, "command 0x00 is already registered in cluster 0x0006")
+	c.Assert(library.RegisterCommand(OnOff, 0, frame.DirectionServerClient, 0x00, &CommandDescriptor{Name: "Vendor", Command: &vendorCommand{}}), IsNil)
+
+	cd, ok := library.CommandDescriptor(OnOff, 0, frame.DirectionServerClient, 0x00)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Name, Equals, "Vendor")
+	cd, _ = library.CommandDescriptor(OnOff, 0, frame.DirectionClientServer, 0x00)
+	c.Assert(cd.Name, Equals, "Off")
+
+	c.Assert(library.OverrideCommand(OnOff, 0, frame.DirectionClientServer, 0x00, &CommandDescriptor{Name: "Vendor", Command: &vendorCommand{}}), IsNil)
+	cd, _ = library.CommandDescriptor(OnOff, 0, frame.DirectionClientServer, 0x00)
+	c.Assert(cd.Name, Equals, "Vendor")
+}
+
+func (s *RegistrySuite) TestRegistrationKeepsDefinitionsInUse(c *C) {
+	library := New()
+	clusters := library.Clusters()
+	onOff := library.Candidates(OnOff, 0)[0]
+
+	c.Assert(library.RegisterAttribute(OnOff, 0, 0x5000, &AttributeDescriptor{Name: "Vendor", Type: ZclDataTypeUint8, Access: Read}), IsNil)
+	c.Assert(library.RegisterCluster(0xfc01, 0, &Cluster{Name: "Vendor"}), IsNil)
+
+	_, ok := onOff.AttributeDescriptors[0x5000]
+	c.Assert(ok, Equals, false)
+	_, ok = clusters[0xfc01]
+	c.Assert(ok, Equals, false)
+	_, ok = library.Clusters()[OnOff].AttributeDescriptors[0x5000]
+	c.Assert(ok, Equals, true)
+}
+
+func (s *RegistrySuite) TestRegistrationCopiesCluster(c *C) {
+	library := New()
+	vendor := &Cluster{
+		Name: "Vendor",
+		AttributeDescriptors: map[uint16]*AttributeDescriptor{
+			0x0000: {Name: "Mode", Type: ZclDataTypeEnum8, Access: Read | Write},
+		},
+		CommandDescriptors: &CommandDescriptors{
+			Received: map[uint8]*CommandDescriptor{0x00: {Name: "Set", Command: &vendorCommand{}}},
+		},
+	}
+	c.Assert(library.RegisterCluster(0xfc01, 0x1234, vendor), IsNil)
+	c.Assert(library.Override(OnOff, 0, vendor), IsNil)
+
+	vendor.Name = "Changed"
+	vendor.AttributeDescriptors[0x0001] = &AttributeDescriptor{Name: "Level", Type: ZclDataTypeUint8, Access: Read}
+	vendor.CommandDescriptors.Received[0x01] = &CommandDescriptor{Name: "Reset", Command: &vendorCommand{}}
+
+	for _, registered := range []*Cluster{library.Candidates(0xfc01, 0x1234)[0], library.Clusters()[OnOff]} {
+		c.Assert(registered.Name, Equals, "Vendor")
+		c.Assert(registered.AttributeDescriptors, HasLen, 1)
+		c.Assert(registered.CommandDescriptors.Received, HasLen, 1)
+	}
+}
+
+func (s *RegistrySuite) TestConcurrentRegistration(c *C) {
+	library := New()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for id := uint16(0x5000); id < 0x5100; id++ {
+			library.RegisterAttribute(OnOff, 0, id, &AttributeDescriptor{Name: "Vendor", Type: ZclDataTypeUint8, Access: Read})
+			library.RegisterCommand(OnOff, 0x1234, frame.DirectionClientServer, uint8(id), &CommandDescriptor{Name: "Vendor", Command: &vendorCommand{}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		for _, cl := range library.Candidates(OnOff, 0x1234) {
+			for range cl.AttributeDescriptors {
+			}
+			for range cl.commandDescriptors(frame.DirectionClientServer) {
+			}
+		}
+		for range library.Clusters() {
+		}
+		for range library.Manufacturers()[0x1234] {
+		}
+	}
+	<-done
+}
@@ -0,0 +1,26 @@
+package cluster
+
+// StatusFlags is the decoded form of the StatusFlags bitmap shared by the
+// Analog, Binary and Multistate Input/Output/Value clusters.
+type StatusFlags struct {
+	InAlarm      bool
+	Fault        bool
+	Overridden   bool
+	OutOfService bool
+}
+
+func NewStatusFlags(value uint64) *StatusFlags {
+	return &StatusFlags{
+		InAlarm:      value&0x01 > 0,
+		Fault:        value&0x02 > 0,
+		Overridden:   value&0x04 > 0,
+		OutOfService: value&0x08 > 0,
+	}
+}
+
+func (f *StatusFlags) Value() uint64 {
+	return uint64(flag(f.InAlarm)) |
+		uint64(flag(f.Fault))<<1 |
+		uint64(flag(f.Overridden))<<2 |
+		uint64(flag(f.OutOfService))<<3
+}
@@ -0,0 +1,25 @@
+package cluster
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type StatusFlagsSuite struct{}
+
+var _ = Suite(&StatusFlagsSuite{})
+
+func (s *StatusFlagsSuite) TestStatusFlags(c *C) {
+	flags := NewStatusFlags(0x0a)
+	c.Assert(flags, DeepEquals, &StatusFlags{false, true, false, true})
+	c.Assert(flags.Value(), Equals, uint64(0x0a))
+}
+
+func (s *StatusFlagsSuite) TestReportable(c *C) {
+	library := New()
+	for _, clusterId := range []ClusterId{AnalogInput, BinaryInput, MultistateInput} {
+		attributes := library.Clusters()[clusterId].AttributeDescriptors
+		c.Assert(attributes[0x0055].Access&Reportable, Equals, Reportable, Commentf("PresentValue of 0x%04x", uint16(clusterId)))
+		c.Assert(attributes[0x006F].Access&Reportable, Equals, Reportable, Commentf("StatusFlags of 0x%04x", uint16(clusterId)))
+		c.Assert(attributes[0x001C].Access&Reportable, Equals, Access(0), Commentf("Description of 0x%04x", uint16(clusterId)))
+	}
+}
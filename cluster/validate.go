@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValidateWrite checks an attribute value a client wants to write against the
+// descriptor and returns the status a server would respond with, checking the
+// data type, the access and the value in this order as ZCL 2.5.3.3 requires.
+func (ad *AttributeDescriptor) ValidateWrite(attribute *Attribute) ZclStatus {
+	if attribute == nil || attribute.DataType != ad.Type {
+		return ZclStatusInvalidDataType
+	}
+	if ad.Access&Write == 0 {
+		return ZclStatusReadOnly
+	}
+	if !valueFits(ad.Type, attribute.Value) || !ad.inRange(attribute.Value) {
+		return ZclStatusInvalidValue
+	}
+	return ZclStatusSuccess
+}
+
+// ValidateWrite checks a write attribute record against the library, returning
+// ZclStatusUnsupportedAttribute for attributes the cluster doesn't define.
+func (cl *ClusterLibrary) ValidateWrite(clusterId ClusterId, manufacturerCode uint16, record *WriteAttributeRecord) ZclStatus {
+	ad, ok := cl.AttributeDescriptor(clusterId, manufacturerCode, record.AttributeID)
+	if !ok {
+		return ZclStatusUnsupportedAttribute
+	}
+	return ad.ValidateWrite(record.Attribute)
+}
+
+// ValidateWriteAttributes checks every record and returns the failed ones, as
+// they would be reported in a WriteAttributesResponse. An empty result means
+// all records are valid.
+func (cl *ClusterLibrary) ValidateWriteAttributes(clusterId ClusterId, manufacturerCode uint16, records []*WriteAttributeRecord) []*WriteAttributeStatus {
+	var statuses []*WriteAttributeStatus
+	for _, record := range records {
+		if status := cl.ValidateWrite(clusterId, manufacturerCode, record); status != ZclStatusSuccess {
+			statuses = append(statuses, &WriteAttributeStatus{Status: status, AttributeName: record.AttributeName, AttributeID: record.AttributeID})
+		}
+	}
+	return statuses
+}
+
+func (ad *AttributeDescriptor) inRange(value interface{}) bool {
+	return (ad.Min == nil || compare(value, ad.Min) >= 0) && (ad.Max == nil || compare(value, ad.Max) <= 0)
+}
+
+// compare returns -1, 0 or 1 comparing numeric values of the same kind.
+// Values which can't be compared are considered equal.
+func compare(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case uint64:
+		if b, ok := b.(uint64); ok {
+			return sign(a < b, a > b)
+		}
+	case int64:
+		if b, ok := b.(int64); ok {
+			return sign(a < b, a > b)
+		}
+	case float32:
+		if b, ok := b.(float32); ok {
+			return sign(a < b, a > b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return sign(a < b, a > b)
+		}
+	}
+	return 0
+}
+
+func sign(less bool, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// valueFits reports whether value has the Go type Attribute uses for dataType
+// and fits its size.
+func valueFits(dataType ZclDataType, value interface{}) bool {
+	switch dataType {
+	case ZclDataTypeNoData, ZclDataTypeUnknown:
+		return value == nil
+	case ZclDataTypeData8:
+		_, ok := value.([1]byte)
+		return ok
+	case ZclDataTypeData16:
+		_, ok := value.([2]byte)
+		return ok
+	case ZclDataTypeData24:
+		_, ok := value.([3]byte)
+		return ok
+	case ZclDataTypeData32:
+		_, ok := value.([4]byte)
+		return ok
+	case ZclDataTypeData40:
+		_, ok := value.([5]byte)
+		return ok
+	case ZclDataTypeData48:
+		_, ok := value.([6]byte)
+		return ok
+	case ZclDataTypeData56:
+		_, ok := value.([7]byte)
+		return ok
+	case ZclDataTypeData64:
+		_, ok := value.([8]byte)
+		return ok
+	case ZclDataTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case ZclDataTypeBitmap8, ZclDataTypeUint8, ZclDataTypeEnum8:
+		return uintFits(value, 1)
+	case ZclDataTypeBitmap16, ZclDataTypeUint16, ZclDataTypeEnum16:
+		return uintFits(value, 2)
+	case ZclDataTypeBitmap24, ZclDataTypeUint24:
+		return uintFits(value, 3)
+	case ZclDataTypeBitmap32, ZclDataTypeUint32:
+		return uintFits(value, 4)
+	case ZclDataTypeBitmap40, ZclDataTypeUint40:
+		return uintFits(value, 5)
+	case ZclDataTypeBitmap48, ZclDataTypeUint48:
+		return uintFits(value, 6)
+	case ZclDataTypeBitmap56, ZclDataTypeUint56:
+		return uintFits(value, 7)
+	case ZclDataTypeBitmap64, ZclDataTypeUint64:
+		return uintFits(value, 8)
+	case ZclDataTypeInt8:
+		return intFits(value, 1)
+	case ZclDataTypeInt16:
+		return intFits(value, 2)
+	case ZclDataTypeInt24:
+		return intFits(value, 3)
+	case ZclDataTypeInt32:
+		return intFits(value, 4)
+	case ZclDataTypeInt40:
+		return intFits(value, 5)
+	case ZclDataTypeInt48:
+		return intFits(value, 6)
+	case ZclDataTypeInt56:
+		return intFits(value, 7)
+	case ZclDataTypeInt64:
+		return intFits(value, 8)
+	case ZclDataTypeSemiPrec, ZclDataTypeSinglePrec:
+		_, ok := value.(float32)
+		return ok
+	case ZclDataTypeDoublePrec:
+		_, ok := value.(float64)
+		return ok
+	case ZclDataTypeOctetStr, ZclDataTypeCharStr:
+		s, ok := value.(string)
+		return ok && len(s) < math.MaxUint8
+	case ZclDataTypeLongOctetStr, ZclDataTypeLongCharStr:
+		s, ok := value.(string)
+		return ok && len(s) < math.MaxUint16
+	case ZclDataTypeArray, ZclDataTypeSet, ZclDataTypeBag:
+		_, ok := value.([]*Attribute)
+		return ok
+	case ZclDataTypeTod:
+		_, ok := value.(*TimeOfDay)
+		return ok
+	case ZclDataTypeDate:
+		_, ok := value.(*Date)
+		return ok
+	case ZclDataTypeUtc, ZclDataTypeBacOid:
+		_, ok := value.(uint32)
+		return ok
+	case ZclDataTypeClusterId, ZclDataTypeAttrId:
+		_, ok := value.(uint16)
+		return ok
+	case ZclDataTypeIeeeAddr:
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(s, "0x") {
+			return false
+		}
+		_, err := strconv.ParseUint(s[2:], 16, 64)
+		return err == nil
+	case ZclDataType_128BitSecKey:
+		_, ok := value.([16]byte)
+		return ok
+	}
+	return false
+}
+
+func uintFits(value interface{}, size uint) bool {
+	v, ok := value.(uint64)
+	return ok && (size == 8 || v < 1<<(size*8))
+}
+
+func intFits(value interface{}, size uint) bool {
+	v, ok := value.(int64)
+	if !ok || size == 8 {
+		return ok
+	}
+	limit := int64(1) << (size*8 - 1)
+	return v >= -limit && v < limit
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ValidateSuite struct{}
+
+var _ = Suite(&ValidateSuite{})
+
+func (s *ValidateSuite) TestValidateWrite(c *C) {
+	library := New()
+	validate := func(clusterId ClusterId, attributeId uint16, dataType ZclDataType, value interface{}) ZclStatus {
+		return library.ValidateWrite(clusterId, 0, &WriteAttributeRecord{AttributeID: attributeId, Attribute: &Attribute{dataType, value}})
+	}
+	c.Assert(validate(Basic, 0x0010, ZclDataTypeCharStr, "Kitchen"), Equals, ZclStatusSuccess)
+	c.Assert(validate(Basic, 0x0005, ZclDataTypeCharStr, "Model"), Equals, ZclStatusReadOnly)
+	c.Assert(validate(Basic, 0x0005, ZclDataTypeUint8, uint64(1)), Equals, ZclStatusInvalidDataType)
+	c.Assert(validate(Basic, 0x0010, ZclDataTypeOctetStr, "Kitchen"), Equals, ZclStatusInvalidDataType)
+	c.Assert(validate(Basic, 0x0010, ZclDataTypeCharStr, uint64(1)), Equals, ZclStatusInvalidValue)
+	c.Assert(validate(Basic, 0x0013, ZclDataTypeBitmap8, uint64(0x03)), Equals, ZclStatusSuccess)
+	c.Assert(validate(Basic, 0x0013, ZclDataTypeBitmap8, uint64(0x04)), Equals, ZclStatusInvalidValue)
+	c.Assert(validate(OnOff, 0x4001, ZclDataTypeUint16, uint64(0x10000)), Equals, ZclStatusInvalidValue)
+	c.Assert(validate(Identify, 0x0000, ZclDataTypeInt16, int64(-32769)), Equals, ZclStatusInvalidValue)
+	c.Assert(validate(Basic, 0x7fff, ZclDataTypeUint8, uint64(1)), Equals, ZclStatusUnsupportedAttribute)
+}
+
+func (s *ValidateSuite) TestValidateRange(c *C) {
+	ad := &AttributeDescriptor{Name: "Temperature", Type: ZclDataTypeInt16, Access: Read | Write, Min: int64(-200), Max: int64(200)}
+	c.Assert(ad.ValidateWrite(&Attribute{ZclDataTypeInt16, int64(-200)}), Equals, ZclStatusSuccess)
+	c.Assert(ad.ValidateWrite(&Attribute{ZclDataTypeInt16, int64(-201)}), Equals, ZclStatusInvalidValue)
+	c.Assert(ad.ValidateWrite(&Attribute{ZclDataTypeInt16, int64(201)}), Equals, ZclStatusInvalidValue)
+	c.Assert(ad.ValidateWrite(nil), Equals, ZclStatusInvalidDataType)
+}
+
+func (s *ValidateSuite) TestValidateWriteAttributes(c *C) {
+	statuses := New().ValidateWriteAttributes(OnOff, 0, []*WriteAttributeRecord{
+		{AttributeID: 0x4001, Attribute: &Attribute{ZclDataTypeUint16, uint64(10)}},
+		{AttributeID: 0x0000, Attribute: &Attribute{ZclDataTypeBoolean, true}},
+	})
+	c.Assert(statuses, DeepEquals, []*WriteAttributeStatus{{Status: ZclStatusReadOnly, AttributeID: 0x0000}})
+}
@@ -0,0 +1,43 @@
+// Command zapgen generates typed ZCL command structs from ZAP / ZCL XML files.
+//
+//	zapgen -package commands -out commands_gen.go general.xml types.xml
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dyrkin/zcl-go/zap"
+)
+
+func main() {
+	packageName := flag.String("package", "commands", "package of the generated file")
+	out := flag.String("out", "", "output file, stdout if empty")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zapgen [-package name] [-out file] file.xml...")
+		os.Exit(2)
+	}
+	if err := run(*packageName, *out, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "zapgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(packageName string, out string, paths []string) error {
+	d, err := zap.ParseFiles(paths...)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	if err := d.Generate(buf, packageName); err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
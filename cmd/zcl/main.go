@@ -0,0 +1,289 @@
+// Command zcl decodes, encodes and validates ZCL frames and lists the
+// clusters it knows.
+//
+//	zcl decode -cluster 0x0006 18050b0100
+//	zcl decode -cluster 0x0000 -format json 1c5f11010a...
+//	zcl encode frame.json
+//	zcl clusters
+//	zcl attributes 0x0006
+//	zcl validate -cluster 0x0006 18050b0100
+//
+// Definitions loaded from ZCL XML files with -zap are merged into the
+// built-in ones: the attributes and commands they declare replace the
+// built-in ones, the others are kept.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/dissect"
+	"github.com/dyrkin/zcl-go/zap"
+	"github.com/dyrkin/znp-go"
+)
+
+const usage = `usage: zcl <command> [flags] [args]
+
+commands:
+  decode -cluster id [-format text|json] [-hexdump] [-color] hex
+  encode [file]          encode a JSON frame or message, from stdin if no file
+  clusters               list the known clusters
+  attributes cluster     list the attributes of a cluster, by id or name
+  validate -cluster id hex
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := run(os.Args[1], os.Args[2:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "zcl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	clusterFlag := flags.String("cluster", "", "cluster id or name")
+	manufacturerFlag := flags.String("manufacturer", "0", "manufacturer code")
+	zapFlag := flags.String("zap", "", "comma separated ZCL XML files to load")
+	format := flags.String("format", "text", "output format of decode: text or json")
+	hexDump := flags.Bool("hexdump", false, "annotate decode output with raw bytes")
+	color := flags.Bool("color", false, "colorize decode output")
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		_, err = fmt.Fprint(stdout, usage)
+		return err
+	} else if err != nil {
+		return err
+	}
+	library := cluster.New()
+	if *zapFlag != "" {
+		if err := zap.LoadFiles(library, nil, strings.Split(*zapFlag, ",")...); err != nil {
+			return err
+		}
+	}
+	manufacturerCode, err := strconv.ParseUint(*manufacturerFlag, 0, 16)
+	if err != nil {
+		return fmt.Errorf("invalid manufacturer code %q", *manufacturerFlag)
+	}
+	switch command {
+	case "decode":
+		clusterId, data, err := frameArgs(library, *clusterFlag, flags.Args())
+		if err != nil {
+			return err
+		}
+		return decode(library, clusterId, data, *format, dissect.Options{Colors: *color, HexDump: *hexDump}, stdout)
+	case "encode":
+		return encode(flags.Args(), stdin, stdout)
+	case "clusters":
+		return clusters(library, stdout)
+	case "attributes":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: zcl attributes [-manufacturer code] cluster")
+		}
+		clusterId, err := parseCluster(library, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		return attributes(library, clusterId, uint16(manufacturerCode), stdout)
+	case "validate":
+		clusterId, data, err := frameArgs(library, *clusterFlag, flags.Args())
+		if err != nil {
+			return err
+		}
+		return validate(library, clusterId, data, stdout)
+	}
+	return fmt.Errorf("unknown command %q\n%s", command, usage)
+}
+
+func decode(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, data []uint8, format string, options dissect.Options, stdout io.Writer) error {
+	switch format {
+	case "text":
+		return dissect.Fprint(stdout, dissect.New(library).DissectData(uint16(clusterId), data), options)
+	case "json":
+		message, err := zcl.NewWithLibrary(library).ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: uint16(clusterId), Data: data})
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(message, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(stdout, "%s\n", out)
+		return err
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// encode reads a ZclFrame, or a ZclIncomingMessage, as written by decode
+// -format json and prints it as hex.
+func encode(args []string, stdin io.Reader, stdout io.Writer) error {
+	var input []byte
+	var err error
+	switch len(args) {
+	case 0:
+		input, err = io.ReadAll(stdin)
+	case 1:
+		input, err = os.ReadFile(args[0])
+	default:
+		return fmt.Errorf("usage: zcl encode [file]")
+	}
+	if err != nil {
+		return err
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(input, &probe); err != nil {
+		return err
+	}
+	f := &zcl.ZclFrame{}
+	if data, ok := probe["Data"]; ok {
+		input = data
+	}
+	if err := json.Unmarshal(input, f); err != nil {
+		return err
+	}
+	data, err := zcl.Encode(f)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, hex.EncodeToString(data))
+	return err
+}
+
+func clusters(library *cluster.ClusterLibrary, stdout io.Writer) error {
+	var lines []string
+	for clusterId, c := range library.Clusters() {
+		lines = append(lines, fmt.Sprintf("0x%04x  %s", uint16(clusterId), c.Name))
+	}
+	for manufacturerCode, clusters := range library.Manufacturers() {
+		for clusterId, c := range clusters {
+			lines = append(lines, fmt.Sprintf("0x%04x  %s (manufacturer 0x%04x)", uint16(clusterId), c.Name, manufacturerCode))
+		}
+	}
+	sort.Strings(lines)
+	_, err := fmt.Fprintln(stdout, strings.Join(lines, "\n"))
+	return err
+}
+
+func attributes(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, manufacturerCode uint16, stdout io.Writer) error {
+	candidates := library.Candidates(clusterId, manufacturerCode)
+	if len(candidates) == 0 {
+		return fmt.Errorf("unknown cluster 0x%04x", uint16(clusterId))
+	}
+	seen := map[uint16]bool{}
+	var ids []int
+	descriptors := map[uint16]*cluster.AttributeDescriptor{}
+	for _, c := range candidates {
+		for id, ad := range c.AttributeDescriptors {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, int(id))
+				descriptors[id] = ad
+			}
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		ad := descriptors[uint16(id)]
+		line := fmt.Sprintf("0x%04x  %-32s %-12s %s", id, ad.Name, ad.Type, access(ad))
+		if ad.Default != nil {
+			line += fmt.Sprintf("  default %v", ad.Default)
+		}
+		if ad.Min != nil || ad.Max != nil {
+			line += fmt.Sprintf("  range %v..%v", orBlank(ad.Min), orBlank(ad.Max))
+		}
+		if ad.Unit != "" {
+			line += "  " + ad.Unit
+		}
+		if _, err := fmt.Fprintln(stdout, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate decodes a frame strictly, failing with the problems found.
+func validate(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, data []uint8, stdout io.Writer) error {
+	z := zcl.NewWithLibrary(library)
+	z.SetDecodingMode(zcl.DecodingModeStrict)
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: uint16(clusterId), Data: data})
+	if err != nil {
+		return fmt.Errorf("invalid frame: %s", err)
+	}
+	_, err = fmt.Fprintf(stdout, "valid %s frame\n", message.Data.CommandName)
+	return err
+}
+
+func frameArgs(library *cluster.ClusterLibrary, clusterArg string, args []string) (cluster.ClusterId, []uint8, error) {
+	if clusterArg == "" {
+		return 0, nil, fmt.Errorf("missing -cluster")
+	}
+	clusterId, err := parseCluster(library, clusterArg)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(args) == 0 {
+		return 0, nil, fmt.Errorf("missing frame")
+	}
+	data, err := parseHex(strings.Join(args, ""))
+	return clusterId, data, err
+}
+
+func parseCluster(library *cluster.ClusterLibrary, s string) (cluster.ClusterId, error) {
+	if id, err := strconv.ParseUint(s, 0, 16); err == nil {
+		return cluster.ClusterId(id), nil
+	}
+	for clusterId, c := range library.Clusters() {
+		if strings.EqualFold(c.Name, s) {
+			return clusterId, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cluster %q", s)
+}
+
+// parseHex accepts hex as it appears in logs: with or without 0x, separated
+// by spaces, colons or commas.
+func parseHex(s string) ([]uint8, error) {
+	s = strings.NewReplacer("0x", "", "0X", "", " ", "", ":", "", ",", "", "-", "").Replace(s)
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex frame: %s", err)
+	}
+	return data, nil
+}
+
+func access(ad *cluster.AttributeDescriptor) string {
+	flags := []byte("---")
+	if ad.Access&cluster.Read != 0 {
+		flags[0] = 'R'
+	}
+	if ad.Access&cluster.Write != 0 {
+		flags[1] = 'W'
+	}
+	if ad.Access&cluster.Reportable != 0 {
+		flags[2] = 'P'
+	}
+	s := string(flags)
+	if ad.Mandatory {
+		s += " M"
+	}
+	return s
+}
+
+func orBlank(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
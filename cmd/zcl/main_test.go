@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestZcl(t *testing.T) { TestingT(t) }
+
+type ZclSuite struct{}
+
+var _ = Suite(&ZclSuite{})
+
+func runCommand(command string, args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	err := run(command, args, strings.NewReader(""), stdout)
+	return stdout.String(), err
+}
+
+func (s *ZclSuite) TestDecode(c *C) {
+	out, err := runCommand("decode", "--cluster", "0x0006", "18 05 0b 01 00")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(out, "    CommandID: 0x01 (On)\n"), Equals, true, Commentf(out))
+
+	_, err = runCommand("decode", "18050b0100")
+	c.Assert(err, ErrorMatches, "missing -cluster")
+	_, err = runCommand("decode", "-cluster", "6", "18050")
+	c.Assert(err, ErrorMatches, "invalid hex frame: .*")
+}
+
+func (s *ZclSuite) TestEncodeDecodedJSON(c *C) {
+	out, err := runCommand("decode", "-cluster", "basic", "-format", "json", "0x1c5f11010a07003003")
+	c.Assert(err, IsNil)
+	stdout := &bytes.Buffer{}
+	c.Assert(run("encode", nil, strings.NewReader(out), stdout), IsNil)
+	c.Assert(stdout.String(), Equals, "1c5f11010a07003003\n")
+}
+
+func (s *ZclSuite) TestListLibrary(c *C) {
+	out, err := runCommand("clusters")
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(out, "0x0006  OnOff\n"), Equals, true, Commentf(out))
+
+	out, err = runCommand("attributes", "OnOff")
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(out, "0x0000  OnOff                            Boolean      R-P M  default false\n"), Equals, true, Commentf(out))
+	_, err = runCommand("attributes", "Nothing")
+	c.Assert(err, ErrorMatches, `unknown cluster "Nothing"`)
+}
+
+func (s *ZclSuite) TestValidate(c *C) {
+	out, err := runCommand("validate", "-cluster", "0x0006", "18050b0100")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "valid DefaultResponse frame\n")
+	_, err = runCommand("validate", "-cluster", "0x0006", "38050b0100")
+	c.Assert(err, ErrorMatches, "invalid frame: strict decoding: reserved frame control bits set: 0x20")
+}
@@ -0,0 +1,59 @@
+package zcl
+
+import (
+	"fmt"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+type DecodingMode uint8
+
+const (
+	// DecodingModeLenient decodes whatever it can and reports conformance
+	// problems as warnings on the ZclFrame.
+	DecodingModeLenient DecodingMode = iota
+	// DecodingModeStrict fails decoding on conformance problems.
+	DecodingModeStrict
+)
+
+func (z *Zcl) SetDecodingMode(mode DecodingMode) {
+	z.mode = mode
+}
+
+// check compares the received frame with what was decoded from it: reserved
+// frame control bits and a payload which is longer or shorter than the
+// decoded command re-encoded. The length of the re-encoded command only
+// approximates what was read: fields missing from a short payload are decoded
+// as zero and re-encoded at their full size, variable length fields at the
+// length they were decoded with, so the byte counts of the warnings are
+// estimates.
+func (z *Zcl) check(f *frame.Frame, cmd interface{}) []string {
+	var warnings []string
+	if f.FrameControl.Reserved != 0 {
+		warnings = append(warnings, fmt.Sprintf("reserved frame control bits set: 0x%02x", f.FrameControl.Reserved<<5))
+	}
+	if cmd == nil {
+		return warnings
+	}
+	encoded, ok := encodedLength(cmd)
+	if !ok {
+		return append(warnings, "decoded command can't be re-encoded")
+	}
+	switch {
+	case len(f.Payload) > encoded:
+		warnings = append(warnings, fmt.Sprintf("%d trailing bytes after command payload", len(f.Payload)-encoded))
+	case len(f.Payload) < encoded:
+		warnings = append(warnings, fmt.Sprintf("command payload truncated by %d bytes", encoded-len(f.Payload)))
+	}
+	return warnings
+}
+
+func encodedLength(cmd interface{}) (length int, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	return len(bin.Encode(cmd)), true
+}
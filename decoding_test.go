@@ -0,0 +1,48 @@
+package zcl
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func onOffMessage(data ...uint8) *znp.AfIncomingMessage {
+	return &znp.AfIncomingMessage{ClusterID: uint16(cluster.OnOff), SrcAddr: "0x1234", Data: data}
+}
+
+func (s *ZclSuite) TestLenientDecodingWarnings(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(onOffMessage(0x18, 0x01, 0x0b, 0x01, 0x00, 0xaa, 0xbb))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, DeepEquals, []string{"2 trailing bytes after command payload"})
+
+	message, err = z.ToZclIncomingMessage(onOffMessage(0x18, 0x01, 0x0b, 0x01))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, DeepEquals, []string{"command payload truncated by 1 bytes"})
+
+	message, err = z.ToZclIncomingMessage(onOffMessage(0x38, 0x01, 0x0b, 0x01, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, DeepEquals, []string{"reserved frame control bits set: 0x20"})
+
+	message, err = z.ToZclIncomingMessage(onOffMessage(0x18, 0x01, 0x0b, 0x01, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, IsNil)
+}
+
+func (s *ZclSuite) TestStrictDecoding(c *C) {
+	z := New()
+	z.SetDecodingMode(DecodingModeStrict)
+	_, err := z.ToZclIncomingMessage(onOffMessage(0x18, 0x01, 0x0b, 0x01, 0x00, 0xaa))
+	c.Assert(err, ErrorMatches, "strict decoding: 1 trailing bytes after command payload")
+
+	_, err = z.ToZclIncomingMessage(onOffMessage(0x38, 0x01, 0x0b, 0x01))
+	c.Assert(err, ErrorMatches, "strict decoding: reserved frame control bits set: 0x20; command payload truncated by 1 bytes")
+
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.PhilipsHueSwitch),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x1d, 0x0b, 0x10, 0x01, 0x00, 0x02, 0x00, 0x00, 0x30, 0x02, 0x00, 0x01, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, IsNil)
+}
@@ -0,0 +1,45 @@
+package zcl
+
+import (
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// DefaultResponseRequired reports whether the receiver of message, having
+// processed it with status and sent no specific response, must answer with a
+// DefaultResponse. It isn't sent for broadcast and group addressed messages,
+// for responses, which their command descriptors mark, and for successful
+// commands which disable it.
+func DefaultResponseRequired(message *ZclIncomingMessage, status cluster.ZclStatus) bool {
+	f := message.Data
+	if f == nil || f.FrameControl == nil {
+		return false
+	}
+	if message.WasBroadcast || message.GroupID != 0 || f.Response {
+		return false
+	}
+	return status != cluster.ZclStatusSuccess || !f.FrameControl.DisableDefaultResponse
+}
+
+// DefaultResponse builds the DefaultResponse to message, or returns nil if
+// none is required. It goes in the opposite direction with the transaction
+// sequence number and manufacturer code of message.
+func DefaultResponse(message *ZclIncomingMessage, status cluster.ZclStatus) *frame.Frame {
+	if !DefaultResponseRequired(message, status) {
+		return nil
+	}
+	request := message.Data
+	return &frame.Frame{
+		FrameControl: &frame.FrameControl{
+			FrameType:              frame.FrameTypeGlobal,
+			ManufacturerSpecific:   frame.Flag(request.FrameControl.ManufacturerSpecific),
+			Direction:              request.FrameControl.Direction.Opposite(),
+			DisableDefaultResponse: 1,
+		},
+		ManufacturerCode:          request.ManufacturerCode,
+		TransactionSequenceNumber: request.TransactionSequenceNumber,
+		CommandIdentifier:         uint8(cluster.ZclCommandDefaultResponse),
+		Payload:                   bin.Encode(&cluster.DefaultResponseCommand{CommandID: request.CommandIdentifier, Status: status}),
+	}
+}
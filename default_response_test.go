@@ -0,0 +1,98 @@
+package zcl
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ZclSuite) TestDefaultResponse(c *C) {
+	message, err := New().ToZclIncomingMessage(onOffMessage(0x01, 0x42, 0x01))
+	c.Assert(err, IsNil)
+
+	f := DefaultResponse(message, cluster.ZclStatusSuccess)
+	c.Assert(f, NotNil)
+	c.Assert(f.FrameControl.FrameType, Equals, frame.FrameTypeGlobal)
+	c.Assert(f.FrameControl.Direction, Equals, frame.DirectionServerClient)
+	c.Assert(f.FrameControl.DisableDefaultResponse, Equals, uint8(1))
+	c.Assert(f.FrameControl.ManufacturerSpecific, Equals, uint8(0))
+	c.Assert(f.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(f.CommandIdentifier, Equals, uint8(cluster.ZclCommandDefaultResponse))
+	c.Assert(f.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusSuccess)})
+}
+
+func (s *ZclSuite) TestDefaultResponseManufacturerSpecific(c *C) {
+	message, _ := New().ToZclIncomingMessage(onOffMessage(0x1d, 0x5f, 0x11, 0x42, 0x01))
+
+	f := DefaultResponse(message, cluster.ZclStatusUnsupManuClusterCommand)
+	c.Assert(f, NotNil)
+	c.Assert(f.FrameControl.ManufacturerSpecific, Equals, uint8(1))
+	c.Assert(f.ManufacturerCode, Equals, uint16(0x115f))
+	c.Assert(f.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(f.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusUnsupManuClusterCommand)})
+}
+
+func (s *ZclSuite) TestDefaultResponseRequired(c *C) {
+	z := New()
+	decode := func(m *znp.AfIncomingMessage) *ZclIncomingMessage {
+		message, _ := z.ToZclIncomingMessage(m)
+		return message
+	}
+	on := decode(onOffMessage(0x01, 0x42, 0x01))
+	c.Assert(DefaultResponseRequired(on, cluster.ZclStatusSuccess), Equals, true)
+
+	disabled := decode(onOffMessage(0x11, 0x42, 0x01))
+	c.Assert(DefaultResponseRequired(disabled, cluster.ZclStatusSuccess), Equals, false)
+	c.Assert(DefaultResponseRequired(disabled, cluster.ZclStatusFailure), Equals, true)
+
+	broadcast := decode(&znp.AfIncomingMessage{ClusterID: uint16(cluster.OnOff), WasBroadcast: 1, Data: []uint8{0x01, 0x42, 0x01}})
+	c.Assert(DefaultResponseRequired(broadcast, cluster.ZclStatusFailure), Equals, false)
+
+	group := decode(&znp.AfIncomingMessage{ClusterID: uint16(cluster.OnOff), GroupID: 0x0001, Data: []uint8{0x01, 0x42, 0x01}})
+	c.Assert(DefaultResponseRequired(group, cluster.ZclStatusSuccess), Equals, false)
+
+	defaultResponse := decode(onOffMessage(0x08, 0x42, 0x0b, 0x01, 0x00))
+	c.Assert(DefaultResponseRequired(defaultResponse, cluster.ZclStatusSuccess), Equals, false)
+
+	readResponse := decode(onOffMessage(0x08, 0x42, 0x01, 0x00, 0x00, 0x86))
+	c.Assert(DefaultResponseRequired(readResponse, cluster.ZclStatusSuccess), Equals, false)
+
+	identifyQueryResponse := decode(&znp.AfIncomingMessage{ClusterID: uint16(cluster.Identify), Data: []uint8{0x09, 0x42, 0x00, 0x0a, 0x00}})
+	c.Assert(DefaultResponseRequired(identifyQueryResponse, cluster.ZclStatusSuccess), Equals, false)
+
+	report := decode(onOffMessage(0x08, 0x42, 0x0a, 0x00, 0x00, 0x10, 0x01))
+	c.Assert(DefaultResponseRequired(report, cluster.ZclStatusSuccess), Equals, true)
+}
+
+func (s *ZclSuite) TestDefaultResponseKeepsTransactionSequenceNumbers(c *C) {
+	message, _ := New().ToZclIncomingMessage(onOffMessage(0x01, 0x42, 0x01))
+	next := func() uint8 {
+		f, _ := frame.New().FrameType(frame.FrameTypeLocal).Direction(frame.DirectionClientServer).CommandId(0x01).Build()
+		return f.TransactionSequenceNumber
+	}
+
+	before := next()
+	c.Assert(DefaultResponse(message, cluster.ZclStatusSuccess), NotNil)
+	c.Assert(next(), Equals, before+1)
+}
+
+func (s *ZclSuite) TestDefaultResponseToMarkedResponses(c *C) {
+	library := cluster.New()
+	c.Assert(library.RegisterCommand(cluster.OnOff, 0, frame.DirectionServerClient, 0x80,
+		&cluster.CommandDescriptor{Name: "VendorStatus", Command: &cluster.OnCommand{}, Response: true}), IsNil)
+	c.Assert(library.RegisterCommand(cluster.OnOff, 0, frame.DirectionServerClient, 0x81,
+		&cluster.CommandDescriptor{Name: "VendorResponse", Command: &cluster.OnCommand{}}), IsNil)
+	z := NewWithLibrary(library)
+
+	status, _ := z.ToZclIncomingMessage(onOffMessage(0x19, 0x42, 0x80))
+	c.Assert(status.Data.Response, Equals, true)
+	c.Assert(DefaultResponseRequired(status, cluster.ZclStatusSuccess), Equals, false)
+
+	notification, _ := z.ToZclIncomingMessage(onOffMessage(0x09, 0x42, 0x81))
+	c.Assert(notification.Data.Response, Equals, false)
+	c.Assert(DefaultResponseRequired(notification, cluster.ZclStatusSuccess), Equals, true)
+
+	f := DefaultResponse(notification, cluster.ZclStatusSuccess)
+	c.Assert(f.FrameControl.Direction, Equals, frame.DirectionClientServer)
+}
@@ -0,0 +1,384 @@
+// Package dissect explains ZCL frames field by field, the way a protocol
+// analyzer does, using the names and types of a cluster library.
+package dissect
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+)
+
+// Item is a line of a dissection. Bytes are the raw bytes the item was
+// decoded from, starting at Offset in the frame; items which aren't encoded
+// on their own, like flags sharing a byte, have none.
+type Item struct {
+	Label   string
+	Value   string
+	Offset  int
+	Bytes   []uint8
+	Warning bool
+	Items   []*Item
+}
+
+type Dissector struct {
+	library *cluster.ClusterLibrary
+	zcl     *zcl.Zcl
+}
+
+func New(library *cluster.ClusterLibrary) *Dissector {
+	return &Dissector{library: library, zcl: zcl.NewWithLibrary(library)}
+}
+
+// DissectData decodes and dissects a frame as it was received. Frames which
+// can't be decoded are dissected as far as possible.
+func (d *Dissector) DissectData(clusterId uint16, data []uint8) *Item {
+	message, err := d.zcl.ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: clusterId, Data: data})
+	root := d.dissect(clusterId, message.Data, data)
+	if err != nil {
+		root.Items = append(root.Items, &Item{Label: "Error", Value: err.Error(), Warning: true})
+	}
+	return root
+}
+
+// Dissect dissects a decoded message. The raw bytes are those of the message
+// encoded again, which match the received ones unless the frame was decoded
+// leniently.
+func (d *Dissector) Dissect(m *zcl.ZclIncomingMessage) *Item {
+	return d.dissect(m.ClusterID, m.Data, encode(m.Data))
+}
+
+func (d *Dissector) dissect(clusterId uint16, f *zcl.ZclFrame, data []uint8) *Item {
+	root := &Item{Label: "ZigBee Cluster Library Frame", Value: d.clusterName(clusterId, f), Bytes: data}
+	if f == nil {
+		return root
+	}
+	offset := 0
+	header := func(label string, value string, size int) *Item {
+		item := &Item{Label: label, Value: value, Offset: offset, Bytes: slice(data, offset, size)}
+		offset += size
+		root.Items = append(root.Items, item)
+		return item
+	}
+	fc := f.FrameControl
+	control := header("Frame Control", fmt.Sprintf("0x%02x", first(data)), 1)
+	control.Items = []*Item{
+		bits(first(data), 0x03, "Frame Type", frameTypeName(fc.FrameType)),
+		bits(first(data), 0x04, "Manufacturer Specific", yesNo(fc.ManufacturerSpecific)),
+		bits(first(data), 0x08, "Direction", directionName(fc.Direction)),
+		bits(first(data), 0x10, "Disable Default Response", yesNo(fc.DisableDefaultResponse)),
+	}
+	if reserved := first(data) & 0xe0; reserved != 0 {
+		item := bits(first(data), 0xe0, "Reserved", fmt.Sprintf("0x%02x", reserved>>5))
+		item.Warning = true
+		control.Items = append(control.Items, item)
+	}
+	if fc.ManufacturerSpecific {
+		header("Manufacturer Code", fmt.Sprintf("0x%04x", f.ManufacturerCode), 2)
+	}
+	header("Sequence Number", fmt.Sprint(f.TransactionSequenceNumber), 1)
+	header("Command", commandName(f), 1)
+	if f.Command != nil {
+		payload := encodeValue(reflect.ValueOf(f.Command))
+		r := &recordContext{d: d, data: data, clusterId: cluster.ClusterId(clusterId), manufacturerCode: manufacturerCode(f)}
+		item := r.value(f.CommandName, reflect.ValueOf(f.Command), offset, len(payload))
+		item.Value = ""
+		root.Items = append(root.Items, item)
+		offset += len(payload)
+	}
+	if offset < len(data) {
+		root.Items = append(root.Items, &Item{Label: "Trailing Bytes", Value: fmt.Sprintf("%d bytes", len(data)-offset), Offset: offset, Bytes: data[offset:], Warning: true})
+	}
+	if len(f.Warnings) > 0 {
+		warnings := &Item{Label: "Warnings", Warning: true}
+		for _, warning := range f.Warnings {
+			warnings.Items = append(warnings.Items, &Item{Label: warning, Warning: true})
+		}
+		root.Items = append(root.Items, warnings)
+	}
+	return root
+}
+
+func (d *Dissector) clusterName(clusterId uint16, f *zcl.ZclFrame) string {
+	var mfr uint16
+	if f != nil {
+		mfr = manufacturerCode(f)
+	}
+	if candidates := d.library.Candidates(cluster.ClusterId(clusterId), mfr); len(candidates) > 0 {
+		return fmt.Sprintf("%s (0x%04x)", candidates[0].Name, clusterId)
+	}
+	return fmt.Sprintf("Unknown (0x%04x)", clusterId)
+}
+
+// recordContext resolves the attributes of the records of a command.
+type recordContext struct {
+	d                *Dissector
+	data             []uint8
+	clusterId        cluster.ClusterId
+	manufacturerCode uint16
+	attribute        *cluster.AttributeDescriptor
+}
+
+var attributeType = reflect.TypeOf(&cluster.Attribute{})
+
+// value dissects a field of a command, or the command itself, encoded as size
+// bytes from offset.
+func (r *recordContext) value(label string, v reflect.Value, offset int, size int) *Item {
+	item := &Item{Label: label, Offset: offset, Bytes: slice(r.data, offset, size)}
+	if v.Type() == attributeType {
+		item.Value = r.attributeValue(v.Interface().(*cluster.Attribute))
+		return item
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return item
+		}
+		if _, ok := v.Interface().(bin.Serializable); ok {
+			item.Value = fmt.Sprintf("%+v", v.Elem().Interface())
+			return item
+		}
+		return r.value(label, v.Elem(), offset, size)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		r.strukt(item, v)
+	case reflect.Slice:
+		if k := v.Type().Elem().Kind(); k == reflect.Ptr || k == reflect.Struct {
+			r.records(item, v, size)
+		} else {
+			item.Value = fmt.Sprint(v.Interface())
+		}
+	default:
+		item.Value = fmt.Sprint(v.Interface())
+	}
+	return item
+}
+
+func (r *recordContext) strukt(item *Item, v reflect.Value) {
+	fields := fieldBytes(v)
+	if fields == nil {
+		item.Value = fmt.Sprintf("%+v", v.Interface())
+		return
+	}
+	record := *r
+	if id := v.FieldByName("AttributeID"); id.IsValid() && id.Kind() == reflect.Uint16 {
+		record.attribute, _ = r.d.library.AttributeDescriptor(r.clusterId, r.manufacturerCode, uint16(id.Uint()))
+	}
+	offset := item.Offset
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if len(fields[i]) == 0 && (field.Tag.Get("transient") == "true" || field.Tag.Get("cond") != "") {
+			continue
+		}
+		child := record.value(field.Name, v.Field(i), offset, len(fields[i]))
+		name := v.FieldByName(strings.TrimSuffix(field.Name, "ID") + "Name")
+		if strings.HasSuffix(field.Name, "ID") && isUint(v.Field(i)) && name.IsValid() && name.Kind() == reflect.String {
+			child.Value = fmt.Sprintf("0x%0*x", 2*v.Field(i).Type().Size(), v.Field(i).Uint())
+			if name.String() != "" {
+				child.Value += " (" + name.String() + ")"
+			}
+		}
+		item.Items = append(item.Items, child)
+		offset += len(fields[i])
+	}
+	if item.Value == "" && record.attribute != nil {
+		item.Value = record.attribute.Name
+	}
+}
+
+// records dissects a list of records. Bytes before the first record, e.g. a
+// length, are part of the list only.
+func (r *recordContext) records(item *Item, v reflect.Value, size int) {
+	encoded := make([][]uint8, v.Len())
+	offset := item.Offset + size
+	for i := range encoded {
+		encoded[i] = encodeValue(v.Index(i))
+		offset -= len(encoded[i])
+	}
+	for i := range encoded {
+		child := r.value(fmt.Sprintf("[%d]", i), v.Index(i), offset, len(encoded[i]))
+		item.Items = append(item.Items, child)
+		offset += len(encoded[i])
+	}
+	item.Value = fmt.Sprintf("%d records", v.Len())
+}
+
+func (r *recordContext) attributeValue(a *cluster.Attribute) string {
+	if a == nil {
+		return ""
+	}
+	value := fmt.Sprintf("%s %s", a.DataType, formatValue(a.Value))
+	if r.attribute == nil {
+		return value
+	}
+	switch rendered := r.attribute.Render(a.Value).(type) {
+	case string:
+		if rendered != a.Value {
+			value += " (" + rendered + ")"
+		}
+	case []string:
+		value += " (" + strings.Join(rendered, "|") + ")"
+	}
+	if r.attribute.Unit != "" {
+		value += " " + r.attribute.Unit
+	}
+	return value
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []*cluster.Attribute:
+		values := make([]string, len(v))
+		for i, a := range v {
+			values[i] = fmt.Sprintf("%s %s", a.DataType, formatValue(a.Value))
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case nil:
+		return "-"
+	}
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]uint8, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return fmt.Sprintf("0x%x", b)
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// fieldBytes returns the encoding of every field of a struct, nil if the
+// struct can't be taken apart. Each field is measured by encoding the fields
+// up to it, so conditions on earlier fields still apply.
+func fieldBytes(v reflect.Value) (fields [][]uint8) {
+	defer func() {
+		if recover() != nil {
+			fields = nil
+		}
+	}()
+	t := v.Type()
+	fields = make([][]uint8, t.NumField())
+	var prefix []reflect.StructField
+	var previous []uint8
+	for i := 0; i < t.NumField(); i++ {
+		prefix = append(prefix, t.Field(i))
+		partial := reflect.New(reflect.StructOf(prefix)).Elem()
+		for j := 0; j <= i; j++ {
+			partial.Field(j).Set(v.Field(j))
+		}
+		encoded := bin.Encode(partial.Addr().Interface())
+		fields[i] = encoded[len(previous):]
+		previous = encoded
+	}
+	return fields
+}
+
+func encodeValue(v reflect.Value) (encoded []uint8) {
+	defer func() {
+		if recover() != nil {
+			encoded = nil
+		}
+	}()
+	if v.Kind() != reflect.Ptr {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	return bin.Encode(v.Interface())
+}
+
+func encode(f *zcl.ZclFrame) []uint8 {
+	if f == nil {
+		return nil
+	}
+	data, _ := zcl.Encode(f)
+	return data
+}
+
+func bits(b uint8, mask uint8, label string, value string) *Item {
+	var pattern strings.Builder
+	for bit := 7; bit >= 0; bit-- {
+		switch {
+		case mask&(1<<uint(bit)) == 0:
+			pattern.WriteByte('.')
+		case b&(1<<uint(bit)) != 0:
+			pattern.WriteByte('1')
+		default:
+			pattern.WriteByte('0')
+		}
+		if bit == 4 {
+			pattern.WriteByte(' ')
+		}
+	}
+	return &Item{Label: pattern.String() + " = " + label, Value: value}
+}
+
+func commandName(f *zcl.ZclFrame) string {
+	name := f.CommandName
+	if name == "" {
+		name = "Unknown"
+	}
+	return fmt.Sprintf("%s (0x%02x)", name, f.CommandIdentifier)
+}
+
+func frameTypeName(t frame.FrameType) string {
+	switch t {
+	case frame.FrameTypeGlobal:
+		return "Global (0)"
+	case frame.FrameTypeLocal:
+		return "Cluster Specific (1)"
+	}
+	return fmt.Sprintf("Reserved (%d)", t)
+}
+
+func directionName(d frame.Direction) string {
+	if d == frame.DirectionServerClient {
+		return "Server to Client"
+	}
+	return "Client to Server"
+}
+
+func manufacturerCode(f *zcl.ZclFrame) uint16 {
+	if f.FrameControl.ManufacturerSpecific {
+		return f.ManufacturerCode
+	}
+	return 0
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func first(data []uint8) uint8 {
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
+
+// slice returns up to size bytes of data from offset.
+func slice(data []uint8, offset int, size int) []uint8 {
+	if offset < 0 || offset >= len(data) || size <= 0 {
+		return nil
+	}
+	if offset+size > len(data) {
+		size = len(data) - offset
+	}
+	return data[offset : offset+size]
+}
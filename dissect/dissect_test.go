@@ -0,0 +1,79 @@
+package dissect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestDissect(t *testing.T) { TestingT(t) }
+
+type DissectSuite struct{}
+
+var _ = Suite(&DissectSuite{})
+
+var report = []uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a,
+	0x07, 0x00, byte(cluster.ZclDataTypeEnum8), 0x03,
+	0x13, 0x00, byte(cluster.ZclDataTypeBitmap8), 0x03}
+
+func (s *DissectSuite) TestDissectAttributes(c *C) {
+	d := New(cluster.New())
+	root := d.DissectData(uint16(cluster.Basic), report)
+	c.Assert(root.Value, Equals, "Basic (0x0000)")
+	c.Assert(root.Items[1], DeepEquals, &Item{Label: "Manufacturer Code", Value: "0x115f", Offset: 1, Bytes: []uint8{0x5f, 0x11}})
+	c.Assert(root.Items[3].Value, Equals, "ReportAttributes (0x0a)")
+
+	records := root.Items[4].Items[0]
+	c.Assert(records.Value, Equals, "2 records")
+	c.Assert(records.Items[0], DeepEquals, &Item{Label: "[0]", Value: "PowerSource", Offset: 5, Bytes: []uint8{0x07, 0x00, 0x30, 0x03}, Items: []*Item{
+		{Label: "AttributeID", Value: "0x0007 (PowerSource)", Offset: 5, Bytes: []uint8{0x07, 0x00}},
+		{Label: "Attribute", Value: "Enum8 3 (Battery)", Offset: 7, Bytes: []uint8{0x30, 0x03}},
+	}})
+	c.Assert(records.Items[1].Items[1].Value, Equals, "Bitmap8 3 (GeneralHardwareFault|GeneralSoftwareFault)")
+}
+
+func (s *DissectSuite) TestDissectMessage(c *C) {
+	message, err := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: uint16(cluster.Basic), Data: report})
+	c.Assert(err, IsNil)
+	d := New(cluster.New())
+	c.Assert(d.Dissect(message), DeepEquals, d.DissectData(uint16(cluster.Basic), report))
+}
+
+func (s *DissectSuite) TestDissectMalformed(c *C) {
+	d := New(cluster.New())
+	root := d.DissectData(uint16(cluster.OnOff), []uint8{0x18, 0x05, 0x0b, 0x01, 0x00, 0xaa})
+	c.Assert(root.Items[4], DeepEquals, &Item{Label: "Trailing Bytes", Value: "1 bytes", Offset: 5, Bytes: []uint8{0xaa}, Warning: true})
+
+	root = d.DissectData(uint16(cluster.OnOff), []uint8{0x1c})
+	c.Assert(root.Items, DeepEquals, []*Item{{Label: "Error", Value: "frame too short: 1 bytes, header needs 5", Warning: true}})
+}
+
+func (s *DissectSuite) TestSprint(c *C) {
+	d := New(cluster.New())
+	root := d.DissectData(uint16(cluster.OnOff), []uint8{0x18, 0x05, 0x0b, 0x01, 0x00})
+	c.Assert(Sprint(root, Options{}), Equals, strings.Join([]string{
+		"ZigBee Cluster Library Frame: OnOff (0x0006)",
+		"  Frame Control: 0x18",
+		"    .... ..00 = Frame Type: Global (0)",
+		"    .... .0.. = Manufacturer Specific: No",
+		"    .... 1... = Direction: Server to Client",
+		"    ...1 .... = Disable Default Response: Yes",
+		"  Sequence Number: 5",
+		"  Command: DefaultResponse (0x0b)",
+		"  DefaultResponse",
+		"    CommandID: 0x01 (On)",
+		"    Status: 0",
+		"",
+	}, "\n"))
+
+	hexDump := Sprint(root, Options{HexDump: true})
+	c.Assert(strings.Contains(hexDump, "\n0003  01                           CommandID: 0x01 (On)\n"), Equals, true, Commentf(hexDump))
+	c.Assert(strings.HasSuffix(hexDump, "\n00000000  18 05 0b 01 00                                    |.....|\n"), Equals, true, Commentf(hexDump))
+
+	colored := Sprint(root, Options{Colors: true})
+	c.Assert(strings.Contains(colored, "\x1b[36mSequence Number:\x1b[0m \x1b[32m5\x1b[0m\n"), Equals, true, Commentf(colored))
+}
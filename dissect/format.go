@@ -0,0 +1,106 @@
+package dissect
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Options struct {
+	// Colors highlights labels, values and warnings with ANSI escape codes.
+	Colors bool
+	// HexDump prefixes every line with the offset and the bytes of the item
+	// and ends with a hex dump of the whole frame.
+	HexDump bool
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiCyan   = "\x1b[36m"
+	hexColumns = 8
+)
+
+// Fprint writes a dissection as an indented tree.
+func Fprint(w io.Writer, item *Item, options Options) error {
+	p := &printer{w: w, options: options}
+	p.print(item, 0)
+	if options.HexDump && p.err == nil && len(item.Bytes) > 0 {
+		p.write("\n" + p.color(ansiDim, strings.TrimRight(hex.Dump(item.Bytes), "\n")) + "\n")
+	}
+	return p.err
+}
+
+// Sprint returns a dissection as an indented tree.
+func Sprint(item *Item, options Options) string {
+	var b strings.Builder
+	Fprint(&b, item, options)
+	return b.String()
+}
+
+type printer struct {
+	w       io.Writer
+	options Options
+	err     error
+}
+
+func (p *printer) print(item *Item, depth int) {
+	var line strings.Builder
+	if p.options.HexDump {
+		line.WriteString(p.color(ansiDim, hexColumn(item, depth)))
+	}
+	line.WriteString(strings.Repeat("  ", depth))
+	switch {
+	case item.Warning:
+		line.WriteString(p.color(ansiRed, label(item)))
+	case item.Value == "":
+		line.WriteString(p.color(ansiCyan, item.Label))
+	default:
+		line.WriteString(p.color(ansiCyan, item.Label+":") + " " + p.color(ansiGreen, item.Value))
+	}
+	p.write(line.String() + "\n")
+	for _, child := range item.Items {
+		p.print(child, depth+1)
+	}
+}
+
+func (p *printer) write(s string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, s)
+	}
+}
+
+func (p *printer) color(code string, s string) string {
+	if !p.options.Colors {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func label(item *Item) string {
+	if item.Value == "" {
+		return item.Label
+	}
+	return item.Label + ": " + item.Value
+}
+
+// hexColumn returns the offset and the first bytes of an item, blank for the
+// frame itself and for items without bytes.
+func hexColumn(item *Item, depth int) string {
+	if depth == 0 || len(item.Bytes) == 0 {
+		return strings.Repeat(" ", 6+3*hexColumns-1+2)
+	}
+	b := item.Bytes
+	more := ""
+	if len(b) > hexColumns {
+		b, more = b[:hexColumns-1], " .."
+	}
+	bytes := make([]string, len(b))
+	for i, v := range b {
+		bytes[i] = fmt.Sprintf("%02x", v)
+	}
+	return fmt.Sprintf("%04x  %-*s  ", item.Offset, 3*hexColumns-1, strings.Join(bytes, " ")+more)
+}
@@ -0,0 +1,122 @@
+package zcl
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+type resolver struct {
+	library          *cluster.ClusterLibrary
+	clusterId        cluster.ClusterId
+	manufacturerCode uint16
+	direction        frame.Direction
+}
+
+func (r *resolver) Direction() frame.Direction {
+	return r.direction
+}
+
+func (r *resolver) AttributeName(attributeId uint16) string {
+	if ad, ok := r.library.AttributeDescriptor(r.clusterId, r.manufacturerCode, attributeId); ok {
+		return ad.Name
+	}
+	return ""
+}
+
+func (r *resolver) CommandDescriptor(direction frame.Direction, commandId uint8) (*cluster.CommandDescriptor, bool) {
+	return r.library.CommandDescriptor(r.clusterId, r.manufacturerCode, direction, commandId)
+}
+
+func (r *resolver) GlobalCommandDescriptor(commandId uint8) (*cluster.CommandDescriptor, bool) {
+	cd, ok := r.library.Global()[commandId]
+	return cd, ok
+}
+
+// enrich fills the names of the attributes and commands a decoded command
+// refers to. Every struct reachable from the command with AttributeID and
+// AttributeName fields gets the attribute name, commands implementing
+// cluster.Enricher resolve the rest themselves.
+func (z *Zcl) enrich(cmd interface{}, clusterId uint16, manufacturerCode uint16, direction frame.Direction) {
+	r := &resolver{z.library, cluster.ClusterId(clusterId), manufacturerCode, direction}
+	walk(reflect.ValueOf(cmd), func(v reflect.Value) {
+		id := v.FieldByName("AttributeID")
+		name := v.FieldByName("AttributeName")
+		if id.IsValid() && name.IsValid() && id.Type() == uint16Type && name.Type() == stringType && name.CanSet() {
+			name.SetString(r.AttributeName(uint16(id.Uint())))
+		}
+	})
+	if enricher, ok := cmd.(cluster.Enricher); ok {
+		enricher.Enrich(r)
+	}
+}
+
+// symbols returns the names of the enumerated values and the bitmap flags,
+// joined by "|", of the attribute values a command carries, by attribute id.
+func (z *Zcl) symbols(cmd interface{}, clusterId uint16, manufacturerCode uint16) map[uint16]string {
+	var symbols map[uint16]string
+	walk(reflect.ValueOf(cmd), func(v reflect.Value) {
+		id := v.FieldByName("AttributeID")
+		field := v.FieldByName("Attribute")
+		if !id.IsValid() || id.Type() != uint16Type || !field.IsValid() || !field.CanInterface() {
+			return
+		}
+		attribute, ok := field.Interface().(*cluster.Attribute)
+		if !ok || attribute == nil {
+			return
+		}
+		if _, ok := attribute.Value.(uint64); !ok {
+			return
+		}
+		ad, ok := z.library.AttributeDescriptor(cluster.ClusterId(clusterId), manufacturerCode, uint16(id.Uint()))
+		if !ok {
+			return
+		}
+		var symbol string
+		switch rendered := ad.Render(attribute.Value).(type) {
+		case string:
+			symbol = rendered
+		case []string:
+			symbol = strings.Join(rendered, "|")
+		}
+		if symbol != "" {
+			if symbols == nil {
+				symbols = map[uint16]string{}
+			}
+			symbols[uint16(id.Uint())] = symbol
+		}
+	})
+	return symbols
+}
+
+var (
+	uint16Type = reflect.TypeOf(uint16(0))
+	stringType = reflect.TypeOf("")
+)
+
+// walk calls visit for every struct reachable from v through exported fields,
+// pointers, slices and arrays.
+func walk(v reflect.Value, visit func(reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walk(v.Elem(), visit)
+		}
+	case reflect.Slice, reflect.Array:
+		if k := v.Type().Elem().Kind(); k != reflect.Ptr && k != reflect.Struct && k != reflect.Slice && k != reflect.Array {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), visit)
+		}
+	case reflect.Struct:
+		visit(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				walk(v.Field(i), visit)
+			}
+		}
+	}
+}
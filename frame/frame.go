@@ -1,6 +1,10 @@
 package frame
 
-import "github.com/dyrkin/bin"
+import (
+	"fmt"
+
+	"github.com/dyrkin/bin"
+)
 
 type Direction uint8
 
@@ -9,6 +13,14 @@ const (
 	DirectionServerClient Direction = 0x01
 )
 
+// Opposite returns the direction of a response to a frame sent in d.
+func (d Direction) Opposite() Direction {
+	if d == DirectionServerClient {
+		return DirectionClientServer
+	}
+	return DirectionServerClient
+}
+
 type FrameType uint8
 
 const (
@@ -32,10 +44,24 @@ type Frame struct {
 	Payload                   []uint8
 }
 
-func Decode(buf []uint8) *Frame {
-	frame := &Frame{}
+// Decode decodes a ZCL frame, failing if the buffer is too short for the
+// frame header.
+func Decode(buf []uint8) (frame *Frame, err error) {
+	header := 3
+	if len(buf) > 0 && buf[0]&0x04 != 0 {
+		header = 5
+	}
+	if len(buf) < header {
+		return nil, fmt.Errorf("frame too short: %d bytes, header needs %d", len(buf), header)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			frame, err = nil, fmt.Errorf("malformed frame: %v", r)
+		}
+	}()
+	frame = &Frame{}
 	bin.Decode(buf, frame)
-	return frame
+	return frame, nil
 }
 
 func Encode(frame *Frame) []uint8 {
@@ -85,9 +85,9 @@ func (f *frameConfiguration) Build() (*Frame, error) {
 	frame := &Frame{}
 	frame.FrameControl = &FrameControl{}
 	frame.FrameControl.FrameType = f.frameType
-	frame.FrameControl.ManufacturerSpecific = flag(f.manufacturerCodeConfigured)
+	frame.FrameControl.ManufacturerSpecific = Flag(f.manufacturerCodeConfigured)
 	frame.FrameControl.Direction = f.direction
-	frame.FrameControl.DisableDefaultResponse = flag(f.disableDefaultResponse)
+	frame.FrameControl.DisableDefaultResponse = Flag(f.disableDefaultResponse)
 	frame.ManufacturerCode = f.manufacturerCode
 	frame.TransactionSequenceNumber = f.transactionIdProvider()
 	frame.CommandIdentifier = f.commandId
@@ -112,7 +112,8 @@ func (f *frameConfiguration) validateConfiguration() error {
 	return nil
 }
 
-func flag(flag bool) uint8 {
+// Flag returns a boolean as the value of a one bit field.
+func Flag(flag bool) uint8 {
 	if flag {
 		return 1
 	} else {
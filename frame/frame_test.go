@@ -43,7 +43,8 @@ func (s *FrameSuite) TestDecode(c *C) {
 		[]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
 	}
 
-	res := Decode([]uint8{0x15, 0x7b, 0x0, 0x1, 0x5, 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9})
+	res, err := Decode([]uint8{0x15, 0x7b, 0x0, 0x1, 0x5, 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9})
+	c.Assert(err, IsNil)
 	c.Assert(res, DeepEquals, frame)
 
 	frame = &Frame{
@@ -53,6 +54,16 @@ func (s *FrameSuite) TestDecode(c *C) {
 		5,
 		[]uint8{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
 	}
-	res = Decode([]uint8{0x11, 0x1, 0x5, 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9})
+	res, err = Decode([]uint8{0x11, 0x1, 0x5, 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9})
+	c.Assert(err, IsNil)
 	c.Assert(res, DeepEquals, frame)
 }
+
+func (s *FrameSuite) TestDecodeShortFrame(c *C) {
+	_, err := Decode([]uint8{0x11, 0x01})
+	c.Assert(err, ErrorMatches, "frame too short: 2 bytes, header needs 3")
+	_, err = Decode([]uint8{0x15, 0x7b, 0x00, 0x01})
+	c.Assert(err, ErrorMatches, "frame too short: 4 bytes, header needs 5")
+	_, err = Decode(nil)
+	c.Assert(err, ErrorMatches, "frame too short: 0 bytes, header needs 3")
+}
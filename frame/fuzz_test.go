@@ -0,0 +1,24 @@
+package frame
+
+import (
+	"testing"
+)
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]uint8{0x18, 0x01, 0x0a, 0x00, 0x00, 0x10, 0x01})
+	f.Add([]uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a, 0x01, 0xff, 0x42, 0x02, 0x01, 0x02})
+	f.Add([]uint8{0x1d, 0x0b, 0x10, 0x01, 0x00, 0x02, 0x00, 0x00, 0x30, 0x02, 0x00, 0x01, 0x00})
+	f.Add([]uint8{0x15, 0x7b, 0x00})
+	f.Fuzz(func(t *testing.T, data []uint8) {
+		frame, err := Decode(data)
+		if err != nil {
+			return
+		}
+		if frame.FrameControl == nil {
+			t.Fatalf("decoded frame without frame control from %x", data)
+		}
+		if encoded := Encode(frame); len(encoded) != len(data) {
+			t.Fatalf("re-encoded %x as %x", data, encoded)
+		}
+	})
+}
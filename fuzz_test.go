@@ -0,0 +1,28 @@
+package zcl
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/znp-go"
+)
+
+func FuzzToZclIncomingMessage(f *testing.F) {
+	f.Add(uint16(cluster.Basic), []uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a,
+		0x01, 0xff, byte(cluster.ZclDataTypeCharStr), 0x02, 0x01, 0x02,
+		0x05, 0x00, byte(cluster.ZclDataTypeCharStr), 0x01, 0x41})
+	f.Add(uint16(cluster.PhilipsHueSwitch), []uint8{0x1d, 0x0b, 0x10, 0x01, 0x00, 0x02, 0x00, 0x00, 0x30, 0x02, 0x00, 0x01, 0x00})
+	f.Add(uint16(cluster.OnOff), []uint8{0x18, 0x05, 0x0b, 0x01, 0x00})
+	f.Add(uint16(cluster.OnOff), []uint8{0x11, 0x06, 0x02})
+	f.Add(uint16(cluster.Basic), []uint8{0x18, 0x01, 0x12, 0x01, 0x00, 0x01})
+	f.Fuzz(func(t *testing.T, clusterId uint16, data []uint8) {
+		for _, mode := range []DecodingMode{DecodingModeLenient, DecodingModeStrict} {
+			z := New()
+			z.SetDecodingMode(mode)
+			message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: clusterId, Data: data})
+			if err == nil && message.Data == nil {
+				t.Fatalf("no frame and no error decoding %x", data)
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+// Package yamljson gives types with a JSON encoding the same YAML encoding,
+// through the Marshaler and Unmarshaler interfaces YAML libraries look for,
+// without depending on one.
+package yamljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Marshal returns the JSON encoding of v as plain maps, slices and scalars,
+// for a MarshalYAML method to return.
+func Marshal(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var generic interface{}
+	if err := d.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return fromJSON(generic), nil
+}
+
+// Unmarshal decodes a YAML value into v through its JSON decoding, for an
+// UnmarshalYAML(unmarshal func(interface{}) error) method.
+func Unmarshal(unmarshal func(interface{}) error, v interface{}) error {
+	var generic interface{}
+	if err := unmarshal(&generic); err != nil {
+		return err
+	}
+	data, err := json.Marshal(toJSON(generic))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// fromJSON turns json.Number into the Go number YAML encodes losslessly.
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = fromJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = fromJSON(value)
+		}
+	}
+	return v
+}
+
+// toJSON turns the map[interface{}]interface{} YAML libraries decode mappings
+// to into maps encoding/json accepts.
+func toJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = toJSON(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = toJSON(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = toJSON(value)
+		}
+	}
+	return v
+}
@@ -0,0 +1,65 @@
+package zcl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/internal/yamljson"
+)
+
+type zclFrame ZclFrame
+
+// zclFrameJSON tags the command with the name of its type, so that it can be
+// decoded back into the same Go type.
+type zclFrameJSON struct {
+	*zclFrame
+	CommandType string
+	Command     json.RawMessage
+}
+
+func (f *ZclFrame) MarshalJSON() ([]byte, error) {
+	command, err := json.Marshal(f.Command)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&zclFrameJSON{(*zclFrame)(f), cluster.CommandTypeName(f.Command), command})
+}
+
+func (f *ZclFrame) UnmarshalJSON(data []byte) error {
+	j := &zclFrameJSON{zclFrame: (*zclFrame)(f)}
+	if err := json.Unmarshal(data, j); err != nil {
+		return err
+	}
+	f.Command = nil
+	if j.CommandType == "" {
+		return nil
+	}
+	command, ok := cluster.NewCommandOfType(j.CommandType)
+	if !ok {
+		return fmt.Errorf("unknown command type %q", j.CommandType)
+	}
+	if err := json.Unmarshal(j.Command, command); err != nil {
+		return fmt.Errorf("%s: %s", j.CommandType, err)
+	}
+	f.Command = command
+	return nil
+}
+
+func (f *ZclFrame) MarshalYAML() (interface{}, error) {
+	return yamljson.Marshal(f)
+}
+
+func (f *ZclFrame) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return yamljson.Unmarshal(unmarshal, f)
+}
+
+type zclIncomingMessage ZclIncomingMessage
+
+func (m *ZclIncomingMessage) MarshalYAML() (interface{}, error) {
+	return yamljson.Marshal((*zclIncomingMessage)(m))
+}
+
+func (m *ZclIncomingMessage) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return yamljson.Unmarshal(unmarshal, (*zclIncomingMessage)(m))
+}
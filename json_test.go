@@ -0,0 +1,100 @@
+package zcl
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func (s *ZclSuite) TestJSONRoundTrip(c *C) {
+	z := New()
+	messages := []*znp.AfIncomingMessage{
+		{ClusterID: uint16(cluster.Basic), SrcAddr: "0x1234", Data: []uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a,
+			0x01, 0xff, byte(cluster.ZclDataTypeCharStr), 0x02, 0x01, 0xff,
+			0x07, 0x00, byte(cluster.ZclDataTypeEnum8), 0x03,
+			0x13, 0x00, byte(cluster.ZclDataTypeBitmap8), 0x03}},
+		{ClusterID: uint16(cluster.PhilipsHueSwitch), SrcAddr: "0x1234", Data: []uint8{0x1d, 0x0b, 0x10, 0x01, 0x00, 0x02, 0x00, 0x00, 0x30, 0x02, 0x00, 0x01, 0x00}},
+		{ClusterID: uint16(cluster.OnOff), SrcAddr: "0x1234", Data: []uint8{0x18, 0x05, 0x0b, 0x01, 0x00}},
+		{ClusterID: uint16(cluster.OnOff), SrcAddr: "0x1234", Data: []uint8{0x11, 0x06, 0x02}},
+	}
+	for _, m := range messages {
+		message, err := z.ToZclIncomingMessage(m)
+		c.Assert(err, IsNil)
+		data, err := json.Marshal(message)
+		c.Assert(err, IsNil)
+		decoded := &ZclIncomingMessage{}
+		c.Assert(json.Unmarshal(data, decoded), IsNil, Commentf("%s", data))
+		c.Assert(decoded, DeepEquals, message, Commentf("%s", data))
+	}
+}
+
+func (s *ZclSuite) TestJSONNames(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Basic),
+		SrcAddr:   "0x1234",
+		Data: []uint8{0x18, 0x01, 0x0a,
+			0x07, 0x00, byte(cluster.ZclDataTypeEnum8), 0x03,
+			0x13, 0x00, byte(cluster.ZclDataTypeBitmap8), 0x03},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.ClusterName, Equals, "Basic")
+	c.Assert(message.Data.Symbols, DeepEquals, map[uint16]string{0x0007: "Battery", 0x0013: "GeneralHardwareFault|GeneralSoftwareFault"})
+
+	data, err := json.Marshal(message)
+	c.Assert(err, IsNil)
+	for _, expected := range []string{`"ClusterName":"Basic"`, `"CommandName":"ReportAttributes"`, `"CommandType":"cluster.ReportAttributesCommand"`,
+		`"AttributeName":"PowerSource"`, `"7":"Battery"`, `{"DataType":"Enum8","Value":3}`} {
+		c.Assert(strings.Contains(string(data), expected), Equals, true, Commentf("%s not in %s", expected, data))
+	}
+}
+
+func (s *ZclSuite) TestJSONUnknownCommandType(c *C) {
+	err := json.Unmarshal([]byte(`{"CommandType":"cluster.Missing","Command":{}}`), &ZclFrame{})
+	c.Assert(err, ErrorMatches, `unknown command type "cluster.Missing"`)
+}
+
+// yamlUnmarshal stands in for a YAML library, which decodes mappings with
+// interface{} keys.
+func yamlUnmarshal(value interface{}) func(interface{}) error {
+	return func(v interface{}) error {
+		*(v.(*interface{})) = toYAMLMapping(value)
+		return nil
+	}
+}
+
+func toYAMLMapping(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		m := map[interface{}]interface{}{}
+		for k, v := range value {
+			m[k] = toYAMLMapping(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range value {
+			value[i] = toYAMLMapping(v)
+		}
+	}
+	return value
+}
+
+func (s *ZclSuite) TestYAMLRoundTrip(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Basic),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0a, 0x07, 0x00, byte(cluster.ZclDataTypeEnum8), 0x03},
+	})
+	c.Assert(err, IsNil)
+	value, err := message.MarshalYAML()
+	c.Assert(err, IsNil)
+	c.Assert(value.(map[string]interface{})["ClusterID"], Equals, int64(0))
+
+	decoded := &ZclIncomingMessage{}
+	c.Assert(decoded.UnmarshalYAML(yamlUnmarshal(value)), IsNil)
+	c.Assert(decoded, DeepEquals, message)
+}
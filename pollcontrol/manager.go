@@ -0,0 +1,151 @@
+package pollcontrol
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+const (
+	checkInCommandId         uint8 = 0x00
+	checkInResponseCommandId uint8 = 0x00
+	fastPollStopCommandId    uint8 = 0x01
+)
+
+type Sender func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error
+
+type PendingCommand struct {
+	Endpoint  uint8
+	ClusterId cluster.ClusterId
+	Frame     *frame.Frame
+}
+
+// Manager holds commands addressed to sleepy end devices until they check in.
+// When a device sends CheckIn the manager asks it to fast poll, flushes the
+// queued commands and sends FastPollStop so the device can go back to sleep.
+type Manager struct {
+	sender          Sender
+	fastPollTimeout uint16
+	mutex           sync.Mutex
+	queues          map[string][]*PendingCommand
+}
+
+func New(sender Sender, fastPollTimeout uint16) *Manager {
+	return &Manager{
+		sender:          sender,
+		fastPollTimeout: fastPollTimeout,
+		queues:          map[string][]*PendingCommand{},
+	}
+}
+
+func (m *Manager) Enqueue(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.queues[address] = append(m.queues[address], &PendingCommand{endpoint, clusterId, f})
+}
+
+func (m *Manager) Pending(address string) []*PendingCommand {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	pending := make([]*PendingCommand, len(m.queues[address]))
+	copy(pending, m.queues[address])
+	return pending
+}
+
+func (m *Manager) Clear(address string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.queues, address)
+}
+
+// Handle answers CheckIn commands. It returns false if the message isn't a
+// CheckIn, so callers can pass every incoming message through it.
+func (m *Manager) Handle(message *zcl.ZclIncomingMessage) (bool, error) {
+	if !isCheckIn(message) {
+		return false, nil
+	}
+	pending := m.take(message.SrcAddr)
+	response := &cluster.CheckInResponse{}
+	if len(pending) > 0 {
+		response.StartFastPolling = 1
+		response.FastPollTimeout = m.fastPollTimeout
+	}
+	f, err := m.buildFrame(checkInResponseCommandId, response)
+	if err != nil {
+		m.requeue(message.SrcAddr, pending)
+		return true, err
+	}
+	f.TransactionSequenceNumber = message.Data.TransactionSequenceNumber
+	if err := m.sender(message.SrcAddr, message.SrcEndpoint, cluster.PollControl, f); err != nil {
+		m.requeue(message.SrcAddr, pending)
+		return true, fmt.Errorf("unable to send check-in response: %s", err)
+	}
+	if len(pending) == 0 {
+		return true, nil
+	}
+	flushErr := m.flush(message.SrcAddr, pending)
+	if err := m.stopFastPoll(message.SrcAddr, message.SrcEndpoint); err != nil && flushErr == nil {
+		return true, err
+	}
+	return true, flushErr
+}
+
+func (m *Manager) flush(address string, pending []*PendingCommand) error {
+	for i, command := range pending {
+		if err := m.sender(address, command.Endpoint, command.ClusterId, command.Frame); err != nil {
+			m.requeue(address, pending[i:])
+			return fmt.Errorf("unable to send queued command: %s", err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) stopFastPoll(address string, endpoint uint8) error {
+	f, err := m.buildFrame(fastPollStopCommandId, &cluster.FastPollStopCommand{})
+	if err != nil {
+		return err
+	}
+	if err := m.sender(address, endpoint, cluster.PollControl, f); err != nil {
+		return fmt.Errorf("unable to send fast poll stop: %s", err)
+	}
+	return nil
+}
+
+func (m *Manager) buildFrame(commandId uint8, command interface{}) (*frame.Frame, error) {
+	return frame.New().
+		FrameType(frame.FrameTypeLocal).
+		Direction(frame.DirectionClientServer).
+		CommandId(commandId).
+		Command(command).
+		Build()
+}
+
+func (m *Manager) take(address string) []*PendingCommand {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	pending := m.queues[address]
+	delete(m.queues, address)
+	return pending
+}
+
+func (m *Manager) requeue(address string, pending []*PendingCommand) {
+	if len(pending) == 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.queues[address] = append(append([]*PendingCommand{}, pending...), m.queues[address]...)
+}
+
+func isCheckIn(message *zcl.ZclIncomingMessage) bool {
+	if message.ClusterID != uint16(cluster.PollControl) || message.Data == nil || message.Data.FrameControl == nil {
+		return false
+	}
+	fc := message.Data.FrameControl
+	return fc.FrameType == frame.FrameTypeLocal &&
+		fc.Direction == frame.DirectionServerClient &&
+		message.Data.CommandIdentifier == checkInCommandId
+}
@@ -0,0 +1,119 @@
+package pollcontrol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestPollControl(t *testing.T) { TestingT(t) }
+
+type ManagerSuite struct{}
+
+var _ = Suite(&ManagerSuite{})
+
+type sent struct {
+	address   string
+	endpoint  uint8
+	clusterId cluster.ClusterId
+	frame     *frame.Frame
+}
+
+func checkIn(c *C) *zcl.ZclIncomingMessage {
+	message, err := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:   uint16(cluster.PollControl),
+		SrcAddr:     "0x1234",
+		SrcEndpoint: 1,
+		Data:        []uint8{0x19, 0x42, 0x00},
+	})
+	c.Assert(err, IsNil)
+	return message
+}
+
+func onOff(c *C) *frame.Frame {
+	f, err := frame.New().
+		FrameType(frame.FrameTypeLocal).
+		Direction(frame.DirectionClientServer).
+		CommandId(0x01).
+		Build()
+	c.Assert(err, IsNil)
+	return f
+}
+
+func (s *ManagerSuite) TestCheckInWithoutPendingCommands(c *C) {
+	var frames []*sent
+	m := New(func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		frames = append(frames, &sent{address, endpoint, clusterId, f})
+		return nil
+	}, 40)
+
+	handled, err := m.Handle(checkIn(c))
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, true)
+	c.Assert(frames, HasLen, 1)
+	c.Assert(frames[0].clusterId, Equals, cluster.PollControl)
+	c.Assert(frames[0].frame.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(frames[0].frame.Payload, DeepEquals, []uint8{0x00, 0x00, 0x00})
+}
+
+func (s *ManagerSuite) TestCheckInFlushesPendingCommands(c *C) {
+	var frames []*sent
+	m := New(func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		frames = append(frames, &sent{address, endpoint, clusterId, f})
+		return nil
+	}, 40)
+	m.Enqueue("0x1234", 2, cluster.OnOff, onOff(c))
+	m.Enqueue("0x5678", 1, cluster.OnOff, onOff(c))
+
+	handled, err := m.Handle(checkIn(c))
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, true)
+	c.Assert(frames, HasLen, 3)
+	c.Assert(frames[0].frame.CommandIdentifier, Equals, checkInResponseCommandId)
+	c.Assert(frames[0].frame.Payload, DeepEquals, []uint8{0x01, 40, 0x00})
+	c.Assert(frames[1].endpoint, Equals, uint8(2))
+	c.Assert(frames[1].clusterId, Equals, cluster.OnOff)
+	c.Assert(frames[2].clusterId, Equals, cluster.PollControl)
+	c.Assert(frames[2].frame.CommandIdentifier, Equals, fastPollStopCommandId)
+	c.Assert(m.Pending("0x1234"), HasLen, 0)
+	c.Assert(m.Pending("0x5678"), HasLen, 1)
+}
+
+func (s *ManagerSuite) TestFailedCommandsStayQueued(c *C) {
+	calls := 0
+	m := New(func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		calls++
+		if clusterId == cluster.OnOff {
+			return errors.New("no ack")
+		}
+		return nil
+	}, 40)
+	m.Enqueue("0x1234", 1, cluster.OnOff, onOff(c))
+
+	handled, err := m.Handle(checkIn(c))
+	c.Assert(handled, Equals, true)
+	c.Assert(err, NotNil)
+	c.Assert(calls, Equals, 3)
+	c.Assert(m.Pending("0x1234"), HasLen, 1)
+}
+
+func (s *ManagerSuite) TestIgnoresOtherMessages(c *C) {
+	m := New(func(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		c.Fatal("nothing should be sent")
+		return nil
+	}, 40)
+	message, _ := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0a, 0x00, 0x00, 0x10, 0x01},
+	})
+
+	handled, err := m.Handle(message)
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, false)
+}
@@ -0,0 +1,261 @@
+// Package server lets applications act as ZCL servers: handlers registered
+// per endpoint, cluster and command execute the commands clients send and the
+// dispatcher answers them as the specification requires. A Store holds the
+// attributes of the clusters and answers the global commands on them, and a
+// Reporter reports them as clients configured.
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// Sender sends a frame from a cluster on an endpoint of the application,
+// srcEndpoint, to an endpoint of a device.
+type Sender func(srcEndpoint uint8, address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error
+
+// BroadcastEndpoint addresses every endpoint of a device.
+const BroadcastEndpoint uint8 = 0xff
+
+var (
+	contextType = reflect.TypeOf(&Context{})
+	statusType  = reflect.TypeOf(cluster.ZclStatusSuccess)
+)
+
+// Context is the command a handler executes, with what it needs to answer.
+// Endpoint is the endpoint executing it, which differs from the destination
+// of the message when it was sent to BroadcastEndpoint.
+type Context struct {
+	Message    *zcl.ZclIncomingMessage
+	Endpoint   uint8
+	dispatcher *Dispatcher
+	responses  []*response
+	silent     bool
+}
+
+type response struct {
+	frameType frame.FrameType
+	commandId uint8
+	command   interface{}
+}
+
+type key struct {
+	endpoint  uint8
+	clusterId cluster.ClusterId
+	frameType frame.FrameType
+	commandId uint8
+}
+
+// Dispatcher calls the handler registered for each command it dispatches and
+// sends the response: the ones the handler produced, otherwise a
+// DefaultResponse with the status the handler returned when one is required.
+type Dispatcher struct {
+	library  *cluster.ClusterLibrary
+	sender   Sender
+	mutex    sync.RWMutex
+	handlers map[key]reflect.Value
+	// clusters are the clusters with handlers on each endpoint.
+	clusters map[uint8]map[cluster.ClusterId]bool
+}
+
+func New(library *cluster.ClusterLibrary, sender Sender) *Dispatcher {
+	return &Dispatcher{
+		library:  library,
+		sender:   sender,
+		handlers: map[key]reflect.Value{},
+		clusters: map[uint8]map[cluster.ClusterId]bool{},
+	}
+}
+
+// Handle registers the handler of a command received by a cluster on an
+// endpoint. The handler is a func(*Context, *Command) cluster.ZclStatus where
+// Command is the type of a global command or of a command the cluster
+// receives, e.g.
+//
+//	d.Handle(1, cluster.OnOff, func(ctx *server.Context, cmd *cluster.OnCommand) cluster.ZclStatus {
+//		return cluster.ZclStatusSuccess
+//	})
+func (d *Dispatcher) Handle(endpoint uint8, clusterId cluster.ClusterId, handler interface{}) error {
+	h := reflect.ValueOf(handler)
+	t := h.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.In(0) != contextType || t.In(1).Kind() != reflect.Ptr ||
+		t.NumOut() != 1 || t.Out(0) != statusType {
+		return fmt.Errorf("handler must be a func(*server.Context, *Command) cluster.ZclStatus, not %s", t)
+	}
+	frameType, commandId, ok := d.lookup(clusterId, frame.DirectionClientServer, t.In(1))
+	if !ok {
+		return fmt.Errorf("%s isn't a command of cluster 0x%04x", t.In(1), uint16(clusterId))
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.handlers[key{endpoint, clusterId, frameType, commandId}] = h
+	if d.clusters[endpoint] == nil {
+		d.clusters[endpoint] = map[cluster.ClusterId]bool{}
+	}
+	d.clusters[endpoint][clusterId] = true
+	return nil
+}
+
+// Dispatch executes a command sent to the application. It returns false for
+// messages which aren't commands to a server, e.g. responses, so callers can
+// pass every incoming message through it. A command sent to
+// BroadcastEndpoint is executed by every endpoint with handlers for the
+// cluster, each of them answering it.
+func (d *Dispatcher) Dispatch(message *zcl.ZclIncomingMessage) (bool, error) {
+	f := message.Data
+	if f == nil || f.FrameControl == nil || f.FrameControl.Direction != frame.DirectionClientServer {
+		return false, nil
+	}
+	if f.FrameControl.FrameType == frame.FrameTypeGlobal && f.CommandIdentifier == uint8(cluster.ZclCommandDefaultResponse) {
+		return false, nil
+	}
+	for _, endpoint := range d.endpoints(message) {
+		ctx := &Context{Message: message, Endpoint: endpoint, dispatcher: d}
+		status := d.call(ctx)
+		if len(ctx.responses) == 0 && !ctx.silent {
+			if f := zcl.DefaultResponse(message, status); f != nil {
+				if err := d.send(ctx, f); err != nil {
+					return true, err
+				}
+			}
+		}
+		for _, r := range ctx.responses {
+			if err := d.respond(ctx, r); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// endpoints returns the endpoints which execute a message.
+func (d *Dispatcher) endpoints(message *zcl.ZclIncomingMessage) []uint8 {
+	if message.DstEndpoint != BroadcastEndpoint {
+		return []uint8{message.DstEndpoint}
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	var endpoints []uint8
+	for endpoint, clusters := range d.clusters {
+		if clusters[cluster.ClusterId(message.ClusterID)] {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i] < endpoints[j] })
+	return endpoints
+}
+
+func (d *Dispatcher) call(ctx *Context) cluster.ZclStatus {
+	f := ctx.Message.Data
+	clusterId := cluster.ClusterId(ctx.Message.ClusterID)
+	d.mutex.RLock()
+	supported := d.clusters[ctx.Endpoint][clusterId]
+	h, ok := d.handlers[key{ctx.Endpoint, clusterId, f.FrameControl.FrameType, f.CommandIdentifier}]
+	d.mutex.RUnlock()
+	if !supported {
+		return cluster.ZclStatusUnsupportedCluster
+	}
+	if !ok || f.FrameControl.ManufacturerSpecific {
+		return unsupported(f)
+	}
+	if f.Command == nil || reflect.TypeOf(f.Command) != h.Type().In(1) {
+		return cluster.ZclStatusMalformedCommand
+	}
+	return h.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(f.Command)})[0].Interface().(cluster.ZclStatus)
+}
+
+func (d *Dispatcher) respond(ctx *Context, r *response) error {
+	request := ctx.Message
+	b := frame.New().
+		FrameType(r.frameType).
+		Direction(frame.DirectionServerClient).
+		DisableDefaultResponse(true).
+		CommandId(r.commandId).
+		Command(r.command)
+	if request.Data.FrameControl.ManufacturerSpecific {
+		b = b.ManufacturerCode(request.Data.ManufacturerCode)
+	}
+	f, err := b.Build()
+	if err != nil {
+		return err
+	}
+	f.TransactionSequenceNumber = request.Data.TransactionSequenceNumber
+	return d.send(ctx, f)
+}
+
+func (d *Dispatcher) send(ctx *Context, f *frame.Frame) error {
+	request := ctx.Message
+	if err := d.sender(ctx.Endpoint, request.SrcAddr, request.SrcEndpoint, cluster.ClusterId(request.ClusterID), f); err != nil {
+		return fmt.Errorf("unable to send response: %s", err)
+	}
+	return nil
+}
+
+// lookup finds the frame type and id of a command type among the global
+// commands and the commands of a cluster in direction.
+func (d *Dispatcher) lookup(clusterId cluster.ClusterId, direction frame.Direction, t reflect.Type) (frame.FrameType, uint8, bool) {
+	for _, c := range d.library.Candidates(clusterId, 0) {
+		descriptors := c.CommandDescriptors
+		if descriptors == nil {
+			continue
+		}
+		commands := descriptors.Received
+		if direction == frame.DirectionServerClient {
+			commands = descriptors.Generated
+		}
+		if commandId, ok := find(commands, t); ok {
+			return frame.FrameTypeLocal, commandId, true
+		}
+	}
+	if commandId, ok := find(d.library.Global(), t); ok {
+		return frame.FrameTypeGlobal, commandId, true
+	}
+	return 0, 0, false
+}
+
+func find(commands map[uint8]*cluster.CommandDescriptor, t reflect.Type) (uint8, bool) {
+	for commandId, cd := range commands {
+		if reflect.TypeOf(cd.Command) == t {
+			return commandId, true
+		}
+	}
+	return 0, false
+}
+
+// Respond sends command in response instead of a DefaultResponse, e.g. an
+// IdentifyQueryResponse to an IdentifyQuery. The command must be a global one
+// or one the cluster generates.
+func (ctx *Context) Respond(command interface{}) error {
+	clusterId := cluster.ClusterId(ctx.Message.ClusterID)
+	frameType, commandId, ok := ctx.dispatcher.lookup(clusterId, frame.DirectionServerClient, reflect.TypeOf(command))
+	if !ok {
+		return fmt.Errorf("%T isn't a command of cluster 0x%04x", command, uint16(clusterId))
+	}
+	ctx.responses = append(ctx.responses, &response{frameType, commandId, command})
+	return nil
+}
+
+// NoResponse suppresses the DefaultResponse of a command which is never
+// answered, e.g. an IdentifyQuery to a device which isn't identifying.
+func (ctx *Context) NoResponse() {
+	ctx.silent = true
+}
+
+func unsupported(f *zcl.ZclFrame) cluster.ZclStatus {
+	global := f.FrameControl.FrameType == frame.FrameTypeGlobal
+	switch {
+	case global && f.FrameControl.ManufacturerSpecific:
+		return cluster.ZclStatusUnsupManuGeneralCommand
+	case global:
+		return cluster.ZclStatusUnsupGeneralCommand
+	case f.FrameControl.ManufacturerSpecific:
+		return cluster.ZclStatusUnsupManuClusterCommand
+	}
+	return cluster.ZclStatusUnsupClusterCommand
+}
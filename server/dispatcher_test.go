@@ -0,0 +1,203 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestServer(t *testing.T) { TestingT(t) }
+
+type DispatcherSuite struct {
+	dispatcher *Dispatcher
+	frames     []*sent
+}
+
+var _ = Suite(&DispatcherSuite{})
+
+type sent struct {
+	source    uint8
+	address   string
+	endpoint  uint8
+	clusterId cluster.ClusterId
+	frame     *frame.Frame
+}
+
+func (s *DispatcherSuite) SetUpTest(c *C) {
+	s.frames = nil
+	s.dispatcher = New(cluster.New(), func(source uint8, address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		s.frames = append(s.frames, &sent{source, address, endpoint, clusterId, f})
+		return nil
+	})
+}
+
+func message(c *C, clusterId cluster.ClusterId, data ...uint8) *zcl.ZclIncomingMessage {
+	return messageTo(c, 1, clusterId, data...)
+}
+
+func messageTo(c *C, endpoint uint8, clusterId cluster.ClusterId, data ...uint8) *zcl.ZclIncomingMessage {
+	message, _ := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:   uint16(clusterId),
+		SrcAddr:     "0x1234",
+		SrcEndpoint: 2,
+		DstEndpoint: endpoint,
+		Data:        data,
+	})
+	c.Assert(message.Data, NotNil)
+	return message
+}
+
+func (s *DispatcherSuite) dispatch(c *C, clusterId cluster.ClusterId, data ...uint8) {
+	s.dispatchTo(c, 1, clusterId, data...)
+}
+
+func (s *DispatcherSuite) dispatchTo(c *C, endpoint uint8, clusterId cluster.ClusterId, data ...uint8) {
+	handled, err := s.dispatcher.Dispatch(messageTo(c, endpoint, clusterId, data...))
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, true)
+}
+
+func (s *DispatcherSuite) TestHandlerAndDefaultResponse(c *C) {
+	var on bool
+	err := s.dispatcher.Handle(1, cluster.OnOff, func(ctx *Context, cmd *cluster.OnCommand) cluster.ZclStatus {
+		on = true
+		return cluster.ZclStatusSuccess
+	})
+	c.Assert(err, IsNil)
+
+	s.dispatch(c, cluster.OnOff, 0x01, 0x42, 0x01)
+	c.Assert(on, Equals, true)
+	c.Assert(s.frames, HasLen, 1)
+	c.Assert(s.frames[0].source, Equals, uint8(1))
+	c.Assert(s.frames[0].address, Equals, "0x1234")
+	c.Assert(s.frames[0].endpoint, Equals, uint8(2))
+	c.Assert(s.frames[0].clusterId, Equals, cluster.OnOff)
+	f := s.frames[0].frame
+	c.Assert(f.FrameControl.FrameType, Equals, frame.FrameTypeGlobal)
+	c.Assert(f.FrameControl.Direction, Equals, frame.DirectionServerClient)
+	c.Assert(f.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(f.CommandIdentifier, Equals, uint8(cluster.ZclCommandDefaultResponse))
+	c.Assert(f.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusSuccess)})
+}
+
+func (s *DispatcherSuite) TestDisableDefaultResponse(c *C) {
+	status := cluster.ZclStatusSuccess
+	s.dispatcher.Handle(1, cluster.OnOff, func(ctx *Context, cmd *cluster.OffCommand) cluster.ZclStatus {
+		return status
+	})
+
+	s.dispatch(c, cluster.OnOff, 0x11, 0x42, 0x00)
+	c.Assert(s.frames, HasLen, 0)
+
+	status = cluster.ZclStatusFailure
+	s.dispatch(c, cluster.OnOff, 0x11, 0x43, 0x00)
+	c.Assert(s.frames, HasLen, 1)
+	c.Assert(s.frames[0].frame.Payload, DeepEquals, []uint8{0x00, uint8(cluster.ZclStatusFailure)})
+}
+
+func (s *DispatcherSuite) TestRespond(c *C) {
+	s.dispatcher.Handle(1, cluster.Identify, func(ctx *Context, cmd *cluster.IdentifyQueryCommand) cluster.ZclStatus {
+		c.Assert(ctx.Respond(&cluster.IdentifyQueryResponse{Timeout: 10}), IsNil)
+		return cluster.ZclStatusSuccess
+	})
+
+	s.dispatch(c, cluster.Identify, 0x01, 0x42, 0x01)
+	c.Assert(s.frames, HasLen, 1)
+	f := s.frames[0].frame
+	c.Assert(f.FrameControl.FrameType, Equals, frame.FrameTypeLocal)
+	c.Assert(f.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(f.CommandIdentifier, Equals, uint8(0x00))
+	c.Assert(f.Payload, DeepEquals, []uint8{10, 0})
+}
+
+func (s *DispatcherSuite) TestGlobalCommand(c *C) {
+	s.dispatcher.Handle(1, cluster.OnOff, func(ctx *Context, cmd *cluster.ReadAttributesCommand) cluster.ZclStatus {
+		c.Assert(cmd.AttributeIDs, DeepEquals, []uint16{0x0000})
+		ctx.Respond(&cluster.ReadAttributesResponse{ReadAttributeStatuses: []*cluster.ReadAttributeStatus{
+			{AttributeID: 0x0000, Status: cluster.ZclStatusSuccess, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true}},
+		}})
+		return cluster.ZclStatusSuccess
+	})
+
+	s.dispatch(c, cluster.OnOff, 0x00, 0x42, 0x00, 0x00, 0x00)
+	c.Assert(s.frames, HasLen, 1)
+	f := s.frames[0].frame
+	c.Assert(f.FrameControl.FrameType, Equals, frame.FrameTypeGlobal)
+	c.Assert(f.CommandIdentifier, Equals, uint8(cluster.ZclCommandReadAttributesResponse))
+	c.Assert(f.Payload, DeepEquals, []uint8{0x00, 0x00, 0x00, uint8(cluster.ZclDataTypeBoolean), 0x01})
+}
+
+func (s *DispatcherSuite) TestUnsupportedCommands(c *C) {
+	s.dispatch(c, cluster.OnOff, 0x11, 0x42, 0x01)
+	s.dispatch(c, cluster.OnOff, 0x00, 0x43, 0x00, 0x00, 0x00)
+	s.dispatcher.Handle(1, cluster.OnOff, func(ctx *Context, cmd *cluster.OnCommand) cluster.ZclStatus {
+		return cluster.ZclStatusSuccess
+	})
+	s.dispatch(c, cluster.OnOff, 0x11, 0x44, 0x00)
+	s.dispatch(c, cluster.OnOff, 0x00, 0x45, 0x00, 0x00, 0x00)
+	s.dispatch(c, cluster.LevelControl, 0x01, 0x46, 0x01)
+
+	c.Assert(s.frames, HasLen, 5)
+	c.Assert(s.frames[0].frame.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusUnsupportedCluster)})
+	c.Assert(s.frames[1].frame.Payload, DeepEquals, []uint8{0x00, uint8(cluster.ZclStatusUnsupportedCluster)})
+	c.Assert(s.frames[2].frame.Payload, DeepEquals, []uint8{0x00, uint8(cluster.ZclStatusUnsupClusterCommand)})
+	c.Assert(s.frames[3].frame.Payload, DeepEquals, []uint8{0x00, uint8(cluster.ZclStatusUnsupGeneralCommand)})
+	c.Assert(s.frames[4].frame.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusUnsupportedCluster)})
+}
+
+func (s *DispatcherSuite) TestBroadcastEndpoint(c *C) {
+	var endpoints []uint8
+	for _, endpoint := range []uint8{3, 1} {
+		s.dispatcher.Handle(endpoint, cluster.OnOff, func(ctx *Context, cmd *cluster.OnCommand) cluster.ZclStatus {
+			endpoints = append(endpoints, ctx.Endpoint)
+			return cluster.ZclStatusSuccess
+		})
+	}
+	s.dispatcher.Handle(2, cluster.Identify, func(ctx *Context, cmd *cluster.IdentifyCommand) cluster.ZclStatus {
+		return cluster.ZclStatusSuccess
+	})
+
+	s.dispatchTo(c, BroadcastEndpoint, cluster.OnOff, 0x01, 0x42, 0x01)
+	c.Assert(endpoints, DeepEquals, []uint8{1, 3})
+	c.Assert(s.frames, HasLen, 2)
+	c.Assert(s.frames[0].source, Equals, uint8(1))
+	c.Assert(s.frames[1].source, Equals, uint8(3))
+	c.Assert(s.frames[1].frame.Payload, DeepEquals, []uint8{0x01, uint8(cluster.ZclStatusSuccess)})
+
+	s.frames = nil
+	s.dispatchTo(c, BroadcastEndpoint, cluster.LevelControl, 0x01, 0x43, 0x01)
+	c.Assert(s.frames, HasLen, 0)
+}
+
+func (s *DispatcherSuite) TestIgnoresResponses(c *C) {
+	handled, err := s.dispatcher.Dispatch(message(c, cluster.OnOff, 0x18, 0x42, 0x0b, 0x01, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, false)
+	c.Assert(s.frames, HasLen, 0)
+}
+
+func (s *DispatcherSuite) TestInvalidHandlers(c *C) {
+	err := s.dispatcher.Handle(1, cluster.OnOff, func(cmd *cluster.OnCommand) cluster.ZclStatus { return 0 })
+	c.Assert(err, ErrorMatches, "handler must be .*")
+	err = s.dispatcher.Handle(1, cluster.OnOff, func(ctx *Context, cmd *cluster.IdentifyCommand) cluster.ZclStatus { return 0 })
+	c.Assert(err, ErrorMatches, `\*cluster.IdentifyCommand isn't a command of cluster 0x0006`)
+}
+
+func (s *DispatcherSuite) TestNoDefaultResponseToBroadcasts(c *C) {
+	message, _ := zcl.New().ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:    uint16(cluster.OnOff),
+		SrcAddr:      "0x1234",
+		SrcEndpoint:  2,
+		DstEndpoint:  1,
+		WasBroadcast: 1,
+		Data:         []uint8{0x01, 0x42, 0x01},
+	})
+	handled, err := s.dispatcher.Dispatch(message)
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, true)
+	c.Assert(s.frames, HasLen, 0)
+}
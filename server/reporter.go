@@ -0,0 +1,292 @@
+package server
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+const stopReporting uint16 = 0xffff
+
+// ReportSender sends a ReportAttributes frame from a cluster on an endpoint,
+// e.g. to the client which configured the reports or to the bound ones.
+type ReportSender func(endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame)
+
+// Reporter reports the attributes of a Store as ConfigureReporting commands
+// configured them: when their value changed by the reportable change, and
+// when the maximum reporting interval elapsed without a report, but never
+// sooner than the minimum reporting interval after the last report.
+type Reporter struct {
+	store   *Store
+	clock   Clock
+	send    ReportSender
+	mutex   sync.Mutex
+	reports map[reportKey]*report
+}
+
+type reportKey struct {
+	endpoint    uint8
+	clusterId   cluster.ClusterId
+	attributeId uint16
+	direction   cluster.ReportDirection
+}
+
+// report is the configuration of an attribute, with the state of its reports
+// for the reported direction.
+type report struct {
+	key    reportKey
+	record *cluster.AttributeReportingConfigurationRecord
+	// change is the reportable change of analog attributes, 0 for discrete
+	// ones which report every change.
+	change   float64
+	reported interface{}
+	last     time.Time
+	periodic Timer
+	pending  Timer
+}
+
+func NewReporter(store *Store, clock Clock, send ReportSender) *Reporter {
+	r := &Reporter{
+		store:   store,
+		clock:   clock,
+		send:    send,
+		reports: map[reportKey]*report{},
+	}
+	store.Subscribe(r.changed)
+	return r
+}
+
+// Configure applies the records of a ConfigureReportingCommand received by a
+// cluster which are valid and lists the others, or a single success status
+// if there are none.
+func (r *Reporter) Configure(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.ConfigureReportingCommand) *cluster.ConfigureReportingResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	response := &cluster.ConfigureReportingResponse{}
+	for _, record := range cmd.AttributeReportingConfigurationRecords {
+		if status := r.configure(endpoint, clusterId, record); status != cluster.ZclStatusSuccess {
+			response.AttributeStatusRecords = append(response.AttributeStatusRecords, &cluster.AttributeStatusRecord{
+				Status:      status,
+				Direction:   record.Direction,
+				AttributeID: record.AttributeID,
+			})
+		}
+	}
+	if len(response.AttributeStatusRecords) == 0 {
+		response.AttributeStatusRecords = []*cluster.AttributeStatusRecord{{Status: cluster.ZclStatusSuccess}}
+	}
+	return response
+}
+
+// ReadConfiguration answers a ReadReportingConfigurationCommand received by a
+// cluster.
+func (r *Reporter) ReadConfiguration(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.ReadReportingConfigurationCommand) *cluster.ReadReportingConfigurationResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	response := &cluster.ReadReportingConfigurationResponse{}
+	for _, record := range cmd.AttributeRecords {
+		rr := &cluster.AttributeReportingConfigurationResponseRecord{
+			Direction:   record.Direction,
+			AttributeID: record.AttributeID,
+		}
+		rr.Status = r.store.Reportable(endpoint, clusterId, record.AttributeID)
+		if rr.Status == cluster.ZclStatusSuccess {
+			if rep, ok := r.reports[reportKey{endpoint, clusterId, record.AttributeID, record.Direction}]; ok {
+				rr.AttributeDataType = rep.record.AttributeDataType
+				rr.MinimumReportingInterval = rep.record.MinimumReportingInterval
+				rr.MaximumReportingInterval = rep.record.MaximumReportingInterval
+				rr.ReportableChange = rep.record.ReportableChange
+				rr.TimeoutPeriod = rep.record.TimeoutPeriod
+			} else {
+				rr.Status = cluster.ZclStatusNotFound
+			}
+		}
+		response.AttributeReportingConfigurationResponseRecords = append(response.AttributeReportingConfigurationResponseRecords, rr)
+	}
+	return response
+}
+
+// Register makes the dispatcher answer ConfigureReporting and
+// ReadReportingConfiguration commands received by a cluster on an endpoint.
+func (r *Reporter) Register(d *Dispatcher, endpoint uint8, clusterId cluster.ClusterId) error {
+	err := d.Handle(endpoint, clusterId, func(ctx *Context, cmd *cluster.ConfigureReportingCommand) cluster.ZclStatus {
+		return respond(ctx, r.Configure(endpoint, clusterId, cmd))
+	})
+	if err != nil {
+		return err
+	}
+	return d.Handle(endpoint, clusterId, func(ctx *Context, cmd *cluster.ReadReportingConfigurationCommand) cluster.ZclStatus {
+		return respond(ctx, r.ReadConfiguration(endpoint, clusterId, cmd))
+	})
+}
+
+// Close stops the timers of the reports.
+func (r *Reporter) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key, rep := range r.reports {
+		rep.stop()
+		delete(r.reports, key)
+	}
+}
+
+func (r *Reporter) configure(endpoint uint8, clusterId cluster.ClusterId, record *cluster.AttributeReportingConfigurationRecord) cluster.ZclStatus {
+	attribute, ok := r.store.Attribute(endpoint, clusterId, record.AttributeID)
+	if !ok {
+		return cluster.ZclStatusUnsupportedAttribute
+	}
+	key := reportKey{endpoint, clusterId, record.AttributeID, record.Direction}
+	stored := *record
+	record = &stored
+	if record.Direction == cluster.ReportDirectionAttributeReceived {
+		// reports the application receives are its own business, the
+		// timeout is only kept to be read back
+		r.replace(key, &report{key: key, record: record})
+		return cluster.ZclStatusSuccess
+	}
+	if status := r.store.Reportable(endpoint, clusterId, record.AttributeID); status != cluster.ZclStatusSuccess {
+		return status
+	}
+	if record.AttributeDataType != attribute.DataType {
+		return cluster.ZclStatusInvalidDataType
+	}
+	if record.MaximumReportingInterval == stopReporting {
+		r.replace(key, nil)
+		return cluster.ZclStatusSuccess
+	}
+	if record.MaximumReportingInterval != 0 && record.MinimumReportingInterval > record.MaximumReportingInterval {
+		return cluster.ZclStatusInvalidValue
+	}
+	rep := &report{key: key, record: record, reported: attribute.Value, last: r.clock.Now()}
+	if record.ReportableChange == nil {
+		record.ReportableChange = &cluster.Attribute{DataType: cluster.ZclDataTypeNoData}
+	}
+	if analog(attribute.DataType) {
+		rep.change, _ = ToFloat(record.ReportableChange.Value)
+	}
+	r.replace(key, rep)
+	r.schedule(rep)
+	return cluster.ZclStatusSuccess
+}
+
+func (r *Reporter) replace(key reportKey, rep *report) {
+	if previous, ok := r.reports[key]; ok {
+		previous.stop()
+	}
+	if rep == nil {
+		delete(r.reports, key)
+		return
+	}
+	r.reports[key] = rep
+}
+
+// changed sends the report of an attribute which changed by its reportable
+// change, or delays it until the minimum interval elapsed.
+func (r *Reporter) changed(change *Change) {
+	r.mutex.Lock()
+	rep, ok := r.reports[reportKey{change.Endpoint, change.ClusterId, change.AttributeId, cluster.ReportDirectionAttributeReported}]
+	if !ok || !rep.due(change.Attribute.Value) {
+		r.mutex.Unlock()
+		return
+	}
+	wait := rep.last.Add(seconds(rep.record.MinimumReportingInterval)).Sub(r.clock.Now())
+	if wait > 0 {
+		if rep.pending == nil {
+			var t Timer
+			t = r.clock.AfterFunc(wait, func() { r.fire(rep, &rep.pending, &t) })
+			rep.pending = t
+		}
+		r.mutex.Unlock()
+		return
+	}
+	f := r.report(rep)
+	r.mutex.Unlock()
+	r.send(rep.key.endpoint, rep.key.clusterId, f)
+}
+
+// schedule sets the timer of the periodic report.
+func (r *Reporter) schedule(rep *report) {
+	if rep.periodic != nil {
+		rep.periodic.Stop()
+		rep.periodic = nil
+	}
+	if rep.record.MaximumReportingInterval == 0 {
+		return
+	}
+	var t Timer
+	t = r.clock.AfterFunc(seconds(rep.record.MaximumReportingInterval), func() { r.fire(rep, &rep.periodic, &t) })
+	rep.periodic = t
+}
+
+// fire sends the report a timer was set for, unless the timer was stopped
+// or, for a delayed change, the value went back to the one last reported. t
+// is read with the reporter locked: the timer may fire before AfterFunc
+// returned it.
+func (r *Reporter) fire(rep *report, timer *Timer, t *Timer) {
+	r.mutex.Lock()
+	if r.reports[rep.key] != rep || *timer != *t {
+		r.mutex.Unlock()
+		return
+	}
+	*timer = nil
+	if timer == &rep.pending {
+		if attribute, ok := r.store.Attribute(rep.key.endpoint, rep.key.clusterId, rep.key.attributeId); !ok || !rep.due(attribute.Value) {
+			r.mutex.Unlock()
+			return
+		}
+	}
+	f := r.report(rep)
+	r.mutex.Unlock()
+	r.send(rep.key.endpoint, rep.key.clusterId, f)
+}
+
+// report builds the report of the current value and restarts the intervals.
+func (r *Reporter) report(rep *report) *frame.Frame {
+	attribute, _ := r.store.Attribute(rep.key.endpoint, rep.key.clusterId, rep.key.attributeId)
+	rep.reported, rep.last = attribute.Value, r.clock.Now()
+	if rep.pending != nil {
+		rep.pending.Stop()
+		rep.pending = nil
+	}
+	r.schedule(rep)
+	f, _ := frame.New().
+		FrameType(frame.FrameTypeGlobal).
+		Direction(frame.DirectionServerClient).
+		DisableDefaultResponse(true).
+		CommandId(uint8(cluster.ZclCommandReportAttributes)).
+		Command(&cluster.ReportAttributesCommand{
+			AttributeReports: []*cluster.AttributeReport{{AttributeID: rep.key.attributeId, Attribute: attribute}},
+		}).
+		Build()
+	return f
+}
+
+// due reports whether value differs enough from the value last reported.
+func (rep *report) due(value interface{}) bool {
+	if reflect.DeepEqual(value, rep.reported) {
+		return false
+	}
+	if rep.change == 0 {
+		return true
+	}
+	v, ok1 := ToFloat(value)
+	reported, ok2 := ToFloat(rep.reported)
+	return !ok1 || !ok2 || math.Abs(v-reported) >= rep.change
+}
+
+func (rep *report) stop() {
+	for _, t := range []Timer{rep.periodic, rep.pending} {
+		if t != nil {
+			t.Stop()
+		}
+	}
+	rep.periodic, rep.pending = nil, nil
+}
+
+func seconds(n uint16) time.Duration {
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"time"
+
+	"github.com/dyrkin/bin"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at   time.Time
+	f    func()
+	done bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := !t.done
+	t.done = true
+	return stopped
+}
+
+// advance moves the time forward, firing the timers due on the way in order.
+func (c *fakeClock) advance(d time.Duration) {
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.done && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.at
+		next.done = true
+		next.f()
+	}
+	c.now = end
+}
+
+type ReporterSuite struct {
+	store    *Store
+	clock    *fakeClock
+	reporter *Reporter
+	reports  []*frame.Frame
+}
+
+var _ = Suite(&ReporterSuite{})
+
+func (s *ReporterSuite) SetUpTest(c *C) {
+	s.reports = nil
+	s.store = NewStore(cluster.New())
+	c.Assert(s.store.AddCluster(1, cluster.OnOff), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.LevelControl), IsNil)
+	s.clock = &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s.reporter = NewReporter(s.store, s.clock, func(endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) {
+		c.Assert(endpoint, Equals, uint8(1))
+		c.Assert(f.CommandIdentifier, Equals, uint8(cluster.ZclCommandReportAttributes))
+		s.reports = append(s.reports, f)
+	})
+}
+
+func (s *ReporterSuite) configure(c *C, clusterId cluster.ClusterId, record *cluster.AttributeReportingConfigurationRecord) {
+	response := s.reporter.Configure(1, clusterId, &cluster.ConfigureReportingCommand{
+		AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{record},
+	})
+	c.Assert(response.AttributeStatusRecords, DeepEquals, []*cluster.AttributeStatusRecord{{Status: cluster.ZclStatusSuccess}})
+	c.Assert(bin.Encode(response), DeepEquals, []uint8{uint8(cluster.ZclStatusSuccess)})
+}
+
+func level(min uint16, max uint16, change uint64) *cluster.AttributeReportingConfigurationRecord {
+	return &cluster.AttributeReportingConfigurationRecord{
+		AttributeID:              0x0000,
+		AttributeDataType:        cluster.ZclDataTypeUint8,
+		MinimumReportingInterval: min,
+		MaximumReportingInterval: max,
+		ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeUint8, Value: change},
+	}
+}
+
+func onOff(min uint16, max uint16) *cluster.AttributeReportingConfigurationRecord {
+	return &cluster.AttributeReportingConfigurationRecord{
+		AttributeID:              0x0000,
+		AttributeDataType:        cluster.ZclDataTypeBoolean,
+		MinimumReportingInterval: min,
+		MaximumReportingInterval: max,
+	}
+}
+
+func (s *ReporterSuite) TestConfigureStatuses(c *C) {
+	response := s.reporter.Configure(1, cluster.OnOff, &cluster.ConfigureReportingCommand{
+		AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{
+			onOff(0, 60),
+			{AttributeID: 0x4001, AttributeDataType: cluster.ZclDataTypeUint16},
+			{AttributeID: 0x1234, AttributeDataType: cluster.ZclDataTypeUint16},
+			{AttributeID: 0x0000, AttributeDataType: cluster.ZclDataTypeUint8},
+		},
+	})
+	c.Assert(response.AttributeStatusRecords, DeepEquals, []*cluster.AttributeStatusRecord{
+		{Status: cluster.ZclStatusUnreportableAttribute, AttributeID: 0x4001},
+		{Status: cluster.ZclStatusUnsupportedAttribute, AttributeID: 0x1234},
+		{Status: cluster.ZclStatusInvalidDataType, AttributeID: 0x0000},
+	})
+	c.Assert(bin.Encode(response)[:4], DeepEquals, []uint8{uint8(cluster.ZclStatusUnreportableAttribute), 0x00, 0x01, 0x40})
+
+	response = s.reporter.Configure(1, cluster.OnOff, &cluster.ConfigureReportingCommand{
+		AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{onOff(60, 10)},
+	})
+	c.Assert(response.AttributeStatusRecords[0].Status, Equals, cluster.ZclStatusInvalidValue)
+}
+
+func (s *ReporterSuite) TestReportableChange(c *C) {
+	s.configure(c, cluster.LevelControl, level(0, 0, 10))
+
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(5)), IsNil)
+	c.Assert(s.reports, HasLen, 0)
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(20)), IsNil)
+	c.Assert(s.reports, HasLen, 1)
+	c.Assert(s.reports[0].Payload, DeepEquals, []uint8{0x00, 0x00, uint8(cluster.ZclDataTypeUint8), 20})
+
+	// the threshold is relative to the value last reported
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(12)), IsNil)
+	c.Assert(s.reports, HasLen, 1)
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(10)), IsNil)
+	c.Assert(s.reports, HasLen, 2)
+}
+
+func (s *ReporterSuite) TestMinimumInterval(c *C) {
+	s.configure(c, cluster.OnOff, onOff(10, 0))
+
+	s.store.Set(1, cluster.OnOff, 0x0000, true)
+	c.Assert(s.reports, HasLen, 0)
+	s.clock.advance(5 * time.Second)
+	c.Assert(s.reports, HasLen, 0)
+	s.clock.advance(5 * time.Second)
+	c.Assert(s.reports, HasLen, 1)
+	c.Assert(s.reports[0].Payload, DeepEquals, []uint8{0x00, 0x00, uint8(cluster.ZclDataTypeBoolean), 0x01})
+
+	// a change undone before the minimum interval elapsed isn't reported
+	s.store.Set(1, cluster.OnOff, 0x0000, false)
+	s.store.Set(1, cluster.OnOff, 0x0000, true)
+	s.clock.advance(time.Minute)
+	c.Assert(s.reports, HasLen, 1)
+}
+
+func (s *ReporterSuite) TestMaximumInterval(c *C) {
+	s.configure(c, cluster.LevelControl, level(1, 60, 10))
+
+	s.clock.advance(59 * time.Second)
+	c.Assert(s.reports, HasLen, 0)
+	s.clock.advance(time.Second)
+	c.Assert(s.reports, HasLen, 1)
+	s.clock.advance(30 * time.Second)
+	s.store.Set(1, cluster.LevelControl, 0x0000, uint64(100))
+	c.Assert(s.reports, HasLen, 2)
+
+	// the change report restarted the maximum interval
+	s.clock.advance(59 * time.Second)
+	c.Assert(s.reports, HasLen, 2)
+	s.clock.advance(time.Second)
+	c.Assert(s.reports, HasLen, 3)
+	c.Assert(s.reports[2].Payload, DeepEquals, []uint8{0x00, 0x00, uint8(cluster.ZclDataTypeUint8), 100})
+
+	s.configure(c, cluster.LevelControl, level(0, 0xffff, 0))
+	s.clock.advance(time.Hour)
+	s.store.Set(1, cluster.LevelControl, 0x0000, uint64(200))
+	c.Assert(s.reports, HasLen, 3)
+}
+
+func (s *ReporterSuite) TestReadConfiguration(c *C) {
+	s.configure(c, cluster.LevelControl, level(1, 60, 10))
+
+	response := s.reporter.ReadConfiguration(1, cluster.LevelControl, &cluster.ReadReportingConfigurationCommand{
+		AttributeRecords: []*cluster.AttributeRecord{{AttributeID: 0x0000}, {AttributeID: 0x0001}, {AttributeID: 0x1234}},
+	})
+	c.Assert(response.AttributeReportingConfigurationResponseRecords, DeepEquals, []*cluster.AttributeReportingConfigurationResponseRecord{
+		{
+			Status:                   cluster.ZclStatusSuccess,
+			AttributeID:              0x0000,
+			AttributeDataType:        cluster.ZclDataTypeUint8,
+			MinimumReportingInterval: 1,
+			MaximumReportingInterval: 60,
+			ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeUint8, Value: uint64(10)},
+		},
+		{Status: cluster.ZclStatusUnreportableAttribute, AttributeID: 0x0001},
+		{Status: cluster.ZclStatusUnsupportedAttribute, AttributeID: 0x1234},
+	})
+
+	response = s.reporter.ReadConfiguration(1, cluster.OnOff, &cluster.ReadReportingConfigurationCommand{
+		AttributeRecords: []*cluster.AttributeRecord{{AttributeID: 0x0000}},
+	})
+	c.Assert(response.AttributeReportingConfigurationResponseRecords[0].Status, Equals, cluster.ZclStatusNotFound)
+}
+
+// immediateClock fires timers as soon as they're set.
+type immediateClock struct {
+	now time.Time
+}
+
+func (c immediateClock) Now() time.Time {
+	return c.now
+}
+
+func (c immediateClock) AfterFunc(d time.Duration, f func()) Timer {
+	go f()
+	return time.NewTimer(time.Hour)
+}
+
+func (s *ReporterSuite) TestTimerFiringBeforeItIsSet(c *C) {
+	reports := make(chan *frame.Frame, 2)
+	reporter := NewReporter(s.store, immediateClock{s.clock.now}, func(endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) {
+		reports <- f
+	})
+	defer reporter.Close()
+	reporter.Configure(1, cluster.OnOff, &cluster.ConfigureReportingCommand{
+		AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{onOff(1, 0)},
+	})
+
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, true), IsNil)
+	<-reports
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, false), IsNil)
+	select {
+	case f := <-reports:
+		c.Assert(f.Payload, DeepEquals, []uint8{0x00, 0x00, uint8(cluster.ZclDataTypeBoolean), 0x00})
+	case <-time.After(time.Second):
+		c.Fatal("the delayed report wasn't sent")
+	}
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// Change is an attribute which got a new value.
+type Change struct {
+	Endpoint    uint8
+	ClusterId   cluster.ClusterId
+	AttributeId uint16
+	Previous    interface{}
+	Attribute   *cluster.Attribute
+}
+
+// Store holds the attributes of server clusters and answers the global
+// commands which read, write and discover them, enforcing the access their
+// descriptors declare.
+type Store struct {
+	library   *cluster.ClusterLibrary
+	mutex     sync.Mutex
+	clusters  map[clusterKey]*attributes
+	listeners []func(change *Change)
+}
+
+type clusterKey struct {
+	endpoint  uint8
+	clusterId cluster.ClusterId
+}
+
+type attributes struct {
+	definition *cluster.Cluster
+	values     map[uint16]*cluster.Attribute
+}
+
+func NewStore(library *cluster.ClusterLibrary) *Store {
+	return &Store{
+		library:  library,
+		clusters: map[clusterKey]*attributes{},
+	}
+}
+
+// AddCluster adds a server cluster to an endpoint, replacing the one which
+// was there. It supports every attribute its library definition has, with its
+// default value or the zero value of its type.
+func (s *Store) AddCluster(endpoint uint8, clusterId cluster.ClusterId) error {
+	candidates := s.library.Candidates(clusterId, 0)
+	if len(candidates) == 0 {
+		return fmt.Errorf("unknown cluster 0x%04x", uint16(clusterId))
+	}
+	a := &attributes{definition: candidates[0], values: map[uint16]*cluster.Attribute{}}
+	for id, ad := range a.definition.AttributeDescriptors {
+		value := ad.Default
+		if value == nil {
+			var ok bool
+			if value, ok = zeroValue(ad.Type); !ok {
+				continue
+			}
+		}
+		a.values[id] = &cluster.Attribute{DataType: ad.Type, Value: value}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clusters[clusterKey{endpoint, clusterId}] = a
+	return nil
+}
+
+func (s *Store) HasCluster(endpoint uint8, clusterId cluster.ClusterId) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.clusters[clusterKey{endpoint, clusterId}]
+	return ok
+}
+
+// Subscribe adds a listener called with every change of an attribute value,
+// after the store applied it. Listeners run on the goroutine which made the
+// change.
+func (s *Store) Subscribe(listener func(change *Change)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Attribute returns a copy of an attribute.
+func (s *Store) Attribute(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (*cluster.Attribute, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	attribute, ok := s.attribute(endpoint, clusterId, attributeId)
+	if !ok {
+		return nil, false
+	}
+	copy := *attribute
+	return &copy, true
+}
+
+// Descriptor returns the descriptor of a supported attribute.
+func (s *Store) Descriptor(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (*cluster.AttributeDescriptor, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.attribute(endpoint, clusterId, attributeId); !ok {
+		return nil, false
+	}
+	return s.clusters[clusterKey{endpoint, clusterId}].definition.AttributeDescriptors[attributeId], true
+}
+
+// Reportable returns the status of configuring reports of an attribute.
+func (s *Store) Reportable(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) cluster.ZclStatus {
+	ad, ok := s.Descriptor(endpoint, clusterId, attributeId)
+	switch {
+	case !ok:
+		return cluster.ZclStatusUnsupportedAttribute
+	case ad.Access&cluster.Reportable == 0:
+		return cluster.ZclStatusUnreportableAttribute
+	}
+	return cluster.ZclStatusSuccess
+}
+
+// Set changes an attribute as the application itself would, e.g. a sensor
+// measuring a new value, regardless of its access.
+func (s *Store) Set(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16, value interface{}) error {
+	s.mutex.Lock()
+	attribute, ok := s.attribute(endpoint, clusterId, attributeId)
+	if !ok {
+		s.mutex.Unlock()
+		return fmt.Errorf("unsupported attribute 0x%04x of cluster 0x%04x on endpoint %d", attributeId, uint16(clusterId), endpoint)
+	}
+	ad := *s.clusters[clusterKey{endpoint, clusterId}].definition.AttributeDescriptors[attributeId]
+	ad.Access |= cluster.Write
+	if status := ad.ValidateWrite(&cluster.Attribute{DataType: attribute.DataType, Value: value}); status != cluster.ZclStatusSuccess {
+		s.mutex.Unlock()
+		return fmt.Errorf("invalid value %v for %s: status 0x%02x", value, ad.Name, uint8(status))
+	}
+	changes := s.set(endpoint, clusterId, attributeId, value)
+	s.mutex.Unlock()
+	s.notify(changes)
+	return nil
+}
+
+// ReadAttributes answers a ReadAttributesCommand received by a cluster.
+func (s *Store) ReadAttributes(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.ReadAttributesCommand) *cluster.ReadAttributesResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	response := &cluster.ReadAttributesResponse{}
+	for _, id := range cmd.AttributeIDs {
+		status := &cluster.ReadAttributeStatus{AttributeID: id}
+		attribute, ok := s.attribute(endpoint, clusterId, id)
+		switch {
+		case !ok:
+			status.Status = cluster.ZclStatusUnsupportedAttribute
+		case s.clusters[clusterKey{endpoint, clusterId}].definition.AttributeDescriptors[id].Access&cluster.Read == 0:
+			status.Status = cluster.ZclStatusWriteOnly
+		default:
+			copy := *attribute
+			status.Attribute = &copy
+		}
+		response.ReadAttributeStatuses = append(response.ReadAttributeStatuses, status)
+	}
+	return response
+}
+
+// WriteAttributes writes the valid records, or none of them if one is invalid
+// and the write is undivided. The response lists the failed records, or a
+// single success status if there are none.
+func (s *Store) WriteAttributes(endpoint uint8, clusterId cluster.ClusterId, records []*cluster.WriteAttributeRecord, undivided bool) *cluster.WriteAttributesResponse {
+	s.mutex.Lock()
+	response := &cluster.WriteAttributesResponse{}
+	var valid []*cluster.WriteAttributeRecord
+	for _, record := range records {
+		status := cluster.ZclStatusUnsupportedAttribute
+		if _, ok := s.attribute(endpoint, clusterId, record.AttributeID); ok {
+			status = s.clusters[clusterKey{endpoint, clusterId}].definition.AttributeDescriptors[record.AttributeID].ValidateWrite(record.Attribute)
+		}
+		if status != cluster.ZclStatusSuccess {
+			response.WriteAttributeStatuses = append(response.WriteAttributeStatuses, &cluster.WriteAttributeStatus{
+				Status:      status,
+				AttributeID: record.AttributeID,
+			})
+			continue
+		}
+		valid = append(valid, record)
+	}
+	if len(response.WriteAttributeStatuses) == 0 {
+		response.WriteAttributeStatuses = []*cluster.WriteAttributeStatus{{Status: cluster.ZclStatusSuccess}}
+	} else if undivided {
+		s.mutex.Unlock()
+		return response
+	}
+	var changes []*Change
+	for _, record := range valid {
+		changes = append(changes, s.set(endpoint, clusterId, record.AttributeID, record.Attribute.Value)...)
+	}
+	s.mutex.Unlock()
+	s.notify(changes)
+	return response
+}
+
+func (s *Store) DiscoverAttributes(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.DiscoverAttributesCommand) *cluster.DiscoverAttributesResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	a := s.clusters[clusterKey{endpoint, clusterId}]
+	ids, complete := a.ids(cmd.StartAttributeID, cmd.MaximumAttributeIdentifiers)
+	response := &cluster.DiscoverAttributesResponse{DiscoveryComplete: complete}
+	for _, id := range ids {
+		response.AttributeInformations = append(response.AttributeInformations, &cluster.AttributeInformation{
+			AttributeID:       id,
+			AttributeDataType: a.values[id].DataType,
+		})
+	}
+	return response
+}
+
+func (s *Store) DiscoverAttributesExtended(endpoint uint8, clusterId cluster.ClusterId, cmd *cluster.DiscoverAttributesExtendedCommand) *cluster.DiscoverAttributesExtendedResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	a := s.clusters[clusterKey{endpoint, clusterId}]
+	ids, complete := a.ids(cmd.StartAttributeID, cmd.MaximumAttributeIdentifiers)
+	response := &cluster.DiscoverAttributesExtendedResponse{DiscoveryComplete: complete}
+	for _, id := range ids {
+		access := a.definition.AttributeDescriptors[id].Access
+		response.ExtendedAttributeInformations = append(response.ExtendedAttributeInformations, &cluster.ExtendedAttributeInformation{
+			AttributeID:       id,
+			AttributeDataType: a.values[id].DataType,
+			AttributeAccessControl: &cluster.AttributeAccessControl{
+				Readable:   frame.Flag(access&cluster.Read != 0),
+				Writeable:  frame.Flag(access&cluster.Write != 0),
+				Reportable: frame.Flag(access&cluster.Reportable != 0),
+			},
+		})
+	}
+	return response
+}
+
+// Register makes the dispatcher answer the global commands which read, write
+// and discover the attributes of a cluster on an endpoint from the store.
+func (s *Store) Register(d *Dispatcher, endpoint uint8, clusterId cluster.ClusterId) error {
+	handlers := []interface{}{
+		func(ctx *Context, cmd *cluster.ReadAttributesCommand) cluster.ZclStatus {
+			return respond(ctx, s.ReadAttributes(endpoint, clusterId, cmd))
+		},
+		func(ctx *Context, cmd *cluster.WriteAttributesCommand) cluster.ZclStatus {
+			return respond(ctx, s.WriteAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, false))
+		},
+		func(ctx *Context, cmd *cluster.WriteAttributesUndividedCommand) cluster.ZclStatus {
+			return respond(ctx, s.WriteAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, true))
+		},
+		func(ctx *Context, cmd *cluster.WriteAttributesNoResponseCommand) cluster.ZclStatus {
+			s.WriteAttributes(endpoint, clusterId, cmd.WriteAttributeRecords, false)
+			ctx.NoResponse()
+			return cluster.ZclStatusSuccess
+		},
+		func(ctx *Context, cmd *cluster.DiscoverAttributesCommand) cluster.ZclStatus {
+			return respond(ctx, s.DiscoverAttributes(endpoint, clusterId, cmd))
+		},
+		func(ctx *Context, cmd *cluster.DiscoverAttributesExtendedCommand) cluster.ZclStatus {
+			return respond(ctx, s.DiscoverAttributesExtended(endpoint, clusterId, cmd))
+		},
+	}
+	for _, handler := range handlers {
+		if err := d.Handle(endpoint, clusterId, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func respond(ctx *Context, response interface{}) cluster.ZclStatus {
+	if err := ctx.Respond(response); err != nil {
+		return cluster.ZclStatusFailure
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (s *Store) attribute(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (*cluster.Attribute, bool) {
+	a, ok := s.clusters[clusterKey{endpoint, clusterId}]
+	if !ok {
+		return nil, false
+	}
+	attribute, ok := a.values[attributeId]
+	return attribute, ok
+}
+
+// set changes a value, returning the change to notify if there is one. The
+// store must be locked.
+func (s *Store) set(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16, value interface{}) []*Change {
+	attribute, _ := s.attribute(endpoint, clusterId, attributeId)
+	if reflect.DeepEqual(attribute.Value, value) {
+		return nil
+	}
+	previous := attribute.Value
+	attribute.Value = value
+	copy := *attribute
+	return []*Change{{endpoint, clusterId, attributeId, previous, &copy}}
+}
+
+func (s *Store) notify(changes []*Change) {
+	if len(changes) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	listeners := s.listeners
+	s.mutex.Unlock()
+	for _, change := range changes {
+		for _, listener := range listeners {
+			listener(change)
+		}
+	}
+}
+
+// ids returns up to max supported attribute ids from start on, and whether
+// they are the last ones.
+func (a *attributes) ids(start uint16, max uint8) ([]uint16, uint8) {
+	if a == nil {
+		return nil, 1
+	}
+	var ids []uint16
+	for id := range a.values {
+		if id >= start {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > int(max) {
+		return ids[:max], 0
+	}
+	return ids, 1
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+type StoreSuite struct {
+	store   *Store
+	changes []*Change
+}
+
+var _ = Suite(&StoreSuite{})
+
+func (s *StoreSuite) SetUpTest(c *C) {
+	s.changes = nil
+	s.store = NewStore(cluster.New())
+	c.Assert(s.store.AddCluster(1, cluster.OnOff), IsNil)
+	c.Assert(s.store.AddCluster(1, cluster.LevelControl), IsNil)
+	s.store.Subscribe(func(change *Change) {
+		s.changes = append(s.changes, change)
+	})
+}
+
+func record(id uint16, dataType cluster.ZclDataType, value interface{}) *cluster.WriteAttributeRecord {
+	return &cluster.WriteAttributeRecord{AttributeID: id, Attribute: &cluster.Attribute{DataType: dataType, Value: value}}
+}
+
+func (s *StoreSuite) TestDefaults(c *C) {
+	attribute, ok := s.store.Attribute(1, cluster.OnOff, 0x4000)
+	c.Assert(ok, Equals, true)
+	c.Assert(attribute, DeepEquals, &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true})
+
+	attribute, ok = s.store.Attribute(1, cluster.LevelControl, 0x0014)
+	c.Assert(ok, Equals, true)
+	c.Assert(attribute, DeepEquals, &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(0)})
+
+	_, ok = s.store.Attribute(2, cluster.OnOff, 0x0000)
+	c.Assert(ok, Equals, false)
+	c.Assert(s.store.AddCluster(1, cluster.ClusterId(0xfc00)), ErrorMatches, "unknown cluster 0xfc00")
+}
+
+func (s *StoreSuite) TestReadAttributes(c *C) {
+	response := s.store.ReadAttributes(1, cluster.OnOff, &cluster.ReadAttributesCommand{AttributeIDs: []uint16{0x0000, 0x1234}})
+	c.Assert(response.ReadAttributeStatuses, HasLen, 2)
+	c.Assert(response.ReadAttributeStatuses[0].Status, Equals, cluster.ZclStatusSuccess)
+	c.Assert(response.ReadAttributeStatuses[0].Attribute.Value, Equals, false)
+	c.Assert(response.ReadAttributeStatuses[1].Status, Equals, cluster.ZclStatusUnsupportedAttribute)
+}
+
+func (s *StoreSuite) TestWriteAttributes(c *C) {
+	response := s.store.WriteAttributes(1, cluster.OnOff, []*cluster.WriteAttributeRecord{
+		record(0x4001, cluster.ZclDataTypeUint16, uint64(50)),
+		record(0x0000, cluster.ZclDataTypeBoolean, true),
+		record(0x4002, cluster.ZclDataTypeUint16, uint64(0x10000)),
+	}, false)
+	c.Assert(response.WriteAttributeStatuses, DeepEquals, []*cluster.WriteAttributeStatus{
+		{Status: cluster.ZclStatusReadOnly, AttributeID: 0x0000},
+		{Status: cluster.ZclStatusInvalidValue, AttributeID: 0x4002},
+	})
+	attribute, _ := s.store.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(attribute.Value, Equals, uint64(50))
+	c.Assert(s.changes, HasLen, 1)
+	c.Assert(s.changes[0].AttributeId, Equals, uint16(0x4001))
+	c.Assert(s.changes[0].Previous, Equals, uint64(0))
+
+	response = s.store.WriteAttributes(1, cluster.OnOff, []*cluster.WriteAttributeRecord{record(0x4001, cluster.ZclDataTypeUint16, uint64(50))}, false)
+	c.Assert(response.WriteAttributeStatuses, DeepEquals, []*cluster.WriteAttributeStatus{{Status: cluster.ZclStatusSuccess}})
+	c.Assert(s.changes, HasLen, 1)
+}
+
+func (s *StoreSuite) TestWriteAttributesUndivided(c *C) {
+	response := s.store.WriteAttributes(1, cluster.OnOff, []*cluster.WriteAttributeRecord{
+		record(0x4001, cluster.ZclDataTypeUint16, uint64(50)),
+		record(0x4002, cluster.ZclDataTypeBoolean, true),
+	}, true)
+	c.Assert(response.WriteAttributeStatuses, DeepEquals, []*cluster.WriteAttributeStatus{
+		{Status: cluster.ZclStatusInvalidDataType, AttributeID: 0x4002},
+	})
+	attribute, _ := s.store.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(attribute.Value, Equals, uint64(0))
+	c.Assert(s.changes, HasLen, 0)
+}
+
+func (s *StoreSuite) TestSet(c *C) {
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x0000, true), IsNil)
+	c.Assert(s.changes, HasLen, 1)
+	c.Assert(s.changes[0].Attribute.Value, Equals, true)
+
+	c.Assert(s.store.Set(1, cluster.LevelControl, 0x0000, uint64(0xff)), ErrorMatches, "invalid value 255 for CurrentLevel: status 0x87")
+	c.Assert(s.store.Set(1, cluster.OnOff, 0x1234, true), ErrorMatches, "unsupported attribute 0x1234 of cluster 0x0006 on endpoint 1")
+}
+
+func (s *StoreSuite) TestReportable(c *C) {
+	c.Assert(s.store.Reportable(1, cluster.OnOff, 0x0000), Equals, cluster.ZclStatusSuccess)
+	c.Assert(s.store.Reportable(1, cluster.OnOff, 0x4001), Equals, cluster.ZclStatusUnreportableAttribute)
+	c.Assert(s.store.Reportable(1, cluster.OnOff, 0x1234), Equals, cluster.ZclStatusUnsupportedAttribute)
+}
+
+func (s *StoreSuite) TestDiscoverAttributesExtended(c *C) {
+	response := s.store.DiscoverAttributesExtended(1, cluster.OnOff, &cluster.DiscoverAttributesExtendedCommand{MaximumAttributeIdentifiers: 1})
+	c.Assert(response.DiscoveryComplete, Equals, uint8(0))
+	c.Assert(response.ExtendedAttributeInformations, HasLen, 1)
+	information := response.ExtendedAttributeInformations[0]
+	c.Assert(information.AttributeID, Equals, uint16(0x0000))
+	c.Assert(information.AttributeAccessControl, DeepEquals, &cluster.AttributeAccessControl{Readable: 1, Reportable: 1})
+}
+
+func (s *StoreSuite) TestRegister(c *C) {
+	var frames []*frame.Frame
+	d := New(cluster.New(), func(source uint8, address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		frames = append(frames, f)
+		return nil
+	})
+	c.Assert(s.store.Register(d, 1, cluster.OnOff), IsNil)
+
+	handled, err := d.Dispatch(message(c, cluster.OnOff, 0x00, 0x42, 0x02, 0x01, 0x40, uint8(cluster.ZclDataTypeUint16), 0x0a, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(handled, Equals, true)
+	c.Assert(frames, HasLen, 1)
+	c.Assert(frames[0].CommandIdentifier, Equals, uint8(cluster.ZclCommandWriteAttributesResponse))
+	c.Assert(frames[0].Payload, DeepEquals, []uint8{uint8(cluster.ZclStatusSuccess)})
+	attribute, _ := s.store.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(attribute.Value, Equals, uint64(10))
+
+	_, err = d.Dispatch(message(c, cluster.OnOff, 0x00, 0x43, 0x05, 0x01, 0x40, uint8(cluster.ZclDataTypeUint16), 0x0b, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(frames, HasLen, 1)
+	attribute, _ = s.store.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(attribute.Value, Equals, uint64(11))
+}
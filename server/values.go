@@ -0,0 +1,71 @@
+package server
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+// zeroValue returns the initial value of an attribute without default, in the
+// Go type Attribute uses for dataType.
+func zeroValue(dataType cluster.ZclDataType) (interface{}, bool) {
+	switch dataType {
+	case cluster.ZclDataTypeBoolean:
+		return false, true
+	case cluster.ZclDataTypeBitmap8, cluster.ZclDataTypeBitmap16, cluster.ZclDataTypeBitmap24, cluster.ZclDataTypeBitmap32,
+		cluster.ZclDataTypeBitmap40, cluster.ZclDataTypeBitmap48, cluster.ZclDataTypeBitmap56, cluster.ZclDataTypeBitmap64,
+		cluster.ZclDataTypeUint8, cluster.ZclDataTypeUint16, cluster.ZclDataTypeUint24, cluster.ZclDataTypeUint32,
+		cluster.ZclDataTypeUint40, cluster.ZclDataTypeUint48, cluster.ZclDataTypeUint56, cluster.ZclDataTypeUint64,
+		cluster.ZclDataTypeEnum8, cluster.ZclDataTypeEnum16:
+		return uint64(0), true
+	case cluster.ZclDataTypeInt8, cluster.ZclDataTypeInt16, cluster.ZclDataTypeInt24, cluster.ZclDataTypeInt32,
+		cluster.ZclDataTypeInt40, cluster.ZclDataTypeInt48, cluster.ZclDataTypeInt56, cluster.ZclDataTypeInt64:
+		return int64(0), true
+	case cluster.ZclDataTypeSemiPrec, cluster.ZclDataTypeSinglePrec:
+		return float32(0), true
+	case cluster.ZclDataTypeDoublePrec:
+		return float64(0), true
+	case cluster.ZclDataTypeOctetStr, cluster.ZclDataTypeCharStr, cluster.ZclDataTypeLongOctetStr, cluster.ZclDataTypeLongCharStr:
+		return "", true
+	case cluster.ZclDataTypeArray, cluster.ZclDataTypeSet, cluster.ZclDataTypeBag:
+		return []*cluster.Attribute{}, true
+	case cluster.ZclDataTypeTod:
+		return &cluster.TimeOfDay{}, true
+	case cluster.ZclDataTypeDate:
+		return &cluster.Date{}, true
+	case cluster.ZclDataTypeUtc, cluster.ZclDataTypeBacOid:
+		return uint32(0), true
+	case cluster.ZclDataTypeClusterId, cluster.ZclDataTypeAttrId:
+		return uint16(0), true
+	case cluster.ZclDataTypeIeeeAddr:
+		return "0x0000000000000000", true
+	}
+	return nil, false
+}
+
+// analog reports whether attributes of dataType have a reportable change.
+func analog(dataType cluster.ZclDataType) bool {
+	switch {
+	case dataType >= cluster.ZclDataTypeUint8 && dataType <= cluster.ZclDataTypeDoublePrec:
+		return true
+	case dataType == cluster.ZclDataTypeTod || dataType == cluster.ZclDataTypeDate || dataType == cluster.ZclDataTypeUtc:
+		return true
+	}
+	return false
+}
+
+// ToFloat returns a numeric attribute value as a float64, false if the value
+// isn't a number.
+func ToFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case uint64:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,293 @@
+package simulator
+
+import (
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/server"
+)
+
+const (
+	onOffAttribute        uint16 = 0x0000
+	onTimeAttribute       uint16 = 0x4001
+	offWaitTimeAttribute  uint16 = 0x4002
+	currentLevelAttribute uint16 = 0x0000
+	identifyTimeAttribute uint16 = 0x0000
+)
+
+const (
+	moveUp           uint8 = 0x00
+	acceptOnlyWhenOn uint8 = 0x01
+)
+
+// tick is the unit of OnTime and OffWaitTime.
+const tick = 100 * time.Millisecond
+
+// handlers execute the cluster specific commands a server receives.
+func (s *clusterServer) handlers(d *Device) []interface{} {
+	switch s.clusterId {
+	case cluster.OnOff:
+		return []interface{}{
+			func(ctx *server.Context, cmd *cluster.OffCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.OnCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.ToggleCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.OffWithEffectCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.OnWithRecallGlobalSceneCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.OnWithTimedOffCommand) cluster.ZclStatus {
+				return s.onOff(d, cmd)
+			},
+		}
+	case cluster.LevelControl:
+		return []interface{}{
+			func(ctx *server.Context, cmd *cluster.MoveToLevelCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.MoveCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.StepCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.StopCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.MoveToLevelOnOffCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.MoveOnOffCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.StepOnOffCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+			func(ctx *server.Context, cmd *cluster.StopOnOffCommand) cluster.ZclStatus {
+				return s.levelControl(d, cmd)
+			},
+		}
+	case cluster.Identify:
+		return []interface{}{
+			func(ctx *server.Context, cmd *cluster.IdentifyCommand) cluster.ZclStatus {
+				s.setIdentifyTime(d, int64(cmd.IdentifyTime))
+				return cluster.ZclStatusSuccess
+			},
+			func(ctx *server.Context, cmd *cluster.IdentifyQueryCommand) cluster.ZclStatus {
+				timeout := s.integer(identifyTimeAttribute)
+				if timeout == 0 {
+					// only identifying devices respond
+					ctx.NoResponse()
+					return cluster.ZclStatusSuccess
+				}
+				ctx.Respond(&cluster.IdentifyQueryResponse{Timeout: uint16(timeout)})
+				return cluster.ZclStatusSuccess
+			},
+			func(ctx *server.Context, cmd *cluster.TriggerEffectCommand) cluster.ZclStatus {
+				return cluster.ZclStatusSuccess
+			},
+		}
+	}
+	return nil
+}
+
+func (s *clusterServer) onOff(d *Device, command interface{}) cluster.ZclStatus {
+	key := timerKey(s, "timed off")
+	switch cmd := command.(type) {
+	case *cluster.OffCommand, *cluster.OffWithEffectCommand:
+		d.cancel(key)
+		d.set(s, onTimeAttribute, uint64(0))
+		d.set(s, onOffAttribute, false)
+	case *cluster.OnCommand, *cluster.OnWithRecallGlobalSceneCommand:
+		d.set(s, onOffAttribute, true)
+	case *cluster.ToggleCommand:
+		if s.bool(onOffAttribute) {
+			d.cancel(key)
+			d.set(s, onTimeAttribute, uint64(0))
+			d.set(s, onOffAttribute, false)
+		} else {
+			d.set(s, onOffAttribute, true)
+		}
+	case *cluster.OnWithTimedOffCommand:
+		on := s.bool(onOffAttribute)
+		if cmd.OnOffControl&acceptOnlyWhenOn != 0 && !on {
+			return cluster.ZclStatusSuccess
+		}
+		offWaitTime := s.integer(offWaitTimeAttribute)
+		if !on && offWaitTime > 0 {
+			if uint64(cmd.OffWaitTime) < offWaitTime {
+				d.set(s, offWaitTimeAttribute, uint64(cmd.OffWaitTime))
+			}
+		} else {
+			onTime := s.integer(onTimeAttribute)
+			if uint64(cmd.OnTime) > onTime {
+				onTime = uint64(cmd.OnTime)
+			}
+			d.set(s, onTimeAttribute, onTime)
+			d.set(s, offWaitTimeAttribute, uint64(cmd.OffWaitTime))
+			d.set(s, onOffAttribute, true)
+		}
+		if cmd.OnTime < 0xffff && cmd.OffWaitTime < 0xffff {
+			d.after(key, tick, func() { s.timedOff(d) })
+		}
+	}
+	return cluster.ZclStatusSuccess
+}
+
+// timedOff counts OnTime down to switching off, then OffWaitTime, one tick at
+// a time.
+func (s *clusterServer) timedOff(d *Device) {
+	onTime, offWaitTime := s.integer(onTimeAttribute), s.integer(offWaitTimeAttribute)
+	switch {
+	case s.bool(onOffAttribute) && onTime > 0:
+		d.set(s, onTimeAttribute, onTime-1)
+		if onTime == 1 {
+			d.set(s, onOffAttribute, false)
+		}
+	case !s.bool(onOffAttribute) && offWaitTime > 0:
+		d.set(s, offWaitTimeAttribute, offWaitTime-1)
+	default:
+		return
+	}
+	d.after(timerKey(s, "timed off"), tick, func() { s.timedOff(d) })
+}
+
+// levelControl applies transitions at once, except moves which step the level
+// at their rate until Stop or a bound.
+func (s *clusterServer) levelControl(d *Device, command interface{}) cluster.ZclStatus {
+	key := timerKey(s, "move")
+	switch cmd := command.(type) {
+	case *cluster.MoveToLevelCommand:
+		d.cancel(key)
+		s.setLevel(d, int64(cmd.Level), false)
+	case *cluster.MoveToLevelOnOffCommand:
+		d.cancel(key)
+		s.setLevel(d, int64(cmd.Level), true)
+	case *cluster.StepCommand:
+		d.cancel(key)
+		s.step(d, cmd.StepMode, cmd.StepSize, false)
+	case *cluster.StepOnOffCommand:
+		d.cancel(key)
+		s.step(d, cmd.StepMode, cmd.StepSize, true)
+	case *cluster.MoveCommand:
+		return s.move(d, cmd.MoveMode, cmd.Rate, false)
+	case *cluster.MoveOnOffCommand:
+		return s.move(d, cmd.MoveMode, cmd.Rate, true)
+	case *cluster.StopCommand, *cluster.StopOnOffCommand:
+		d.cancel(key)
+	}
+	return cluster.ZclStatusSuccess
+}
+
+func (s *clusterServer) step(d *Device, mode uint8, size uint8, withOnOff bool) {
+	level := int64(s.integer(currentLevelAttribute))
+	if mode == moveUp {
+		s.setLevel(d, level+int64(size), withOnOff)
+	} else {
+		s.setLevel(d, level-int64(size), withOnOff)
+	}
+}
+
+func (s *clusterServer) move(d *Device, mode uint8, rate uint8, withOnOff bool) cluster.ZclStatus {
+	if rate == 0 {
+		return cluster.ZclStatusInvalidField
+	}
+	var delta int64 = 1
+	if mode != moveUp {
+		delta = -1
+	}
+	interval := time.Second / time.Duration(rate)
+	var step func()
+	step = func() {
+		level := int64(s.integer(currentLevelAttribute))
+		s.setLevel(d, level+delta, withOnOff)
+		if next := int64(s.integer(currentLevelAttribute)); next != level+delta || next == s.levelBound(delta) {
+			return
+		}
+		d.after(timerKey(s, "move"), interval, step)
+	}
+	d.after(timerKey(s, "move"), interval, step)
+	return cluster.ZclStatusSuccess
+}
+
+// setLevel sets CurrentLevel within its bounds and, for the commands with
+// OnOff, switches the OnOff server of the endpoint on above the minimum level
+// and off at it.
+func (s *clusterServer) setLevel(d *Device, level int64, withOnOff bool) {
+	if min := s.levelBound(-1); level < min {
+		level = min
+	}
+	if max := s.levelBound(1); level > max {
+		level = max
+	}
+	d.set(s, currentLevelAttribute, uint64(level))
+	if onOff, ok := d.endpoints[s.endpoint][cluster.OnOff]; ok && withOnOff {
+		d.set(onOff, onOffAttribute, level > s.levelBound(-1))
+	}
+}
+
+// levelBound returns the maximum CurrentLevel for a positive direction and
+// the minimum one otherwise.
+func (s *clusterServer) levelBound(direction int64) int64 {
+	ad := s.definition.AttributeDescriptors[currentLevelAttribute]
+	if direction > 0 {
+		if max, ok := server.ToFloat(ad.Max); ok {
+			return int64(max)
+		}
+		return 0xfe
+	}
+	if min, ok := server.ToFloat(ad.Min); ok {
+		return int64(min)
+	}
+	return 0
+}
+
+// setIdentifyTime sets IdentifyTime and counts it down every second.
+func (s *clusterServer) setIdentifyTime(d *Device, timeout int64) {
+	key := timerKey(s, "identify")
+	d.set(s, identifyTimeAttribute, number(s.dataType(identifyTimeAttribute), timeout))
+	if timeout <= 0 {
+		d.cancel(key)
+		return
+	}
+	d.after(key, time.Second, func() {
+		s.setIdentifyTime(d, int64(s.integer(identifyTimeAttribute))-1)
+	})
+}
+
+func (s *clusterServer) bool(attributeId uint16) bool {
+	if attribute, ok := s.attribute(attributeId); ok {
+		b, _ := attribute.Value.(bool)
+		return b
+	}
+	return false
+}
+
+// integer returns the value of an integer attribute, or 0 if it's negative or
+// unsupported.
+func (s *clusterServer) integer(attributeId uint16) uint64 {
+	if attribute, ok := s.attribute(attributeId); ok {
+		if v, ok := server.ToFloat(attribute.Value); ok && v > 0 {
+			return uint64(v)
+		}
+	}
+	return 0
+}
+
+func (s *clusterServer) dataType(attributeId uint16) cluster.ZclDataType {
+	if attribute, ok := s.attribute(attributeId); ok {
+		return attribute.DataType
+	}
+	return cluster.ZclDataTypeNoData
+}
+
+func (s *clusterServer) attribute(attributeId uint16) (*cluster.Attribute, bool) {
+	return s.store.Attribute(s.endpoint, s.clusterId, attributeId)
+}
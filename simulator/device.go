@@ -0,0 +1,245 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/server"
+	"github.com/dyrkin/znp-go"
+)
+
+// Device is a simulated device with server clusters on its endpoints.
+type Device struct {
+	address    string
+	transport  *Transport
+	mutex      sync.Mutex
+	endpoints  map[uint8]map[cluster.ClusterId]*clusterServer
+	dispatcher *server.Dispatcher
+	store      *server.Store
+	reporter   *server.Reporter
+	timers     map[string]*time.Timer
+	sequence   uint8
+	// responses holds the frames answering the command being dispatched and
+	// changes the reports attribute changes triggered, until they're sent.
+	responses []*outgoing
+	changes   []*outgoing
+}
+
+// clusterServer is a server cluster on an endpoint, with its attributes in
+// the store of the device.
+type clusterServer struct {
+	endpoint   uint8
+	clusterId  cluster.ClusterId
+	definition *cluster.Cluster
+	store      *server.Store
+}
+
+// outgoing is a frame a device sends to the client.
+type outgoing struct {
+	endpoint  uint8
+	clusterId cluster.ClusterId
+	frame     *frame.Frame
+}
+
+func newDevice(address string, transport *Transport) *Device {
+	d := &Device{
+		address:   address,
+		transport: transport,
+		endpoints: map[uint8]map[cluster.ClusterId]*clusterServer{},
+		store:     server.NewStore(transport.library),
+		timers:    map[string]*time.Timer{},
+	}
+	// commands are dispatched with the device locked
+	d.dispatcher = server.New(transport.library, func(source uint8, address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+		d.responses = append(d.responses, &outgoing{source, clusterId, f})
+		return nil
+	})
+	// the reporter only sends with the device locked: attributes change with
+	// it locked and its clock runs timers with it locked
+	d.reporter = server.NewReporter(d.store, deviceClock{d}, func(endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) {
+		d.sequence++
+		f.TransactionSequenceNumber = d.sequence
+		d.changes = append(d.changes, &outgoing{endpoint, clusterId, f})
+	})
+	return d
+}
+
+func (d *Device) Address() string {
+	return d.address
+}
+
+// AddEndpoint adds server clusters to an endpoint. They support every
+// attribute their library definition has, with its default value or the zero
+// value of its type.
+func (d *Device) AddEndpoint(endpoint uint8, clusterIds ...cluster.ClusterId) error {
+	if endpoint == 0 || endpoint == server.BroadcastEndpoint {
+		return fmt.Errorf("invalid endpoint %d", endpoint)
+	}
+	servers := map[cluster.ClusterId]*clusterServer{}
+	for _, clusterId := range clusterIds {
+		candidates := d.transport.library.Candidates(clusterId, 0)
+		if len(candidates) == 0 {
+			return fmt.Errorf("unknown cluster 0x%04x", uint16(clusterId))
+		}
+		servers[clusterId] = &clusterServer{
+			endpoint:   endpoint,
+			clusterId:  clusterId,
+			definition: candidates[0],
+			store:      d.store,
+		}
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.endpoints[endpoint] == nil {
+		d.endpoints[endpoint] = map[cluster.ClusterId]*clusterServer{}
+	}
+	for clusterId, s := range servers {
+		if err := d.store.AddCluster(endpoint, clusterId); err != nil {
+			return err
+		}
+		if err := d.register(s); err != nil {
+			return err
+		}
+		d.endpoints[endpoint][clusterId] = s
+	}
+	return nil
+}
+
+// register makes the dispatcher execute the commands a cluster server
+// receives.
+func (d *Device) register(s *clusterServer) error {
+	if err := d.store.Register(d.dispatcher, s.endpoint, s.clusterId); err != nil {
+		return err
+	}
+	if err := d.reporter.Register(d.dispatcher, s.endpoint, s.clusterId); err != nil {
+		return err
+	}
+	for _, handler := range append(s.discoverHandlers(), s.handlers(d)...) {
+		if err := d.dispatcher.Handle(s.endpoint, s.clusterId, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Attribute returns the value of an attribute.
+func (d *Device) Attribute(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16) (*cluster.Attribute, bool) {
+	return d.store.Attribute(endpoint, clusterId, attributeId)
+}
+
+// SetAttribute changes an attribute as the device itself would, e.g. a
+// sensor measuring a new value, regardless of its access. Configured reports
+// are sent for the change.
+func (d *Device) SetAttribute(endpoint uint8, clusterId cluster.ClusterId, attributeId uint16, value interface{}) error {
+	d.mutex.Lock()
+	err := d.store.Set(endpoint, clusterId, attributeId, value)
+	out := d.takeChanges()
+	d.mutex.Unlock()
+	d.send(out)
+	return err
+}
+
+// Close stops the timers of the device: reports, identification and
+// transitions.
+func (d *Device) Close() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.reporter.Close()
+	for key, timer := range d.timers {
+		timer.Stop()
+		delete(d.timers, key)
+	}
+}
+
+func (d *Device) receive(endpoint uint8, clusterId cluster.ClusterId, data []uint8) {
+	// the dispatcher answers frames which don't decode from their header
+	message, _ := d.transport.zcl.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:   uint16(clusterId),
+		SrcAddr:     ClientAddress,
+		SrcEndpoint: ClientEndpoint,
+		DstEndpoint: endpoint,
+		Data:        data,
+	})
+	if message.Data == nil {
+		// too short to have a transaction sequence number to answer
+		return
+	}
+	d.mutex.Lock()
+	d.dispatcher.Dispatch(message)
+	out := append(d.responses, d.takeChanges()...)
+	d.responses = nil
+	d.mutex.Unlock()
+	d.send(out)
+}
+
+func (d *Device) send(out []*outgoing) {
+	for _, o := range out {
+		d.transport.deliver(d, o)
+	}
+}
+
+// set changes an attribute. The device must be locked, the reports the change
+// triggers are sent once it's unlocked.
+func (d *Device) set(s *clusterServer, attributeId uint16, value interface{}) {
+	s.store.Set(s.endpoint, s.clusterId, attributeId, value)
+}
+
+func (d *Device) takeChanges() []*outgoing {
+	changes := d.changes
+	d.changes = nil
+	return changes
+}
+
+// after calls f with the device locked once delay has elapsed, replacing the
+// pending call with the same key, and sends the reports it triggers.
+func (d *Device) after(key string, delay time.Duration, f func()) {
+	d.cancel(key)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.mutex.Lock()
+		if d.timers[key] != timer {
+			d.mutex.Unlock()
+			return
+		}
+		delete(d.timers, key)
+		f()
+		out := d.takeChanges()
+		d.mutex.Unlock()
+		d.send(out)
+	})
+	d.timers[key] = timer
+}
+
+func (d *Device) cancel(key string) {
+	if timer, ok := d.timers[key]; ok {
+		timer.Stop()
+		delete(d.timers, key)
+	}
+}
+
+func timerKey(s *clusterServer, name string) string {
+	return fmt.Sprintf("%d/0x%04x/%s", s.endpoint, uint16(s.clusterId), name)
+}
+
+// deviceClock runs the timers of the reporter with the device locked, then
+// sends the reports.
+type deviceClock struct {
+	d *Device
+}
+
+func (c deviceClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c deviceClock) AfterFunc(delay time.Duration, f func()) server.Timer {
+	return time.AfterFunc(delay, func() {
+		c.d.mutex.Lock()
+		f()
+		out := c.d.takeChanges()
+		c.d.mutex.Unlock()
+		c.d.send(out)
+	})
+}
@@ -0,0 +1,58 @@
+package simulator
+
+import (
+	"sort"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/zcl-go/server"
+)
+
+// discoverHandlers answer the discovery of the commands a server receives
+// and generates, which are the ones its library definition has.
+func (s *clusterServer) discoverHandlers() []interface{} {
+	return []interface{}{
+		func(ctx *server.Context, cmd *cluster.DiscoverCommandsReceivedCommand) cluster.ZclStatus {
+			ids, complete := discoverCommands(s.commands(frame.DirectionClientServer), cmd.StartCommandID, cmd.MaximumCommandIdentifiers)
+			ctx.Respond(&cluster.DiscoverCommandsReceivedResponse{
+				DiscoveryComplete:  complete,
+				CommandIdentifiers: ids,
+			})
+			return cluster.ZclStatusSuccess
+		},
+		func(ctx *server.Context, cmd *cluster.DiscoverCommandsGeneratedCommand) cluster.ZclStatus {
+			ids, complete := discoverCommands(s.commands(frame.DirectionServerClient), cmd.StartCommandID, cmd.MaximumCommandIdentifiers)
+			ctx.Respond(&cluster.DiscoverCommandsGeneratedResponse{
+				DiscoveryComplete:  complete,
+				CommandIdentifiers: ids,
+			})
+			return cluster.ZclStatusSuccess
+		},
+	}
+}
+
+func (s *clusterServer) commands(direction frame.Direction) map[uint8]*cluster.CommandDescriptor {
+	if s.definition.CommandDescriptors == nil {
+		return nil
+	}
+	if direction == frame.DirectionServerClient {
+		return s.definition.CommandDescriptors.Generated
+	}
+	return s.definition.CommandDescriptors.Received
+}
+
+// discoverCommands returns up to max command ids from start on, and whether
+// they are the last ones.
+func discoverCommands(commands map[uint8]*cluster.CommandDescriptor, start uint8, max uint8) ([]uint8, uint8) {
+	ids := []uint8{}
+	for id := range commands {
+		if id >= start {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > int(max) {
+		return ids[:max], 0
+	}
+	return ids, 1
+}
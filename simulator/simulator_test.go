@@ -0,0 +1,273 @@
+package simulator
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	. "gopkg.in/check.v1"
+)
+
+func TestSimulator(t *testing.T) { TestingT(t) }
+
+type SimulatorSuite struct {
+	transport *Transport
+	device    *Device
+	mutex     sync.Mutex
+	messages  []*zcl.ZclIncomingMessage
+}
+
+var _ = Suite(&SimulatorSuite{})
+
+func (s *SimulatorSuite) SetUpTest(c *C) {
+	s.messages = nil
+	s.transport = NewTransport(cluster.New(), func(message *zcl.ZclIncomingMessage) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.messages = append(s.messages, message)
+	})
+	s.device = s.transport.AddDevice("0x1234")
+	c.Assert(s.device.AddEndpoint(1, cluster.Identify, cluster.OnOff, cluster.LevelControl), IsNil)
+}
+
+func (s *SimulatorSuite) TearDownTest(c *C) {
+	s.transport.Close()
+}
+
+func (s *SimulatorSuite) received() []*zcl.ZclIncomingMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	messages := s.messages
+	s.messages = nil
+	return messages
+}
+
+func (s *SimulatorSuite) send(c *C, endpoint uint8, clusterId cluster.ClusterId, frameType frame.FrameType, commandId uint8, command interface{}) []*zcl.ZclIncomingMessage {
+	f, err := frame.New().
+		FrameType(frameType).
+		Direction(frame.DirectionClientServer).
+		CommandId(commandId).
+		Command(command).
+		Build()
+	c.Assert(err, IsNil)
+	f.TransactionSequenceNumber = 0x42
+	c.Assert(s.transport.Send("0x1234", endpoint, clusterId, f), IsNil)
+	return s.received()
+}
+
+func (s *SimulatorSuite) global(c *C, clusterId cluster.ClusterId, commandId cluster.ZclCommand, command interface{}) []*zcl.ZclIncomingMessage {
+	return s.send(c, 1, clusterId, frame.FrameTypeGlobal, uint8(commandId), command)
+}
+
+func (s *SimulatorSuite) TestReadAttributes(c *C) {
+	messages := s.global(c, cluster.OnOff, cluster.ZclCommandReadAttributes, &cluster.ReadAttributesCommand{AttributeIDs: []uint16{0x0000, 0x1234}})
+	c.Assert(messages, HasLen, 1)
+	m := messages[0]
+	c.Assert(m.SrcAddr, Equals, "0x1234")
+	c.Assert(m.SrcEndpoint, Equals, uint8(1))
+	c.Assert(m.Data.TransactionSequenceNumber, Equals, uint8(0x42))
+	c.Assert(m.Data.FrameControl.Direction, Equals, frame.DirectionServerClient)
+	response := m.Data.Command.(*cluster.ReadAttributesResponse)
+	c.Assert(response.ReadAttributeStatuses, HasLen, 2)
+	c.Assert(response.ReadAttributeStatuses[0].Status, Equals, cluster.ZclStatusSuccess)
+	c.Assert(response.ReadAttributeStatuses[0].Attribute.Value, Equals, false)
+	c.Assert(response.ReadAttributeStatuses[1].Status, Equals, cluster.ZclStatusUnsupportedAttribute)
+}
+
+func (s *SimulatorSuite) TestWriteAttributes(c *C) {
+	messages := s.global(c, cluster.OnOff, cluster.ZclCommandWriteAttributes, &cluster.WriteAttributesCommand{WriteAttributeRecords: []*cluster.WriteAttributeRecord{
+		{AttributeID: 0x4001, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(50)}},
+		{AttributeID: 0x0000, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true}},
+	}})
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.WriteAttributesResponse)
+	c.Assert(response.WriteAttributeStatuses, HasLen, 1)
+	c.Assert(response.WriteAttributeStatuses[0].Status, Equals, cluster.ZclStatusReadOnly)
+	c.Assert(response.WriteAttributeStatuses[0].AttributeID, Equals, uint16(0x0000))
+
+	attribute, ok := s.device.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(ok, Equals, true)
+	c.Assert(attribute.Value, Equals, uint64(50))
+}
+
+func (s *SimulatorSuite) TestWriteAttributesUndivided(c *C) {
+	messages := s.global(c, cluster.OnOff, cluster.ZclCommandWriteAttributesUndivided, &cluster.WriteAttributesUndividedCommand{WriteAttributeRecords: []*cluster.WriteAttributeRecord{
+		{AttributeID: 0x4001, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(50)}},
+		{AttributeID: 0x4002, Attribute: &cluster.Attribute{DataType: cluster.ZclDataTypeBoolean, Value: true}},
+	}})
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.WriteAttributesResponse)
+	c.Assert(response.WriteAttributeStatuses, HasLen, 1)
+	c.Assert(response.WriteAttributeStatuses[0].Status, Equals, cluster.ZclStatusInvalidDataType)
+
+	attribute, _ := s.device.Attribute(1, cluster.OnOff, 0x4001)
+	c.Assert(attribute.Value, Equals, uint64(0))
+}
+
+func (s *SimulatorSuite) TestDiscoverAttributes(c *C) {
+	messages := s.global(c, cluster.OnOff, cluster.ZclCommandDiscoverAttributes, &cluster.DiscoverAttributesCommand{StartAttributeID: 0x0001, MaximumAttributeIdentifiers: 2})
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.DiscoverAttributesResponse)
+	c.Assert(response.DiscoveryComplete, Equals, uint8(0))
+	c.Assert(response.AttributeInformations, HasLen, 2)
+	c.Assert(response.AttributeInformations[0].AttributeID, Equals, uint16(0x4000))
+	c.Assert(response.AttributeInformations[1].AttributeID, Equals, uint16(0x4001))
+	c.Assert(response.AttributeInformations[1].AttributeDataType, Equals, cluster.ZclDataTypeUint16)
+}
+
+func (s *SimulatorSuite) TestDiscoverCommandsReceived(c *C) {
+	messages := s.global(c, cluster.Identify, cluster.ZclCommandDiscoverCommandsReceived, &cluster.DiscoverCommandsReceivedCommand{MaximumCommandIdentifiers: 10})
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.DiscoverCommandsReceivedResponse)
+	c.Assert(response.DiscoveryComplete, Equals, uint8(1))
+	c.Assert(response.CommandIdentifiers, DeepEquals, []uint8{0x00, 0x01, 0x40})
+}
+
+func (s *SimulatorSuite) TestOnOff(c *C) {
+	messages := s.send(c, 1, cluster.OnOff, frame.FrameTypeLocal, 0x01, &cluster.OnCommand{})
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].Data.CommandName, Equals, "DefaultResponse")
+	response := messages[0].Data.Command.(*cluster.DefaultResponseCommand)
+	c.Assert(response.CommandID, Equals, uint8(0x01))
+	c.Assert(response.Status, Equals, cluster.ZclStatusSuccess)
+	attribute, _ := s.device.Attribute(1, cluster.OnOff, 0x0000)
+	c.Assert(attribute.Value, Equals, true)
+
+	s.send(c, 1, cluster.OnOff, frame.FrameTypeLocal, 0x02, &cluster.ToggleCommand{})
+	attribute, _ = s.device.Attribute(1, cluster.OnOff, 0x0000)
+	c.Assert(attribute.Value, Equals, false)
+}
+
+func (s *SimulatorSuite) TestUnsupportedCluster(c *C) {
+	messages := s.send(c, 1, cluster.Basic, frame.FrameTypeLocal, 0x00, nil)
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.DefaultResponseCommand)
+	c.Assert(response.Status, Equals, cluster.ZclStatusUnsupportedCluster)
+}
+
+func (s *SimulatorSuite) TestUnsupportedCommand(c *C) {
+	messages := s.send(c, 1, cluster.OnOff, frame.FrameTypeLocal, 0x30, nil)
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.DefaultResponseCommand)
+	c.Assert(response.Status, Equals, cluster.ZclStatusUnsupClusterCommand)
+}
+
+func (s *SimulatorSuite) TestMoveToLevelWithOnOff(c *C) {
+	s.send(c, 1, cluster.LevelControl, frame.FrameTypeLocal, 0x04, &cluster.MoveToLevelOnOffCommand{Level: 0xff})
+	level, _ := s.device.Attribute(1, cluster.LevelControl, 0x0000)
+	c.Assert(level.Value, Equals, uint64(0xfe))
+	on, _ := s.device.Attribute(1, cluster.OnOff, 0x0000)
+	c.Assert(on.Value, Equals, true)
+
+	s.send(c, 1, cluster.LevelControl, frame.FrameTypeLocal, 0x06, &cluster.StepOnOffCommand{StepMode: 0x01, StepSize: 0xff})
+	level, _ = s.device.Attribute(1, cluster.LevelControl, 0x0000)
+	c.Assert(level.Value, Equals, uint64(0))
+	on, _ = s.device.Attribute(1, cluster.OnOff, 0x0000)
+	c.Assert(on.Value, Equals, false)
+}
+
+func (s *SimulatorSuite) TestIdentifyQuery(c *C) {
+	messages := s.send(c, 1, cluster.Identify, frame.FrameTypeLocal, 0x01, &cluster.IdentifyQueryCommand{})
+	c.Assert(messages, HasLen, 0)
+
+	s.send(c, 1, cluster.Identify, frame.FrameTypeLocal, 0x00, &cluster.IdentifyCommand{IdentifyTime: 10})
+	messages = s.send(c, 1, cluster.Identify, frame.FrameTypeLocal, 0x01, &cluster.IdentifyQueryCommand{})
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].Data.CommandIdentifier, Equals, uint8(0x00))
+	c.Assert(messages[0].Data.Command.(*cluster.IdentifyQueryResponse).Timeout, Equals, uint16(10))
+}
+
+func (s *SimulatorSuite) TestConfigureReporting(c *C) {
+	messages := s.global(c, cluster.LevelControl, cluster.ZclCommandConfigureReporting, &cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{
+		{
+			AttributeID:              0x0000,
+			AttributeDataType:        cluster.ZclDataTypeUint8,
+			MaximumReportingInterval: 300,
+			ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeUint8, Value: uint64(10)},
+		},
+		{
+			AttributeID:              0x0001,
+			AttributeDataType:        cluster.ZclDataTypeUint16,
+			MaximumReportingInterval: 300,
+			ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeUint16, Value: uint64(1)},
+		},
+	}})
+	c.Assert(messages, HasLen, 1)
+	response := messages[0].Data.Command.(*cluster.ConfigureReportingResponse)
+	c.Assert(response.AttributeStatusRecords, HasLen, 1)
+	c.Assert(response.AttributeStatusRecords[0].Status, Equals, cluster.ZclStatusUnreportableAttribute)
+	c.Assert(response.AttributeStatusRecords[0].AttributeID, Equals, uint16(0x0001))
+
+	c.Assert(s.device.SetAttribute(1, cluster.LevelControl, 0x0000, uint64(5)), IsNil)
+	c.Assert(s.received(), HasLen, 0)
+
+	c.Assert(s.device.SetAttribute(1, cluster.LevelControl, 0x0000, uint64(20)), IsNil)
+	messages = s.received()
+	c.Assert(messages, HasLen, 1)
+	c.Assert(messages[0].Data.CommandName, Equals, "ReportAttributes")
+	report := messages[0].Data.Command.(*cluster.ReportAttributesCommand)
+	c.Assert(report.AttributeReports, HasLen, 1)
+	c.Assert(report.AttributeReports[0].AttributeID, Equals, uint16(0x0000))
+	c.Assert(report.AttributeReports[0].Attribute.Value, Equals, uint64(20))
+}
+
+func (s *SimulatorSuite) TestMinimumReportingInterval(c *C) {
+	s.global(c, cluster.OnOff, cluster.ZclCommandConfigureReporting, &cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{
+		{
+			AttributeID:              0x0000,
+			AttributeDataType:        cluster.ZclDataTypeBoolean,
+			MinimumReportingInterval: 1,
+			ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeNoData},
+		},
+	}})
+	s.send(c, 1, cluster.OnOff, frame.FrameTypeLocal, 0x01, &cluster.OnCommand{})
+
+	deadline := time.Now().Add(3 * time.Second)
+	var messages []*zcl.ZclIncomingMessage
+	for len(messages) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		messages = s.received()
+	}
+	c.Assert(messages, HasLen, 1)
+	report := messages[0].Data.Command.(*cluster.ReportAttributesCommand)
+	c.Assert(report.AttributeReports[0].Attribute.Value, Equals, true)
+}
+
+func (s *SimulatorSuite) TestReadReportingConfiguration(c *C) {
+	s.global(c, cluster.LevelControl, cluster.ZclCommandConfigureReporting, &cluster.ConfigureReportingCommand{AttributeReportingConfigurationRecords: []*cluster.AttributeReportingConfigurationRecord{
+		{
+			AttributeID:              0x0000,
+			AttributeDataType:        cluster.ZclDataTypeUint8,
+			MinimumReportingInterval: 1,
+			MaximumReportingInterval: 300,
+			ReportableChange:         &cluster.Attribute{DataType: cluster.ZclDataTypeUint8, Value: uint64(5)},
+		},
+	}})
+
+	messages := s.global(c, cluster.LevelControl, cluster.ZclCommandReadReportingConfiguration, &cluster.ReadReportingConfigurationCommand{
+		AttributeRecords: []*cluster.AttributeRecord{{AttributeID: 0x0000}, {AttributeID: 0x0010}},
+	})
+	c.Assert(messages, HasLen, 1)
+	records := messages[0].Data.Command.(*cluster.ReadReportingConfigurationResponse).AttributeReportingConfigurationResponseRecords
+	c.Assert(records, HasLen, 2)
+	c.Assert(records[0].Status, Equals, cluster.ZclStatusSuccess)
+	c.Assert(records[0].MinimumReportingInterval, Equals, uint16(1))
+	c.Assert(records[0].MaximumReportingInterval, Equals, uint16(300))
+	c.Assert(records[0].ReportableChange.Value, Equals, uint64(5))
+	c.Assert(records[1].Status, Equals, cluster.ZclStatusUnreportableAttribute)
+}
+
+func (s *SimulatorSuite) TestBroadcastEndpoint(c *C) {
+	c.Assert(s.device.AddEndpoint(2, cluster.OnOff), IsNil)
+	messages := s.send(c, 0xff, cluster.OnOff, frame.FrameTypeLocal, 0x01, &cluster.OnCommand{})
+	c.Assert(messages, HasLen, 2)
+	c.Assert(messages[0].SrcEndpoint, Equals, uint8(1))
+	c.Assert(messages[1].SrcEndpoint, Equals, uint8(2))
+	for _, endpoint := range []uint8{1, 2} {
+		attribute, _ := s.device.Attribute(endpoint, cluster.OnOff, 0x0000)
+		c.Assert(attribute.Value, Equals, true)
+	}
+}
@@ -0,0 +1,102 @@
+// Package simulator runs virtual ZCL devices in memory, for integration tests
+// without radios. Devices expose server clusters defined in a ClusterLibrary,
+// answer the global commands, execute OnOff, LevelControl and Identify
+// commands and report attributes as configured.
+package simulator
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+)
+
+// The client is the coordinator sending frames through the transport.
+const (
+	ClientAddress        = "0x0000"
+	ClientEndpoint uint8 = 1
+)
+
+// Transport connects the client to the simulated devices. Frames go through
+// the wire encoding in both directions: devices decode what the client sends
+// and the client receives what devices send as decoded ZclIncomingMessages.
+type Transport struct {
+	library *cluster.ClusterLibrary
+	zcl     *zcl.Zcl
+	handler func(message *zcl.ZclIncomingMessage)
+	mutex   sync.Mutex
+	devices map[string]*Device
+}
+
+// NewTransport creates a transport calling handler with every message a
+// device sends, from the goroutine of Send for responses and from a timer
+// goroutine for reports.
+func NewTransport(library *cluster.ClusterLibrary, handler func(message *zcl.ZclIncomingMessage)) *Transport {
+	return &Transport{
+		library: library,
+		zcl:     zcl.NewWithLibrary(library),
+		handler: handler,
+		devices: map[string]*Device{},
+	}
+}
+
+// AddDevice adds a device without endpoints at a network address, e.g.
+// "0x1234", replacing the device which was there.
+func (t *Transport) AddDevice(address string) *Device {
+	d := newDevice(address, t)
+	t.mutex.Lock()
+	previous := t.devices[address]
+	t.devices[address] = d
+	t.mutex.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+	return d
+}
+
+func (t *Transport) Device(address string) (*Device, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	d, ok := t.devices[address]
+	return d, ok
+}
+
+// Send delivers a frame from the client to a device and returns once the
+// device has processed it and its responses reached the handler. It has the
+// signature of pollcontrol.Sender.
+func (t *Transport) Send(address string, endpoint uint8, clusterId cluster.ClusterId, f *frame.Frame) error {
+	d, ok := t.Device(address)
+	if !ok {
+		return fmt.Errorf("no device at %s", address)
+	}
+	d.receive(endpoint, clusterId, frame.Encode(f))
+	return nil
+}
+
+// Close stops the timers of all devices.
+func (t *Transport) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, d := range t.devices {
+		d.Close()
+	}
+}
+
+func (t *Transport) deliver(d *Device, o *outgoing) {
+	if t.handler == nil {
+		return
+	}
+	message, _ := t.zcl.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID:      uint16(o.clusterId),
+		SrcAddr:        d.address,
+		SrcEndpoint:    o.endpoint,
+		DstEndpoint:    ClientEndpoint,
+		LinkQuality:    0xff,
+		TransSeqNumber: o.frame.TransactionSequenceNumber,
+		Data:           frame.Encode(o.frame),
+	})
+	t.handler(message)
+}
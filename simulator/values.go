@@ -0,0 +1,16 @@
+package simulator
+
+import (
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+// number returns n in the Go type of integer attributes of dataType.
+func number(dataType cluster.ZclDataType, n int64) interface{} {
+	if dataType >= cluster.ZclDataTypeInt8 && dataType <= cluster.ZclDataTypeInt64 {
+		return n
+	}
+	if n < 0 {
+		n = 0
+	}
+	return uint64(n)
+}
@@ -0,0 +1,202 @@
+package zap
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+var (
+	presentIf    = regexp.MustCompile(`^\s*(\w+)\s*(==|!=)\s*(\w+)\s*$`)
+	presentIfSet = regexp.MustCompile(`^\w+$`)
+)
+
+type goField struct {
+	name string
+	typ  string
+	tags []string
+}
+
+// Generate writes Go source declaring a struct per command, tagged for
+// github.com/dyrkin/bin, and a Prototypes map which can be passed to Load.
+func (d *Definitions) Generate(w io.Writer, packageName string) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by zapgen. DO NOT EDIT.\n\npackage %s\n\n", packageName)
+	prototypes := &bytes.Buffer{}
+	types := map[string]bool{}
+	generate := func(clusterName string, commands []*Command) error {
+		for _, cmd := range commands {
+			name := Identifier(cmd.Name)
+			typeName := commandTypeName(clusterName, name)
+			if types[typeName] {
+				continue
+			}
+			types[typeName] = true
+			fields, err := d.goFields(cmd)
+			if err != nil {
+				return fmt.Errorf("command %s.%s: %s", clusterName, name, err)
+			}
+			if len(fields) == 0 {
+				fmt.Fprintf(buf, "type %s struct{}\n\n", typeName)
+				fmt.Fprintf(prototypes, "\t%q: &%s{},\n", clusterName+"."+name, typeName)
+				continue
+			}
+			fmt.Fprintf(buf, "type %s struct {\n", typeName)
+			for _, f := range fields {
+				fmt.Fprintf(buf, "\t%s %s", f.name, f.typ)
+				if len(f.tags) > 0 {
+					fmt.Fprintf(buf, " `%s`", strings.Join(f.tags, " "))
+				}
+				fmt.Fprintln(buf)
+			}
+			fmt.Fprint(buf, "}\n\n")
+			fmt.Fprintf(prototypes, "\t%q: &%s{},\n", clusterName+"."+name, typeName)
+		}
+		return nil
+	}
+	for _, c := range d.Clusters {
+		if err := generate(Identifier(c.Name), c.Commands); err != nil {
+			return err
+		}
+	}
+	for _, e := range d.Extensions {
+		if err := generate(d.clusterName(e.Code), e.Commands); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buf, "var Prototypes = map[string]interface{}{\n%s}\n", prototypes)
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+func (d *Definitions) clusterName(code string) string {
+	for _, c := range d.Clusters {
+		if c.Code == code {
+			return Identifier(c.Name)
+		}
+	}
+	return "Cluster" + Identifier(code)
+}
+
+func commandTypeName(clusterName string, commandName string) string {
+	if strings.HasSuffix(commandName, "Response") {
+		return clusterName + commandName
+	}
+	return clusterName + commandName + "Command"
+}
+
+func (d *Definitions) goFields(cmd *Command) ([]*goField, error) {
+	var fields []*goField
+	names := map[string]bool{}
+	for _, arg := range cmd.Args {
+		dataType, err := d.DataType(arg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+		}
+		if dataType == cluster.ZclDataTypeNoData {
+			continue
+		}
+		f := &goField{name: Identifier(arg.Name)}
+		for names[f.name] {
+			f.name += "_"
+		}
+		names[f.name] = true
+		f.typ, f.tags = goType(dataType)
+		if isTrue(arg.Array) {
+			if !repeatable(dataType) {
+				return nil, fmt.Errorf("argument %q: array of %s is not supported", arg.Name, arg.Type)
+			}
+			integers := strings.HasPrefix(f.typ, "uint") && len(f.tags) == 0
+			switch {
+			case integers:
+				f.typ = "[]" + f.typ
+			case arg.CountArg == "":
+				f.typ, f.tags = "[]uint8", nil
+			default:
+				return nil, fmt.Errorf("argument %q: counted array of %s is not supported", arg.Name, arg.Type)
+			}
+			if arg.CountArg != "" {
+				// the count is decoded as the length of the slice
+				size, err := countSize(fields, Identifier(arg.CountArg))
+				if err != nil {
+					return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+				}
+				fields = fields[:len(fields)-1]
+				f.tags = append(f.tags, fmt.Sprintf(`size:"%d"`, size))
+			}
+		}
+		c, err := condition(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+		}
+		if c != nil {
+			op := "=="
+			if c.Negate {
+				op = "!="
+			}
+			f.tags = append(f.tags, fmt.Sprintf(`cond:"uint:%s%s%d"`, c.Field, op, c.Value))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// countSize returns the size of the count of an array, which must be the
+// unsigned integer right before it.
+func countSize(fields []*goField, count string) (int, error) {
+	if len(fields) > 0 {
+		last := fields[len(fields)-1]
+		sizes := map[string]int{"uint8": 1, "uint16": 2, "uint32": 4, "uint64": 8}
+		if size, ok := sizes[last.typ]; ok && last.name == count && len(last.tags) == 0 {
+			return size, nil
+		}
+	}
+	return 0, fmt.Errorf("count %q must be the integer argument right before the array", count)
+}
+
+// goType maps a ZCL data type to the type used in command structs. Signed
+// integers share the representation of unsigned ones, floats are carried as
+// their raw bits.
+func goType(dataType cluster.ZclDataType) (string, []string) {
+	switch dataType {
+	case cluster.ZclDataTypeData8, cluster.ZclDataTypeBoolean, cluster.ZclDataTypeBitmap8,
+		cluster.ZclDataTypeUint8, cluster.ZclDataTypeInt8, cluster.ZclDataTypeEnum8:
+		return "uint8", nil
+	case cluster.ZclDataTypeData16, cluster.ZclDataTypeBitmap16, cluster.ZclDataTypeUint16, cluster.ZclDataTypeInt16,
+		cluster.ZclDataTypeEnum16, cluster.ZclDataTypeSemiPrec, cluster.ZclDataTypeClusterId, cluster.ZclDataTypeAttrId:
+		return "uint16", nil
+	case cluster.ZclDataTypeData24, cluster.ZclDataTypeBitmap24, cluster.ZclDataTypeUint24, cluster.ZclDataTypeInt24:
+		return "uint32", []string{`bound:"3"`}
+	case cluster.ZclDataTypeData32, cluster.ZclDataTypeBitmap32, cluster.ZclDataTypeUint32, cluster.ZclDataTypeInt32,
+		cluster.ZclDataTypeSinglePrec, cluster.ZclDataTypeTod, cluster.ZclDataTypeDate, cluster.ZclDataTypeUtc,
+		cluster.ZclDataTypeBacOid:
+		return "uint32", nil
+	case cluster.ZclDataTypeData40, cluster.ZclDataTypeBitmap40, cluster.ZclDataTypeUint40, cluster.ZclDataTypeInt40:
+		return "uint64", []string{`bound:"5"`}
+	case cluster.ZclDataTypeData48, cluster.ZclDataTypeBitmap48, cluster.ZclDataTypeUint48, cluster.ZclDataTypeInt48:
+		return "uint64", []string{`bound:"6"`}
+	case cluster.ZclDataTypeData56, cluster.ZclDataTypeBitmap56, cluster.ZclDataTypeUint56, cluster.ZclDataTypeInt56:
+		return "uint64", []string{`bound:"7"`}
+	case cluster.ZclDataTypeData64, cluster.ZclDataTypeBitmap64, cluster.ZclDataTypeUint64, cluster.ZclDataTypeInt64,
+		cluster.ZclDataTypeDoublePrec:
+		return "uint64", nil
+	case cluster.ZclDataTypeOctetStr, cluster.ZclDataTypeCharStr:
+		return "string", []string{`size:"1"`}
+	case cluster.ZclDataTypeLongOctetStr, cluster.ZclDataTypeLongCharStr:
+		return "string", []string{`size:"2"`}
+	case cluster.ZclDataTypeIeeeAddr:
+		return "string", []string{`hex:"8"`}
+	case cluster.ZclDataType_128BitSecKey:
+		return "[16]uint8", nil
+	}
+	return "[]uint8", nil
+}
@@ -0,0 +1,376 @@
+package zap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+)
+
+// Load registers the definitions with the library. Clusters which are already
+// registered are merged with the definitions: the attributes and commands
+// the definitions declare replace the registered ones, the others and e.g.
+// the alarms of the cluster are kept. Commands are decoded into the
+// prototypes keyed by "Cluster.Command", as emitted by zapgen. Commands
+// without a prototype keep the struct already registered in the library, the
+// rest are decoded with a schema built from their arguments.
+func (d *Definitions) Load(library *cluster.ClusterLibrary, prototypes map[string]interface{}) error {
+	for _, c := range d.Clusters {
+		clusterId, err := parseUint(c.Code, 16)
+		if err != nil {
+			return fmt.Errorf("cluster %q: invalid code %q", c.Name, c.Code)
+		}
+		manufacturerCode, err := parseManufacturerCode(c.ManufacturerCode)
+		if err != nil {
+			return fmt.Errorf("cluster %q: %s", c.Name, err)
+		}
+		name := Identifier(c.Name)
+		definition := &cluster.Cluster{
+			Name:                 name,
+			AttributeDescriptors: map[uint16]*cluster.AttributeDescriptor{},
+			CommandDescriptors: &cluster.CommandDescriptors{
+				Received:  map[uint8]*cluster.CommandDescriptor{},
+				Generated: map[uint8]*cluster.CommandDescriptor{},
+			},
+		}
+		for _, a := range c.Attributes {
+			if !serverSide(a) {
+				continue
+			}
+			attributeId, ad, err := d.attributeDescriptor(a)
+			if err != nil {
+				return fmt.Errorf("cluster %q: %s", c.Name, err)
+			}
+			definition.AttributeDescriptors[attributeId] = ad
+		}
+		answers := responses(c.Commands)
+		for _, cmd := range c.Commands {
+			direction, commandId, cd, err := d.commandDescriptor(library, cluster.ClusterId(clusterId), manufacturerCode, name, cmd, prototypes, answers)
+			if err != nil {
+				return fmt.Errorf("cluster %q: %s", c.Name, err)
+			}
+			if cd == nil {
+				continue
+			}
+			if direction == frame.DirectionServerClient {
+				definition.CommandDescriptors.Generated[commandId] = cd
+			} else {
+				definition.CommandDescriptors.Received[commandId] = cd
+			}
+		}
+		if err := merge(library, cluster.ClusterId(clusterId), manufacturerCode, definition); err != nil {
+			return err
+		}
+	}
+	for _, e := range d.Extensions {
+		if err := d.loadExtension(library, e, prototypes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge registers a cluster, or its attributes and commands one by one if the
+// library already knows the cluster.
+func merge(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, manufacturerCode uint16, definition *cluster.Cluster) error {
+	if len(library.Candidates(clusterId, manufacturerCode)) == 0 {
+		return library.RegisterCluster(clusterId, manufacturerCode, definition)
+	}
+	for attributeId, ad := range definition.AttributeDescriptors {
+		if err := library.OverrideAttribute(clusterId, manufacturerCode, attributeId, ad); err != nil {
+			return err
+		}
+	}
+	for commandId, cd := range definition.CommandDescriptors.Received {
+		if err := library.OverrideCommand(clusterId, manufacturerCode, frame.DirectionClientServer, commandId, cd); err != nil {
+			return err
+		}
+	}
+	for commandId, cd := range definition.CommandDescriptors.Generated {
+		if err := library.OverrideCommand(clusterId, manufacturerCode, frame.DirectionServerClient, commandId, cd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFiles parses the files and loads them into the library.
+func LoadFiles(library *cluster.ClusterLibrary, prototypes map[string]interface{}, paths ...string) error {
+	d, err := ParseFiles(paths...)
+	if err != nil {
+		return err
+	}
+	return d.Load(library, prototypes)
+}
+
+func (d *Definitions) loadExtension(library *cluster.ClusterLibrary, e *ClusterExtension, prototypes map[string]interface{}) error {
+	clusterId, err := parseUint(e.Code, 16)
+	if err != nil {
+		return fmt.Errorf("cluster extension: invalid code %q", e.Code)
+	}
+	name := ""
+	for _, c := range library.Candidates(cluster.ClusterId(clusterId), 0) {
+		name = c.Name
+	}
+	for _, a := range e.Attributes {
+		if !serverSide(a) {
+			continue
+		}
+		manufacturerCode, err := parseManufacturerCode(a.ManufacturerCode)
+		if err != nil {
+			return fmt.Errorf("cluster extension 0x%04x: %s", clusterId, err)
+		}
+		attributeId, ad, err := d.attributeDescriptor(a)
+		if err != nil {
+			return fmt.Errorf("cluster extension 0x%04x: %s", clusterId, err)
+		}
+		if err := library.OverrideAttribute(cluster.ClusterId(clusterId), manufacturerCode, attributeId, ad); err != nil {
+			return err
+		}
+	}
+	answers := responses(e.Commands)
+	for _, cmd := range e.Commands {
+		manufacturerCode, err := parseManufacturerCode(cmd.ManufacturerCode)
+		if err != nil {
+			return fmt.Errorf("cluster extension 0x%04x: %s", clusterId, err)
+		}
+		direction, commandId, cd, err := d.commandDescriptor(library, cluster.ClusterId(clusterId), manufacturerCode, name, cmd, prototypes, answers)
+		if err != nil {
+			return fmt.Errorf("cluster extension 0x%04x: %s", clusterId, err)
+		}
+		if cd == nil {
+			continue
+		}
+		if err := library.OverrideCommand(cluster.ClusterId(clusterId), manufacturerCode, direction, commandId, cd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) attributeDescriptor(a *Attribute) (uint16, *cluster.AttributeDescriptor, error) {
+	attributeId, err := parseUint(a.Code, 16)
+	if err != nil {
+		return 0, nil, fmt.Errorf("attribute %q: invalid code %q", a.Identifier(), a.Code)
+	}
+	dataType, err := d.DataType(a.Type)
+	if err != nil {
+		return 0, nil, fmt.Errorf("attribute 0x%04x: %s", attributeId, err)
+	}
+	access := cluster.Read
+	if isTrue(a.Writable) {
+		access |= cluster.Write
+	}
+	if isTrue(a.Reportable) {
+		access |= cluster.Reportable
+	}
+	return uint16(attributeId), &cluster.AttributeDescriptor{
+		Name:        a.Identifier(),
+		Type:        dataType,
+		Access:      access,
+		Mandatory:   !isTrue(a.Optional),
+		Default:     value(dataType, a.Default),
+		Min:         value(dataType, a.Min),
+		Max:         value(dataType, a.Max),
+		Description: strings.TrimSpace(a.Description),
+		Enum:        d.enum(a.Type),
+		Bitmap:      d.bitmap(a.Type),
+	}, nil
+}
+
+func (d *Definitions) enum(name string) cluster.Enum {
+	e := d.Enum(name)
+	if e == nil {
+		return nil
+	}
+	enum := cluster.Enum{}
+	for _, item := range e.Items {
+		if v, err := parseUint(item.Value, 64); err == nil {
+			enum[v] = Identifier(item.Name)
+		}
+	}
+	return enum
+}
+
+func (d *Definitions) bitmap(name string) cluster.Bitmap {
+	b := d.Bitmap(name)
+	if b == nil {
+		return nil
+	}
+	bitmap := cluster.Bitmap{}
+	for _, field := range b.Fields {
+		if mask, err := parseUint(field.Mask, 64); err == nil {
+			bitmap[mask] = Identifier(field.Name)
+		}
+	}
+	return bitmap
+}
+
+var integerSizes = map[cluster.ZclDataType]uint{
+	cluster.ZclDataTypeBitmap8: 1, cluster.ZclDataTypeUint8: 1, cluster.ZclDataTypeEnum8: 1, cluster.ZclDataTypeInt8: 1,
+	cluster.ZclDataTypeBitmap16: 2, cluster.ZclDataTypeUint16: 2, cluster.ZclDataTypeEnum16: 2, cluster.ZclDataTypeInt16: 2,
+	cluster.ZclDataTypeBitmap24: 3, cluster.ZclDataTypeUint24: 3, cluster.ZclDataTypeInt24: 3,
+	cluster.ZclDataTypeBitmap32: 4, cluster.ZclDataTypeUint32: 4, cluster.ZclDataTypeInt32: 4,
+	cluster.ZclDataTypeBitmap40: 5, cluster.ZclDataTypeUint40: 5, cluster.ZclDataTypeInt40: 5,
+	cluster.ZclDataTypeBitmap48: 6, cluster.ZclDataTypeUint48: 6, cluster.ZclDataTypeInt48: 6,
+	cluster.ZclDataTypeBitmap56: 7, cluster.ZclDataTypeUint56: 7, cluster.ZclDataTypeInt56: 7,
+	cluster.ZclDataTypeBitmap64: 8, cluster.ZclDataTypeUint64: 8, cluster.ZclDataTypeInt64: 8,
+}
+
+// value converts a default or a bound to the Go type an Attribute of dataType
+// decodes to. Signed values may be given as their two's complement in hex.
+// Values which can't be converted are ignored.
+func value(dataType cluster.ZclDataType, s string) interface{} {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	switch dataType {
+	case cluster.ZclDataTypeInt8, cluster.ZclDataTypeInt16, cluster.ZclDataTypeInt24, cluster.ZclDataTypeInt32,
+		cluster.ZclDataTypeInt40, cluster.ZclDataTypeInt48, cluster.ZclDataTypeInt56, cluster.ZclDataTypeInt64:
+		if v, err := strconv.ParseInt(s, 0, 64); err == nil && !strings.HasPrefix(strings.ToLower(s), "0x") {
+			return v
+		}
+		v, err := parseUint(s, 64)
+		if err != nil {
+			return nil
+		}
+		shift := 64 - integerSizes[dataType]*8
+		return int64(v<<shift) >> shift
+	case cluster.ZclDataTypeBoolean:
+		if v, err := parseUint(s, 8); err == nil {
+			return v != 0
+		}
+		if v, err := strconv.ParseBool(s); err == nil {
+			return v
+		}
+	case cluster.ZclDataTypeSemiPrec, cluster.ZclDataTypeSinglePrec:
+		if v, err := strconv.ParseFloat(s, 32); err == nil {
+			return float32(v)
+		}
+	case cluster.ZclDataTypeDoublePrec:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	case cluster.ZclDataTypeOctetStr, cluster.ZclDataTypeCharStr, cluster.ZclDataTypeLongOctetStr, cluster.ZclDataTypeLongCharStr:
+		return s
+	default:
+		if _, ok := integerSizes[dataType]; ok {
+			if v, err := parseUint(s, 64); err == nil {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// responses returns the names of the commands answering others, which name
+// them in their response attribute.
+func responses(commands []*Command) map[string]bool {
+	names := map[string]bool{}
+	for _, cmd := range commands {
+		if cmd.Response != "" {
+			names[cmd.Response] = true
+		}
+	}
+	return names
+}
+
+func (d *Definitions) commandDescriptor(library *cluster.ClusterLibrary, clusterId cluster.ClusterId, manufacturerCode uint16,
+	clusterName string, cmd *Command, prototypes map[string]interface{}, responses map[string]bool) (frame.Direction, uint8, *cluster.CommandDescriptor, error) {
+	commandId, err := parseUint(cmd.Code, 8)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("command %q: invalid code %q", cmd.Name, cmd.Code)
+	}
+	direction := frame.DirectionClientServer
+	if strings.EqualFold(cmd.Source, "server") {
+		direction = frame.DirectionServerClient
+	}
+	name := Identifier(cmd.Name)
+	response := responses[cmd.Name]
+	if prototype, ok := prototypes[clusterName+"."+name]; ok {
+		return direction, uint8(commandId), &cluster.CommandDescriptor{Name: name, Command: prototype, Response: response}, nil
+	}
+	if existing, ok := library.CommandDescriptor(clusterId, manufacturerCode, direction, uint8(commandId)); ok {
+		return direction, uint8(commandId), &cluster.CommandDescriptor{Name: name, Command: existing.Command, Response: response || existing.Response}, nil
+	}
+	schema, err := d.CommandSchema(cmd)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("command %q: %s", cmd.Name, err)
+	}
+	return direction, uint8(commandId), &cluster.CommandDescriptor{Name: name, Command: schema.NewCommand(), Response: response}, nil
+}
+
+// CommandSchema describes the arguments of a command, to decode commands
+// which don't have a generated struct.
+func (d *Definitions) CommandSchema(cmd *Command) (*cluster.CommandSchema, error) {
+	schema := &cluster.CommandSchema{}
+	for _, arg := range cmd.Args {
+		dataType, err := d.DataType(arg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+		}
+		if dataType == cluster.ZclDataTypeNoData {
+			continue
+		}
+		fd := &cluster.FieldDescriptor{Name: Identifier(arg.Name), Type: dataType}
+		if isTrue(arg.Array) {
+			if !repeatable(dataType) {
+				return nil, fmt.Errorf("argument %q: array of %s is not supported", arg.Name, arg.Type)
+			}
+			if arg.CountArg != "" {
+				fd.CountField = Identifier(arg.CountArg)
+			} else {
+				fd.List = true
+			}
+		}
+		if fd.Condition, err = condition(arg); err != nil {
+			return nil, fmt.Errorf("argument %q: %s", arg.Name, err)
+		}
+		schema.Fields = append(schema.Fields, fd)
+	}
+	return schema, nil
+}
+
+// repeatable tells if a data type can be the element of an array. Struct
+// members aren't described, so a struct element has no known width.
+func repeatable(dataType cluster.ZclDataType) bool {
+	return dataType != cluster.ZclDataTypeStruct && dataType != cluster.ZclDataTypeUnknown
+}
+
+// condition parses the presentIf expression of an argument: the comparison
+// of an earlier argument with a number, or an earlier argument which must not
+// be zero. Other expressions are an error, for the generated structs and the
+// schemas to decode the same fields.
+func condition(arg *Arg) (*cluster.FieldCondition, error) {
+	expression := strings.TrimSpace(arg.PresentIf)
+	switch {
+	case expression == "":
+		return nil, nil
+	case presentIfSet.MatchString(expression):
+		return &cluster.FieldCondition{Field: Identifier(expression), Negate: true}, nil
+	}
+	if m := presentIf.FindStringSubmatch(expression); m != nil {
+		if value, err := parseUint(m[3], 64); err == nil {
+			return &cluster.FieldCondition{Field: Identifier(m[1]), Value: value, Negate: m[2] == "!="}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported condition %q", arg.PresentIf)
+}
+
+func serverSide(a *Attribute) bool {
+	return a.Side == "" || strings.EqualFold(a.Side, "server")
+}
+
+func parseManufacturerCode(s string) (uint16, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	code, err := parseUint(s, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid manufacturer code %q", s)
+	}
+	return uint16(code), nil
+}
@@ -0,0 +1,289 @@
+// Package zap reads the ZCL cluster definitions published as XML by the
+// Connectivity Standards Alliance and used by the ZAP tool.
+//
+// Typed command structs can be generated from the same files:
+//
+//	//go:generate go run github.com/dyrkin/zcl-go/cmd/zapgen -package commands -out commands_gen.go zcl/general.xml zcl/types.xml
+package zap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dyrkin/zcl-go/cluster"
+)
+
+type Definitions struct {
+	Enums      []*Enum             `xml:"enum"`
+	Bitmaps    []*Bitmap           `xml:"bitmap"`
+	Structs    []*Struct           `xml:"struct"`
+	Clusters   []*Cluster          `xml:"cluster"`
+	Extensions []*ClusterExtension `xml:"clusterExtension"`
+}
+
+type Enum struct {
+	Name  string      `xml:"name,attr"`
+	Type  string      `xml:"type,attr"`
+	Items []*EnumItem `xml:"item"`
+}
+
+type EnumItem struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type Bitmap struct {
+	Name   string         `xml:"name,attr"`
+	Type   string         `xml:"type,attr"`
+	Fields []*BitmapField `xml:"field"`
+}
+
+type BitmapField struct {
+	Name string `xml:"name,attr"`
+	Mask string `xml:"mask,attr"`
+}
+
+type Struct struct {
+	Name string `xml:"name,attr"`
+}
+
+type Cluster struct {
+	Name             string       `xml:"name"`
+	Domain           string       `xml:"domain"`
+	Description      string       `xml:"description"`
+	Code             string       `xml:"code"`
+	ManufacturerCode string       `xml:"manufacturerCode,attr"`
+	Attributes       []*Attribute `xml:"attribute"`
+	Commands         []*Command   `xml:"command"`
+}
+
+type ClusterExtension struct {
+	Code       string       `xml:"code,attr"`
+	Attributes []*Attribute `xml:"attribute"`
+	Commands   []*Command   `xml:"command"`
+}
+
+type Attribute struct {
+	Side             string `xml:"side,attr"`
+	Code             string `xml:"code,attr"`
+	Define           string `xml:"define,attr"`
+	Name             string `xml:"name,attr"`
+	Type             string `xml:"type,attr"`
+	Min              string `xml:"min,attr"`
+	Max              string `xml:"max,attr"`
+	Writable         string `xml:"writable,attr"`
+	Reportable       string `xml:"reportable,attr"`
+	Default          string `xml:"default,attr"`
+	Optional         string `xml:"optional,attr"`
+	ManufacturerCode string `xml:"manufacturerCode,attr"`
+	Text             string `xml:",chardata"`
+	Description      string `xml:"description"`
+}
+
+type Command struct {
+	Source           string `xml:"source,attr"`
+	Code             string `xml:"code,attr"`
+	Name             string `xml:"name,attr"`
+	Optional         string `xml:"optional,attr"`
+	ManufacturerCode string `xml:"manufacturerCode,attr"`
+	// Response names the command answering this one.
+	Response    string `xml:"response,attr"`
+	Description string `xml:"description"`
+	Args        []*Arg `xml:"arg"`
+}
+
+type Arg struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Array     string `xml:"array,attr"`
+	CountArg  string `xml:"countArg,attr"`
+	PresentIf string `xml:"presentIf,attr"`
+	Optional  string `xml:"optional,attr"`
+}
+
+var dataTypes = map[string]cluster.ZclDataType{
+	"NO_DATA":           cluster.ZclDataTypeNoData,
+	"DATA8":             cluster.ZclDataTypeData8,
+	"DATA16":            cluster.ZclDataTypeData16,
+	"DATA24":            cluster.ZclDataTypeData24,
+	"DATA32":            cluster.ZclDataTypeData32,
+	"DATA40":            cluster.ZclDataTypeData40,
+	"DATA48":            cluster.ZclDataTypeData48,
+	"DATA56":            cluster.ZclDataTypeData56,
+	"DATA64":            cluster.ZclDataTypeData64,
+	"BOOLEAN":           cluster.ZclDataTypeBoolean,
+	"BITMAP8":           cluster.ZclDataTypeBitmap8,
+	"BITMAP16":          cluster.ZclDataTypeBitmap16,
+	"BITMAP24":          cluster.ZclDataTypeBitmap24,
+	"BITMAP32":          cluster.ZclDataTypeBitmap32,
+	"BITMAP40":          cluster.ZclDataTypeBitmap40,
+	"BITMAP48":          cluster.ZclDataTypeBitmap48,
+	"BITMAP56":          cluster.ZclDataTypeBitmap56,
+	"BITMAP64":          cluster.ZclDataTypeBitmap64,
+	"INT8U":             cluster.ZclDataTypeUint8,
+	"INT16U":            cluster.ZclDataTypeUint16,
+	"INT24U":            cluster.ZclDataTypeUint24,
+	"INT32U":            cluster.ZclDataTypeUint32,
+	"INT40U":            cluster.ZclDataTypeUint40,
+	"INT48U":            cluster.ZclDataTypeUint48,
+	"INT56U":            cluster.ZclDataTypeUint56,
+	"INT64U":            cluster.ZclDataTypeUint64,
+	"INT8S":             cluster.ZclDataTypeInt8,
+	"INT16S":            cluster.ZclDataTypeInt16,
+	"INT24S":            cluster.ZclDataTypeInt24,
+	"INT32S":            cluster.ZclDataTypeInt32,
+	"INT40S":            cluster.ZclDataTypeInt40,
+	"INT48S":            cluster.ZclDataTypeInt48,
+	"INT56S":            cluster.ZclDataTypeInt56,
+	"INT64S":            cluster.ZclDataTypeInt64,
+	"ENUM8":             cluster.ZclDataTypeEnum8,
+	"ENUM16":            cluster.ZclDataTypeEnum16,
+	"FLOAT_SEMI":        cluster.ZclDataTypeSemiPrec,
+	"FLOAT_SINGLE":      cluster.ZclDataTypeSinglePrec,
+	"FLOAT_DOUBLE":      cluster.ZclDataTypeDoublePrec,
+	"SEMI":              cluster.ZclDataTypeSemiPrec,
+	"SINGLE":            cluster.ZclDataTypeSinglePrec,
+	"DOUBLE":            cluster.ZclDataTypeDoublePrec,
+	"OCTET_STRING":      cluster.ZclDataTypeOctetStr,
+	"CHAR_STRING":       cluster.ZclDataTypeCharStr,
+	"LONG_OCTET_STRING": cluster.ZclDataTypeLongOctetStr,
+	"LONG_CHAR_STRING":  cluster.ZclDataTypeLongCharStr,
+	"ARRAY":             cluster.ZclDataTypeArray,
+	"STRUCT":            cluster.ZclDataTypeStruct,
+	"SET":               cluster.ZclDataTypeSet,
+	"BAG":               cluster.ZclDataTypeBag,
+	"TOD":               cluster.ZclDataTypeTod,
+	"TIME_OF_DAY":       cluster.ZclDataTypeTod,
+	"DATE":              cluster.ZclDataTypeDate,
+	"UTC":               cluster.ZclDataTypeUtc,
+	"UTC_TIME":          cluster.ZclDataTypeUtc,
+	"CLUSTER_ID":        cluster.ZclDataTypeClusterId,
+	"ATTRIB_ID":         cluster.ZclDataTypeAttrId,
+	"ATTRIBUTE_ID":      cluster.ZclDataTypeAttrId,
+	"BACNET_OID":        cluster.ZclDataTypeBacOid,
+	"IEEE_ADDRESS":      cluster.ZclDataTypeIeeeAddr,
+	"EUI64":             cluster.ZclDataTypeIeeeAddr,
+	"SECURITY_KEY":      cluster.ZclDataType_128BitSecKey,
+	"KEY128":            cluster.ZclDataType_128BitSecKey,
+	"UNKNOWN":           cluster.ZclDataTypeUnknown,
+}
+
+func Parse(r io.Reader) (*Definitions, error) {
+	d := &Definitions{}
+	if err := xml.NewDecoder(r).Decode(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ParseFiles parses and merges several files, so that enums and bitmaps
+// defined in one file can be referenced by clusters defined in another.
+func ParseFiles(paths ...string) (*Definitions, error) {
+	merged := &Definitions{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		d, err := Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		merged.Merge(d)
+	}
+	return merged, nil
+}
+
+func (d *Definitions) Merge(other *Definitions) {
+	d.Enums = append(d.Enums, other.Enums...)
+	d.Bitmaps = append(d.Bitmaps, other.Bitmaps...)
+	d.Structs = append(d.Structs, other.Structs...)
+	d.Clusters = append(d.Clusters, other.Clusters...)
+	d.Extensions = append(d.Extensions, other.Extensions...)
+}
+
+// DataType resolves a type name used by an attribute or a command argument,
+// following named enums and bitmaps to their underlying type.
+func (d *Definitions) DataType(name string) (cluster.ZclDataType, error) {
+	if dataType, ok := dataTypes[strings.ToUpper(name)]; ok {
+		return dataType, nil
+	}
+	if enum := d.Enum(name); enum != nil {
+		return d.DataType(enum.Type)
+	}
+	if bitmap := d.Bitmap(name); bitmap != nil {
+		return d.DataType(bitmap.Type)
+	}
+	for _, s := range d.Structs {
+		if s.Name == name {
+			return cluster.ZclDataTypeStruct, nil
+		}
+	}
+	return cluster.ZclDataTypeUnknown, fmt.Errorf("unknown type %q", name)
+}
+
+func (d *Definitions) Enum(name string) *Enum {
+	for _, enum := range d.Enums {
+		if enum.Name == name {
+			return enum
+		}
+	}
+	return nil
+}
+
+func (d *Definitions) Bitmap(name string) *Bitmap {
+	for _, bitmap := range d.Bitmaps {
+		if bitmap.Name == name {
+			return bitmap
+		}
+	}
+	return nil
+}
+
+func (a *Attribute) Identifier() string {
+	switch {
+	case a.Name != "":
+		return Identifier(a.Name)
+	case strings.TrimSpace(a.Text) != "":
+		return Identifier(a.Text)
+	case a.Description != "":
+		return Identifier(a.Description)
+	}
+	return Identifier(strings.ToLower(a.Define))
+}
+
+// Identifier turns a human readable name like "ZCL version" or "identify_time"
+// into a Go identifier like "ZCLVersion" or "IdentifyTime".
+func Identifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteRune('_')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func parseUint(s string, bitSize int) (uint64, error) {
+	return strconv.ParseUint(strings.TrimSpace(s), 0, bitSize)
+}
+
+func isTrue(s string) bool {
+	return strings.EqualFold(strings.TrimSpace(s), "true")
+}
@@ -0,0 +1,248 @@
+package zap
+
+import (
+	"bytes"
+	"testing"
+
+	zcl "github.com/dyrkin/zcl-go"
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestZap(t *testing.T) { TestingT(t) }
+
+type ZapSuite struct{}
+
+var _ = Suite(&ZapSuite{})
+
+type vendorSensorStatusCommand struct {
+	Ieee    string `hex:"8"`
+	Flags   uint8
+	Counter uint32 `bound:"3"`
+	Label   string `size:"1" cond:"uint:Flags==1"`
+	Samples []uint16
+}
+
+func (s *ZapSuite) definitions(c *C) *Definitions {
+	d, err := ParseFiles("testdata/types.xml", "testdata/general.xml")
+	c.Assert(err, IsNil)
+	return d
+}
+
+func (s *ZapSuite) TestParse(c *C) {
+	d := s.definitions(c)
+	c.Assert(d.Clusters, HasLen, 4)
+	c.Assert(d.Enum("PowerSource").Items, HasLen, 3)
+	c.Assert(d.Bitmap("OnOffControl").Fields[0].Mask, Equals, "0x01")
+	c.Assert(d.Clusters[1].Commands[1].Args[0].Type, Equals, "IdentifyEffectIdentifier")
+
+	dataType, err := d.DataType("PowerSource")
+	c.Assert(err, IsNil)
+	c.Assert(dataType, Equals, cluster.ZclDataTypeEnum8)
+	_, err = d.DataType("Missing")
+	c.Assert(err, ErrorMatches, `unknown type "Missing"`)
+
+	c.Assert(Identifier("ZCL version"), Equals, "ZCLVersion")
+	c.Assert(Identifier("On/off"), Equals, "OnOff")
+	c.Assert(Identifier("identify_time"), Equals, "IdentifyTime")
+}
+
+func (s *ZapSuite) TestLoad(c *C) {
+	library := cluster.NewEmpty()
+	prototypes := map[string]interface{}{"VendorSensor.Status": &vendorSensorStatusCommand{}}
+	c.Assert(s.definitions(c).Load(library, prototypes), IsNil)
+
+	basic := library.Clusters()[cluster.Basic]
+	c.Assert(basic.Name, Equals, "Basic")
+	c.Assert(basic.AttributeDescriptors[0x0000], DeepEquals, &cluster.AttributeDescriptor{
+		Name:      "ZCLVersion",
+		Type:      cluster.ZclDataTypeUint8,
+		Access:    cluster.Read,
+		Mandatory: true,
+		Default:   uint64(0x08),
+		Min:       uint64(0x00),
+		Max:       uint64(0xff),
+	})
+	c.Assert(basic.AttributeDescriptors[0x0005].Mandatory, Equals, false)
+	c.Assert(basic.AttributeDescriptors[0x0010].Default, Equals, nil)
+	c.Assert(basic.AttributeDescriptors[0x0007].Type, Equals, cluster.ZclDataTypeEnum8)
+	c.Assert(basic.AttributeDescriptors[0x0007].Enum, DeepEquals, cluster.Enum{0x00: "Unknown", 0x01: "SinglePhaseMains", 0x03: "Battery"})
+	c.Assert(basic.AttributeDescriptors[0x0007].Render(uint64(3)), Equals, "Battery")
+	c.Assert(basic.AttributeDescriptors[0x0010].Access, Equals, cluster.Read|cluster.Write)
+	c.Assert(basic.AttributeDescriptors[0x4000].Name, Equals, "SwBuildId")
+	_, ok := basic.AttributeDescriptors[0xfffd]
+	c.Assert(ok, Equals, false)
+
+	ad, ok := library.AttributeDescriptor(cluster.Basic, cluster.ManufacturerXiaomi, 0xff01)
+	c.Assert(ok, Equals, true)
+	c.Assert(ad.Name, Equals, "XiaomiInfo")
+	_, ok = library.AttributeDescriptor(cluster.Basic, 0, 0xff01)
+	c.Assert(ok, Equals, false)
+
+	ad, _ = library.AttributeDescriptor(cluster.OnOff, 0, 0x0000)
+	c.Assert(ad.Access, Equals, cluster.Read|cluster.Reportable)
+	c.Assert(ad.Default, Equals, false)
+
+	ad, _ = library.AttributeDescriptor(0xfc01, 0x1234, 0x0001)
+	c.Assert(ad.Min, Equals, int64(-27315))
+	c.Assert(ad.Max, Equals, int64(32767))
+	c.Assert(ad.Description, Equals, "Offset applied to the measured temperature")
+
+	cd, ok := library.CommandDescriptor(cluster.OnOff, 0, frame.DirectionClientServer, 0x00)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Name, Equals, "Off")
+	cd, ok = library.CommandDescriptor(cluster.OnOff, 0, frame.DirectionClientServer, 0x42)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Command, FitsTypeOf, &cluster.DynamicCommand{})
+
+	cd, ok = library.CommandDescriptor(0xfc01, 0x1234, frame.DirectionServerClient, 0x01)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Name, Equals, "Status")
+	c.Assert(cd.Response, Equals, false)
+
+	cd, ok = library.CommandDescriptor(cluster.Identify, 0, frame.DirectionServerClient, 0x00)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Response, Equals, true)
+}
+
+func (s *ZapSuite) TestLoadKeepsExistingCommands(c *C) {
+	library := cluster.New()
+	c.Assert(s.definitions(c).Load(library, nil), IsNil)
+
+	cd, ok := library.CommandDescriptor(cluster.Identify, 0, frame.DirectionClientServer, 0x00)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Command, FitsTypeOf, &cluster.IdentifyCommand{})
+	cd, ok = library.CommandDescriptor(cluster.OnOff, 0, frame.DirectionClientServer, 0x42)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Command, FitsTypeOf, &cluster.OnWithTimedOffCommand{})
+}
+
+func (s *ZapSuite) TestLoadMergesExistingClusters(c *C) {
+	library := cluster.New()
+	c.Assert(s.definitions(c).Load(library, nil), IsNil)
+
+	basic := library.Clusters()[cluster.Basic]
+	c.Assert(basic.AttributeDescriptors[0x0000].Name, Equals, "ZCLVersion")
+	c.Assert(basic.AttributeDescriptors[0x0001].Name, Equals, "ApplicationVersion")
+	description, ok := library.AlarmDescription(cluster.Basic, 0x01)
+	c.Assert(ok, Equals, true)
+	c.Assert(description, Equals, "GeneralSoftwareFault")
+
+	cd, ok := library.CommandDescriptor(cluster.OnOff, 0, frame.DirectionClientServer, 0x01)
+	c.Assert(ok, Equals, true)
+	c.Assert(cd.Name, Equals, "On")
+}
+
+func (s *ZapSuite) TestDecodeLoadedCommand(c *C) {
+	library := cluster.NewEmpty()
+	prototypes := map[string]interface{}{"VendorSensor.Status": &vendorSensorStatusCommand{}}
+	c.Assert(s.definitions(c).Load(library, prototypes), IsNil)
+
+	message, err := zcl.NewWithLibrary(library).ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: 0xfc01,
+		SrcAddr:   "0x1234",
+		Data: []uint8{0x1d, 0x34, 0x12, 0x05, 0x01,
+			0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+			0x01, 0x03, 0x02, 0x01,
+			0x02, 0x4f, 0x4b,
+			0x01, 0x00, 0x02, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.CommandName, Equals, "Status")
+	c.Assert(message.Data.Command, DeepEquals, &vendorSensorStatusCommand{
+		Ieee:    "0x0102030405060708",
+		Flags:   1,
+		Counter: 0x010203,
+		Label:   "OK",
+		Samples: []uint16{1, 2},
+	})
+}
+
+func (s *ZapSuite) TestDecodeWithSchema(c *C) {
+	library := cluster.NewEmpty()
+	c.Assert(s.definitions(c).Load(library, nil), IsNil)
+
+	message, err := zcl.NewWithLibrary(library).ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: 0xfc01,
+		SrcAddr:   "0x1234",
+		Data: []uint8{0x1d, 0x34, 0x12, 0x05, 0x01,
+			0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+			0x00, 0x03, 0x02, 0x01,
+			0x01, 0x00, 0x02, 0x00},
+	})
+	c.Assert(err, IsNil)
+	command := message.Data.Command.(*cluster.DynamicCommand)
+	c.Assert(command.Fields, DeepEquals, []*cluster.Field{
+		{Name: "Ieee", Type: cluster.ZclDataTypeIeeeAddr, Value: "0x0102030405060708"},
+		{Name: "Flags", Type: cluster.ZclDataTypeBitmap8, Value: uint64(0)},
+		{Name: "Counter", Type: cluster.ZclDataTypeUint24, Value: uint64(0x010203)},
+		{Name: "Samples", Type: cluster.ZclDataTypeUint16, Value: []interface{}{uint64(1), uint64(2)}},
+	})
+}
+
+type vendorSensorHistory struct {
+	Values  []uint16 `size:"1"`
+	Trailer uint8
+}
+
+func (s *ZapSuite) TestDecodeCountedArray(c *C) {
+	data := []uint8{0x1d, 0x34, 0x12, 0x05, 0x02, 0x01, 0x01, 0x00, 0x07}
+	for _, prototypes := range []map[string]interface{}{nil, {"VendorSensor.History": &vendorSensorHistory{}}} {
+		library := cluster.NewEmpty()
+		c.Assert(s.definitions(c).Load(library, prototypes), IsNil)
+		message, err := zcl.NewWithLibrary(library).ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: 0xfc01, SrcAddr: "0x1234", Data: data})
+		c.Assert(err, IsNil)
+		switch command := message.Data.Command.(type) {
+		case *cluster.DynamicCommand:
+			c.Assert(command.Fields, DeepEquals, []*cluster.Field{
+				{Name: "Count", Type: cluster.ZclDataTypeUint8, Value: uint64(1)},
+				{Name: "Values", Type: cluster.ZclDataTypeUint16, Value: []interface{}{uint64(1)}},
+				{Name: "Trailer", Type: cluster.ZclDataTypeUint8, Value: uint64(7)},
+			})
+		default:
+			c.Assert(command, DeepEquals, &vendorSensorHistory{Values: []uint16{1}, Trailer: 7})
+		}
+	}
+}
+
+func (s *ZapSuite) TestUnsupportedCondition(c *C) {
+	d := s.definitions(c)
+	d.Clusters[3].Commands[0].Args[3].PresentIf = "flags && counter"
+	_, err := d.CommandSchema(d.Clusters[3].Commands[0])
+	c.Assert(err, ErrorMatches, `argument "label": unsupported condition "flags && counter"`)
+	c.Assert(d.Load(cluster.NewEmpty(), nil), ErrorMatches, `cluster "Vendor Sensor": command "Status": argument "label": unsupported condition .*`)
+	c.Assert(d.Generate(&bytes.Buffer{}, "commands"), ErrorMatches, `command VendorSensor.Status: argument "label": unsupported condition .*`)
+
+	d.Clusters[3].Commands[0].Args[3].PresentIf = "flags"
+	schema, err := d.CommandSchema(d.Clusters[3].Commands[0])
+	c.Assert(err, IsNil)
+	c.Assert(schema.Fields[3].Condition, DeepEquals, &cluster.FieldCondition{Field: "Flags", Negate: true})
+}
+
+func (s *ZapSuite) TestStructArray(c *C) {
+	d, err := ParseFiles("testdata/types.xml", "testdata/struct.xml")
+	c.Assert(err, IsNil)
+	_, err = d.CommandSchema(d.Clusters[0].Commands[0])
+	c.Assert(err, ErrorMatches, `argument "records": array of ReadingRecord is not supported`)
+	c.Assert(d.Load(cluster.NewEmpty(), nil), ErrorMatches, `cluster "Vendor Log": command "Readings": argument "records": array of .*`)
+	c.Assert(d.Generate(&bytes.Buffer{}, "commands"), ErrorMatches, `command VendorLog.Readings: argument "records": array of .*`)
+
+	d.Clusters[0].Commands[0].Args[0].Array = ""
+	schema, err := d.CommandSchema(d.Clusters[0].Commands[0])
+	c.Assert(err, IsNil)
+	c.Assert(schema.Fields[0], DeepEquals, &cluster.FieldDescriptor{Name: "Records", Type: cluster.ZclDataTypeStruct})
+}
+
+func (s *ZapSuite) TestGenerate(c *C) {
+	buf := &bytes.Buffer{}
+	c.Assert(s.definitions(c).Generate(buf, "commands"), IsNil)
+	source := buf.String()
+	c.Assert(source, Matches, `(?s)// Code generated by zapgen. DO NOT EDIT.\n\npackage commands\n.*`)
+	c.Assert(source, Matches, "(?s).*type BasicResetToFactoryDefaultsCommand struct{}\n.*")
+	c.Assert(source, Matches, "(?s).*type IdentifyIdentifyQueryResponse struct \\{\n\tTimeout uint16\n\\}.*")
+	c.Assert(source, Matches, "(?s).*\tCounter uint32 `bound:\"3\"`\n\tLabel   string `size:\"1\" cond:\"uint:Flags==1\"`\n\tSamples \\[\\]uint16\n.*")
+	c.Assert(source, Matches, "(?s).*\"VendorSensor.Status\": +&VendorSensorStatusCommand\\{\\},.*")
+	c.Assert(source, Matches, "(?s).*type VendorSensorHistoryCommand struct \\{\n\tValues  \\[\\]uint16 `size:\"1\"`\n\tTrailer uint8\n\\}.*")
+}
@@ -2,6 +2,8 @@ package zcl
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/dyrkin/bin"
 	"github.com/dyrkin/zcl-go/cluster"
 	"github.com/dyrkin/zcl-go/frame"
@@ -29,11 +31,21 @@ type ZclFrame struct {
 	CommandIdentifier         uint8
 	CommandName               string
 	Command                   interface{}
+	// Response reports whether the command answers another one, as its
+	// descriptor marks it.
+	Response bool
+	// Symbols holds the names of the enumerated values and the bitmap flags
+	// of the attribute values Command carries, by attribute id.
+	Symbols map[uint16]string
+	// Warnings lists conformance problems found while decoding. In strict
+	// mode decoding also fails with them.
+	Warnings []string
 }
 
 type ZclIncomingMessage struct {
 	GroupID              uint16
 	ClusterID            uint16
+	ClusterName          string
 	SrcAddr              string
 	SrcEndpoint          uint8
 	DstEndpoint          uint8
@@ -47,10 +59,17 @@ type ZclIncomingMessage struct {
 
 type Zcl struct {
 	library *cluster.ClusterLibrary
+	mode    DecodingMode
 }
 
 func New() *Zcl {
-	return &Zcl{cluster.New()}
+	return &Zcl{library: cluster.New()}
+}
+
+// NewWithLibrary creates a Zcl which resolves clusters with the given library,
+// e.g. one loaded from ZCL XML definitions.
+func NewWithLibrary(library *cluster.ClusterLibrary) *Zcl {
+	return &Zcl{library: library}
 }
 
 func (z *Zcl) ToZclIncomingMessage(m *znp.AfIncomingMessage) (*ZclIncomingMessage, error) {
@@ -67,128 +86,128 @@ func (z *Zcl) ToZclIncomingMessage(m *znp.AfIncomingMessage) (*ZclIncomingMessag
 	im.TransactionSeqNumber = m.TransSeqNumber
 	data, err := z.toZclFrame(m.Data, m.ClusterID)
 	im.Data = data
+	im.ClusterName = z.clusterName(m.ClusterID, data)
 	return im, err
 }
 
+// Encode encodes a frame, e.g. one read from JSON, failing if the command
+// doesn't match its struct tags.
+func Encode(f *ZclFrame) (data []uint8, err error) {
+	if f.FrameControl == nil {
+		return nil, fmt.Errorf("frame control missing")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			data, err = nil, fmt.Errorf("can't encode %s: %v", f.CommandName, r)
+		}
+	}()
+	raw := &frame.Frame{
+		FrameControl: &frame.FrameControl{
+			FrameType:              f.FrameControl.FrameType,
+			ManufacturerSpecific:   frame.Flag(f.FrameControl.ManufacturerSpecific),
+			Direction:              f.FrameControl.Direction,
+			DisableDefaultResponse: frame.Flag(f.FrameControl.DisableDefaultResponse),
+		},
+		ManufacturerCode:          f.ManufacturerCode,
+		TransactionSequenceNumber: f.TransactionSequenceNumber,
+		CommandIdentifier:         f.CommandIdentifier,
+	}
+	if f.Command != nil {
+		raw.Payload = bin.Encode(f.Command)
+	}
+	return frame.Encode(raw), nil
+}
+
 func (z *Zcl) toZclFrame(data []uint8, clusterId uint16) (*ZclFrame, error) {
-	frame := frame.Decode(data)
+	frame, err := frame.Decode(data)
+	if err != nil {
+		return nil, err
+	}
 	f := &ZclFrame{}
 	f.FrameControl = z.toZclFrameControl(frame.FrameControl)
 	f.ManufacturerCode = frame.ManufacturerCode
 	f.TransactionSequenceNumber = frame.TransactionSequenceNumber
 	f.CommandIdentifier = frame.CommandIdentifier
-	cmd, name, err := z.toZclCommand(clusterId, frame)
-	f.CommandName = name
+	cmd, cd, err := z.toZclCommand(clusterId, frame)
+	if cd != nil {
+		f.CommandName, f.Response = cd.Name, cd.Response
+	}
 	f.Command = cmd
+	f.Symbols = z.symbols(cmd, clusterId, z.manufacturerCode(frame))
+	f.Warnings = z.check(frame, cmd)
+	if err == nil && z.mode == DecodingModeStrict && len(f.Warnings) > 0 {
+		err = fmt.Errorf("strict decoding: %s", strings.Join(f.Warnings, "; "))
+	}
 	return f, err
 }
 
-func (z *Zcl) toZclCommand(clusterId uint16, f *frame.Frame) (interface{}, string, error) {
+func (z *Zcl) toZclCommand(clusterId uint16, f *frame.Frame) (interface{}, *cluster.CommandDescriptor, error) {
 	var cd *cluster.CommandDescriptor
 	var ok bool
+	manufacturerCode := z.manufacturerCode(f)
 	switch f.FrameControl.FrameType {
 	case frame.FrameTypeGlobal:
 		if cd, ok = z.library.Global()[f.CommandIdentifier]; !ok {
-			return nil, "", fmt.Errorf("unsupported global cmd identifier %d", f.CommandIdentifier)
+			return nil, nil, fmt.Errorf("unsupported global cmd identifier %d", f.CommandIdentifier)
 		}
-		cmd := cd.Command
-		copy := reflection.Copy(cmd)
-		bin.Decode(f.Payload, copy)
-		z.patchName(copy, clusterId, f.CommandIdentifier)
-		return copy, cd.Name, nil
+		copy := newCommand(cd.Command)
+		if err := decodeCommand(f.Payload, copy, cd.Name); err != nil {
+			return nil, cd, err
+		}
+		z.enrich(copy, clusterId, manufacturerCode, f.FrameControl.Direction)
+		return copy, cd, nil
 	case frame.FrameTypeLocal:
-		var c *cluster.Cluster
-		if c, ok = z.library.Clusters()[cluster.ClusterId(clusterId)]; !ok {
-			return nil, "", fmt.Errorf("unknown cluster %d", clusterId)
+		if len(z.library.Candidates(cluster.ClusterId(clusterId), manufacturerCode)) == 0 {
+			return nil, nil, fmt.Errorf("unknown cluster %d", clusterId)
 		}
-		var commandDescriptors map[uint8]*cluster.CommandDescriptor
-		switch f.FrameControl.Direction {
-		case frame.DirectionClientServer:
-			commandDescriptors = c.CommandDescriptors.Received
-		case frame.DirectionServerClient:
-			commandDescriptors = c.CommandDescriptors.Generated
+		if cd, ok = z.library.CommandDescriptor(cluster.ClusterId(clusterId), manufacturerCode, f.FrameControl.Direction, f.CommandIdentifier); !ok {
+			return nil, nil, fmt.Errorf("cluster %d doesn't support this cmd %d", clusterId, f.CommandIdentifier)
 		}
-		if cd, ok = commandDescriptors[f.CommandIdentifier]; !ok {
-			return nil, "", fmt.Errorf("cluster %d doesn't support this cmd %d", clusterId, f.CommandIdentifier)
+		copy := newCommand(cd.Command)
+		if err := decodeCommand(f.Payload, copy, cd.Name); err != nil {
+			return nil, cd, err
 		}
-		cmd := cd.Command
-		copy := reflection.Copy(cmd)
-		bin.Decode(f.Payload, copy)
-		return copy, cd.Name, nil
+		z.enrich(copy, clusterId, manufacturerCode, f.FrameControl.Direction)
+		return copy, cd, nil
 	}
-	return nil, "", fmt.Errorf("unknown frame type")
+	return nil, nil, fmt.Errorf("unknown frame type")
 }
 
-func (z *Zcl) patchName(cmd interface{}, clusterId uint16, commandId uint8) {
-	switch cmd := cmd.(type) {
-	case *cluster.ReadAttributesResponse:
-		for _, v := range cmd.ReadAttributeStatuses {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesCommand:
-		for _, v := range cmd.WriteAttributeRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesUndividedCommand:
-		for _, v := range cmd.WriteAttributeRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesNoResponseCommand:
-		for _, v := range cmd.WriteAttributeRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesResponse:
-		for _, v := range cmd.WriteAttributeStatuses {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ConfigureReportingCommand:
-		for _, v := range cmd.AttributeReportingConfigurationRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ConfigureReportingResponse:
-		for _, v := range cmd.AttributeStatusRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ReadReportingConfigurationCommand:
-		for _, v := range cmd.AttributeRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ReadReportingConfigurationResponse:
-		for _, v := range cmd.AttributeReportingConfigurationResponseRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ReportAttributesCommand:
-		for _, v := range cmd.AttributeReports {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.DiscoverAttributesResponse:
-		for _, v := range cmd.AttributeInformations {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.ReadAttributesStructuredCommand:
-		for _, v := range cmd.AttributeSelectors {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesStructuredCommand:
-		for _, v := range cmd.WriteAttributeStructuredRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.WriteAttributesStructuredResponse:
-		for _, v := range cmd.WriteAttributeStatusRecords {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
-		}
-	case *cluster.DiscoverAttributesExtendedResponse:
-		for _, v := range cmd.ExtendedAttributeInformations {
-			v.AttributeName = z.getAttributeName(clusterId, v.AttributeID)
+// decodeCommand decodes a payload into cmd, turning panics of the reflection
+// based decoder on malformed payloads into errors.
+func decodeCommand(payload []uint8, cmd interface{}, name string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed %s payload: %v", name, r)
 		}
+	}()
+	bin.Decode(payload, cmd)
+	return nil
+}
+
+// newCommand returns an empty command to decode a payload into. Dynamic
+// commands keep the schema of their prototype.
+func newCommand(prototype interface{}) interface{} {
+	if dynamic, ok := prototype.(*cluster.DynamicCommand); ok {
+		return dynamic.Schema.NewCommand()
 	}
+	return reflection.Copy(prototype)
 }
 
-func (z *Zcl) getAttributeName(clusterId uint16, attributeId uint16) string {
-	if cluster, ok := z.library.Clusters()[cluster.ClusterId(clusterId)]; ok {
-		if attributeDescriptor, ok := cluster.AttributeDescriptors[attributeId]; ok {
-			return attributeDescriptor.Name
-		}
+func (z *Zcl) manufacturerCode(f *frame.Frame) uint16 {
+	if f.FrameControl.ManufacturerSpecific > 0 {
+		return f.ManufacturerCode
+	}
+	return 0
+}
+
+func (z *Zcl) clusterName(clusterId uint16, f *ZclFrame) string {
+	var manufacturerCode uint16
+	if f != nil && f.FrameControl.ManufacturerSpecific {
+		manufacturerCode = f.ManufacturerCode
+	}
+	if candidates := z.library.Candidates(cluster.ClusterId(clusterId), manufacturerCode); len(candidates) > 0 {
+		return candidates[0].Name
 	}
 	return ""
 }
@@ -0,0 +1,230 @@
+package zcl
+
+import (
+	"testing"
+
+	"github.com/dyrkin/zcl-go/cluster"
+	"github.com/dyrkin/zcl-go/frame"
+	"github.com/dyrkin/znp-go"
+	. "gopkg.in/check.v1"
+)
+
+func TestZcl(t *testing.T) { TestingT(t) }
+
+type ZclSuite struct{}
+
+var _ = Suite(&ZclSuite{})
+
+func (s *ZclSuite) TestManufacturerSpecificAttributeName(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Basic),
+		SrcAddr:   "0x1234",
+		Data: []uint8{0x1c, 0x5f, 0x11, 0x01, 0x0a,
+			0x01, 0xff, byte(cluster.ZclDataTypeCharStr), 0x02, 0x01, 0x02,
+			0x05, 0x00, byte(cluster.ZclDataTypeCharStr), 0x01, 0x41},
+	})
+	c.Assert(err, IsNil)
+	reports := message.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports
+	c.Assert(reports[0].AttributeName, Equals, "XiaomiInfo")
+	c.Assert(reports[1].AttributeName, Equals, "ModelIdentifier")
+}
+
+func (s *ZclSuite) TestStandardFrameIgnoresVendorAttributes(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Basic),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0a, 0x01, 0xff, byte(cluster.ZclDataTypeCharStr), 0x00},
+	})
+	c.Assert(err, IsNil)
+	reports := message.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports
+	c.Assert(reports[0].AttributeName, Equals, "")
+}
+
+func (s *ZclSuite) TestManufacturerSpecificCommand(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.PhilipsHueSwitch),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x1d, 0x0b, 0x10, 0x01, 0x00, 0x02, 0x00, 0x00, 0x30, 0x02, 0x00, 0x01, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.CommandName, Equals, "HueNotification")
+	c.Assert(message.Data.Command, DeepEquals, &cluster.HueNotificationCommand{
+		Button:   2,
+		Unknown1: [3]uint8{0x00, 0x00, 0x30},
+		Type:     2,
+		Time:     1,
+	})
+
+	_, err = z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.PhilipsHueSwitch),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x19, 0x01, 0x00, 0x02},
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *ZclSuite) TestManufacturerSpecificCluster(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.IkeaManufacturerSpecific),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x1c, 0x7c, 0x11, 0x01, 0x0a, 0x10, 0x00, byte(cluster.ZclDataTypeUint8), 0x01},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.ClusterName, Equals, "IkeaManufacturerSpecific")
+	c.Assert(message.Data.ManufacturerCode, Equals, cluster.ManufacturerIkea)
+	reports := message.Data.Command.(*cluster.ReportAttributesCommand).AttributeReports
+	c.Assert(reports[0].AttributeName, Equals, "")
+
+	message, err = z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.IkeaManufacturerSpecific),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0a, 0x10, 0x00, byte(cluster.ZclDataTypeUint8), 0x01},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.ClusterName, Equals, "")
+}
+
+func (s *ZclSuite) TestDiscoverCommandsReceivedResponse(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x12, 0x01, 0x00, 0x01, 0x99},
+	})
+	c.Assert(err, IsNil)
+	response := message.Data.Command.(*cluster.DiscoverCommandsReceivedResponse)
+	c.Assert(response.Commands, DeepEquals, []*cluster.DiscoveredCommand{
+		{CommandID: 0x00, Name: "Off", Known: true},
+		{CommandID: 0x01, Name: "On", Known: true},
+		{CommandID: 0x99},
+	})
+}
+
+func (s *ZclSuite) TestDiscoverCommandsGeneratedResponse(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Alarms),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x14, 0x01, 0x00, 0x01},
+	})
+	c.Assert(err, IsNil)
+	response := message.Data.Command.(*cluster.DiscoverCommandsGeneratedResponse)
+	c.Assert(response.Commands, DeepEquals, []*cluster.DiscoveredCommand{
+		{CommandID: 0x00, Name: "Alarm", Known: true},
+		{CommandID: 0x01, Name: "GetAlarmResponse", Known: true},
+	})
+
+	message, err = z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Alarms),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x10, 0x01, 0x14, 0x01, 0x00, 0x01},
+	})
+	c.Assert(err, IsNil)
+	response = message.Data.Command.(*cluster.DiscoverCommandsGeneratedResponse)
+	c.Assert(response.Commands[0].Name, Equals, "ResetAlarm")
+	c.Assert(response.Commands[1].Name, Equals, "ResetAllAlarms")
+}
+
+func (s *ZclSuite) TestDiscoverManufacturerSpecificCommands(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.PhilipsHueSwitch),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x1c, 0x0b, 0x10, 0x01, 0x14, 0x01, 0x00, 0x01},
+	})
+	c.Assert(err, IsNil)
+	response := message.Data.Command.(*cluster.DiscoverCommandsGeneratedResponse)
+	c.Assert(response.Commands, DeepEquals, []*cluster.DiscoveredCommand{
+		{CommandID: 0x00, Name: "HueNotification", Known: true},
+		{CommandID: 0x01},
+	})
+}
+
+func (s *ZclSuite) TestDefaultResponseCommandName(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0b, 0x01, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Command, DeepEquals, &cluster.DefaultResponseCommand{CommandID: 0x01, CommandName: "On", Status: cluster.ZclStatusSuccess})
+
+	message, err = z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x08, 0x01, 0x0b, 0x0a, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Command.(*cluster.DefaultResponseCommand).CommandName, Equals, "ReportAttributes")
+
+	message, err = z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x18, 0x01, 0x0b, 0x00, 0x00},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Command.(*cluster.DefaultResponseCommand).CommandName, Equals, "")
+}
+
+type attributeRecord struct {
+	AttributeID   uint16
+	AttributeName string `transient:"true"`
+}
+
+type vendorAttributeCommand struct {
+	Count   uint8
+	Records []*attributeRecord
+}
+
+func (s *ZclSuite) TestRegisteredCommandAttributeNames(c *C) {
+	library := cluster.New()
+	c.Assert(library.RegisterCommand(cluster.OnOff, 0x1234, frame.DirectionClientServer, 0x80,
+		&cluster.CommandDescriptor{Name: "VendorRead", Command: &vendorAttributeCommand{}}), IsNil)
+	message, err := NewWithLibrary(library).ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.OnOff),
+		SrcAddr:   "0x1234",
+		Data:      []uint8{0x05, 0x34, 0x12, 0x01, 0x80, 0x02, 0x00, 0x00, 0x01, 0x40},
+	})
+	c.Assert(err, IsNil)
+	command := message.Data.Command.(*vendorAttributeCommand)
+	c.Assert(command.Records[0].AttributeName, Equals, "OnOff")
+	c.Assert(command.Records[1].AttributeName, Equals, "OnTime")
+}
+
+func (s *ZclSuite) TestMalformedFrame(c *C) {
+	z := New()
+	_, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{ClusterID: uint16(cluster.Basic), Data: []uint8{0x1c, 0x5f}})
+	c.Assert(err, ErrorMatches, "frame too short: 2 bytes, header needs 5")
+
+	message, err := z.ToZclIncomingMessage(&znp.AfIncomingMessage{
+		ClusterID: uint16(cluster.Basic),
+		Data:      []uint8{0x18, 0x01, 0x0a, 0x05, 0x00, byte(cluster.ZclDataTypeArray), 0xff, 0xff},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, Not(HasLen), 0)
+}
+
+func (s *ZclSuite) TestWriteAttributesResponse(c *C) {
+	z := New()
+	message, err := z.ToZclIncomingMessage(onOffMessage(0x18, 0x42, 0x04, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, HasLen, 0)
+	statuses := message.Data.Command.(*cluster.WriteAttributesResponse).WriteAttributeStatuses
+	c.Assert(statuses, HasLen, 1)
+	c.Assert(statuses[0].Status, Equals, cluster.ZclStatusSuccess)
+
+	message, err = z.ToZclIncomingMessage(onOffMessage(0x18, 0x42, 0x04, 0x86, 0x01, 0x40, 0x88, 0x00, 0x00))
+	c.Assert(err, IsNil)
+	c.Assert(message.Data.Warnings, HasLen, 0)
+	statuses = message.Data.Command.(*cluster.WriteAttributesResponse).WriteAttributeStatuses
+	c.Assert(statuses, HasLen, 2)
+	c.Assert(statuses[0].Status, Equals, cluster.ZclStatusUnsupportedAttribute)
+	c.Assert(statuses[0].AttributeID, Equals, uint16(0x4001))
+	c.Assert(statuses[1].Status, Equals, cluster.ZclStatusReadOnly)
+	c.Assert(statuses[1].AttributeID, Equals, uint16(0x0000))
+}